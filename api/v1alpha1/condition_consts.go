@@ -18,6 +18,59 @@ const (
 
 	// LoadBalancerNotAvailableReason is used to indicate that the load balancer isn't available.
 	LoadBalancerNotAvailableReason = "LoadBalancerNotAvailable"
+
+	// WaitingForControlPlaneEndpointReason is used to indicate that the control plane endpoint
+	// is waiting for an IP address to be allocated by an IPAM provider.
+	WaitingForControlPlaneEndpointReason = "WaitingForControlPlaneEndpoint"
+)
+
+const (
+	// CredentialsReadyCondition indicates that the secrets used to connect to the hosts exist
+	// and contain the required keys.
+	CredentialsReadyCondition clusterv1.ConditionType = "CredentialsReady"
+
+	// CredentialsSecretNotFoundReason indicates that a secret referenced for connecting to the
+	// hosts doesn't exist.
+	CredentialsSecretNotFoundReason = "CredentialsSecretNotFound"
+
+	// CredentialsSecretKeyMissingReason indicates that a secret referenced for connecting to the
+	// hosts is missing a required key.
+	CredentialsSecretKeyMissingReason = "CredentialsSecretKeyMissing"
+
+	// IdentityNotFoundReason indicates that the MicrovmClusterIdentity referenced by the
+	// MicrovmCluster doesn't exist.
+	IdentityNotFoundReason = "IdentityNotFound"
+
+	// IdentityNotAllowedReason indicates that the namespace of the MicrovmCluster isn't allowed
+	// to use the MicrovmClusterIdentity it references.
+	IdentityNotAllowedReason = "IdentityNotAllowed"
+)
+
+const (
+	// ClientCertificateReadyCondition indicates that the client certificate issued by cert-manager
+	// for connecting to the hosts is ready.
+	ClientCertificateReadyCondition clusterv1.ConditionType = "ClientCertificateReady"
+
+	// WaitingForClientCertificateReason indicates that the cert-manager Certificate for the client
+	// certificate isn't ready yet.
+	WaitingForClientCertificateReason = "WaitingForClientCertificate"
+)
+
+const (
+	// TLSCertificatesValidCondition indicates that the client certificates used to connect to the
+	// hosts are valid and aren't about to expire.
+	TLSCertificatesValidCondition clusterv1.ConditionType = "TLSCertificatesValid"
+
+	// TLSCertificateInvalidReason indicates that a TLS secret is missing or doesn't contain
+	// a valid certificate.
+	TLSCertificateInvalidReason = "TLSCertificateInvalid"
+
+	// TLSCertificateExpiredReason indicates that a client certificate has expired.
+	TLSCertificateExpiredReason = "TLSCertificateExpired"
+
+	// TLSCertificateExpiringReason indicates that a client certificate will expire soon and
+	// needs to be rotated.
+	TLSCertificateExpiringReason = "TLSCertificateExpiring"
 )
 
 const (
@@ -47,4 +100,31 @@ const (
 	// WaitingForBootstrapDataReason indicates that microvm is waiting for the bootstrap data
 	// to be available before proceeding.
 	WaitingForBootstrapDataReason = "WaitingForBoostrapData"
+
+	// WaitingForIPAddressReason indicates that the microvm is waiting for an IP address to
+	// be allocated by an IPAM provider before proceeding.
+	WaitingForIPAddressReason = "WaitingForIPAddress"
+
+	// DuplicateMACAddressReason indicates that the microvm can't be created as one of its MAC
+	// addresses is already used by another microvm on the same host.
+	DuplicateMACAddressReason = "DuplicateMACAddress"
+
+	// SSHPublicKeysUnavailableReason indicates that the SSH public keys referenced by the
+	// machine or cluster couldn't be read.
+	SSHPublicKeysUnavailableReason = "SSHPublicKeysUnavailable"
+
+	// HostUnavailableReason indicates that the flintlock host of the microvm isn't being called
+	// as recent calls to it have failed.
+	HostUnavailableReason = "HostUnavailable"
+
+	// FlintlockTimeoutReason indicates that a call to the flintlock host of the microvm timed out.
+	FlintlockTimeoutReason = "FlintlockTimeout"
+
+	// CredentialsErrorReason indicates that the basic auth token or client certificate used to
+	// connect to the flintlock host of the microvm couldn't be read.
+	CredentialsErrorReason = "CredentialsError"
+
+	// NoCompatibleHostReason indicates that none of the hosts the microvm can be placed on can
+	// create it, e.g. because they don't have the provider it uses.
+	NoCompatibleHostReason = "NoCompatibleHost"
 )
@@ -6,10 +6,21 @@ package v1alpha1
 import (
 	flclient "github.com/liquidmetal-dev/controller-pkg/client"
 	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
+const (
+	// ClusterFinalizer allows ReconcileMicrovmCluster to clean up resources associated with MicrovmCluster
+	// before removing it from the apiserver.
+	ClusterFinalizer = "microvmcluster.infrastructure.cluster.x-k8s.io"
+
+	// FailureDomainEndpointAttribute is the failure domain attribute with the current endpoint of
+	// the host in the failure domain.
+	FailureDomainEndpointAttribute = "endpoint"
+)
+
 // MicrovmClusterSpec defines the desired state of MicrovmCluster.
 type MicrovmClusterSpec struct {
 	// ControlPlaneEndpoint represents the endpoint used to communicate with the control plane.
@@ -19,16 +30,42 @@ type MicrovmClusterSpec struct {
 	//
 	// +optional
 	ControlPlaneEndpoint clusterv1.APIEndpoint `json:"controlPlaneEndpoint"`
+	// ControlPlaneEndpointFromPool is a reference to an IP pool that the host of the control
+	// plane endpoint will be allocated from using the CAPI IPAM contract. The address is
+	// released when the MicrovmCluster is deleted. It can't be used if the host of the
+	// ControlPlaneEndpoint is set when the MicrovmCluster is created, and the host set from
+	// the pool can't be changed. The port of the ControlPlaneEndpoint will be used if set,
+	// otherwise it defaults to 6443.
+	// +optional
+	ControlPlaneEndpointFromPool *corev1.TypedLocalObjectReference `json:"controlPlaneEndpointFromPool,omitempty"`
+	// LoadBalancer is the configuration of the load balancer for the control plane endpoint. If
+	// kube-vip is used then its static pod manifest is added to the bootstrap data of the control
+	// plane machines.
+	// +optional
+	LoadBalancer *LoadBalancerSpec `json:"loadBalancer,omitempty"`
 	// SSHPublicKeys is a list of SSHPublicKeys and their associated users.
 	// If specified these keys will be applied to all machine created unless you
 	// specify different keys at the machine level.
 	// +optional
 	SSHPublicKeys []microvm.SSHPublicKey `json:"sshPublicKeys,omitempty"`
+	// SSHPublicKeysFrom is a list of references to SSH public keys stored in Secrets or ConfigMaps.
+	// They are combined with SSHPublicKeys and read when a machine is created, so changes apply to
+	// new machines without editing the cluster.
+	// +optional
+	SSHPublicKeysFrom []SSHPublicKeySource `json:"sshPublicKeysFrom,omitempty"`
+	// SSHPublicKeysPolicy is how the SSH public keys of a machine are combined with the keys of the
+	// cluster. Replace uses the keys of the machine instead of the cluster's if it has any, and Merge
+	// adds the keys of both for each user.
+	// +kubebuilder:validation:Enum=Replace;Merge
+	// +kubebuilder:default=Replace
+	// +optional
+	SSHPublicKeysPolicy SSHPublicKeysPolicy `json:"sshPublicKeysPolicy,omitempty"`
 	// Placement specifies how machines for the cluster should be placed onto hosts (i.e. where the microvms are created).
 	// +kubebuilder:validation:Required
 	Placement Placement `json:"placement"`
 	// MicrovmProxy is the proxy server details to use when calling the microvm service. This is an
 	// alteranative to using the http proxy environment variables and applied purely to the grpc service.
+	// Hosts can override it with their own Proxy.
 	MicrovmProxy *flclient.Proxy `json:"microvmProxy,omitempty"`
 
 	// mTLS Configuration:
@@ -38,7 +75,7 @@ type MicrovmClusterSpec struct {
 	// The CAPMVM client should be provided with the CA, and a client cert and key
 	// signed by that CA.
 	// TLSSecretRef is a reference to the name of a secret which contains TLS cert information
-	// for connecting to Flintlock hosts.
+	// for connecting to Flintlock hosts. It's used for the hosts that don't set their own TLSSecretRef.
 	// The secret should be created in the same namespace as the MicroVMCluster.
 	// The secret should be of type Opaque
 	// with the addition of a ca.crt key.
@@ -64,6 +101,20 @@ type MicrovmClusterSpec struct {
 	// 		-----END CERTIFICATE-----
 	// +optional
 	TLSSecretRef string `json:"tlsSecretRef,omitempty"`
+	// IdentityRef is a reference to a MicrovmClusterIdentity with the credentials for connecting
+	// to the hosts. The namespace of the MicrovmCluster must be allowed by the identity. It can't
+	// be used with TLSSecretRef, the TLSSecretRef of the hosts or the BasicAuthSecret.
+	// +optional
+	IdentityRef *MicrovmClusterIdentityReference `json:"identityRef,omitempty"`
+	// ClientCertificate configures a client certificate for connecting to the flintlock hosts that
+	// is issued by cert-manager. The provider creates and owns a cert-manager Certificate and uses
+	// the secret it creates instead of TLSSecretRef. It can't be used with TLSSecretRef or IdentityRef.
+	// +optional
+	ClientCertificate *ClientCertificateSpec `json:"clientCertificate,omitempty"`
+	// FlintlockCalls configures the timeouts and retries of the calls to the flintlock hosts.
+	// Anything that isn't set uses the defaults of the controller.
+	// +optional
+	FlintlockCalls *FlintlockCallsSpec `json:"flintlockCalls,omitempty"`
 }
 
 type SSHPublicKey struct {
@@ -89,6 +140,18 @@ type MicrovmClusterStatus struct {
 	// FailureDomains is a list of the failure domains that CAPI should spread the machines across. For
 	// the CAPMVM provider this equates to host machines that can run microvms using Flintlock.
 	FailureDomains clusterv1.FailureDomains `json:"failureDomains,omitempty"`
+
+	// Hosts is the observed state of the hosts that the machines of the cluster can be placed on.
+	// +optional
+	Hosts []HostStatus `json:"hosts,omitempty"`
+
+	// HostCount is the number of hosts in Hosts.
+	// +optional
+	HostCount int32 `json:"hostCount,omitempty"`
+
+	// ReachableHostCount is the number of hosts in Hosts that responded to the last call.
+	// +optional
+	ReachableHostCount int32 `json:"reachableHostCount,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -97,6 +160,8 @@ type MicrovmClusterStatus struct {
 // +kubebuilder:resource:path=microvmclusters,scope=Namespaced,categories=cluster-api,shortName=mvmc
 // +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".metadata.labels.cluster\\.x-k8s\\.io/cluster-name",description="Cluster to which this MicrovmCluster belongs"
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready",description="Cluster infrastructure is ready"
+// +kubebuilder:printcolumn:name="Hosts",type="integer",JSONPath=".status.hostCount",description="Number of hosts the machines can be placed on"
+// +kubebuilder:printcolumn:name="Reachable",type="integer",JSONPath=".status.reachableHostCount",description="Number of hosts that responded to the last call"
 // +kubebuilder:printcolumn:name="ControlPlaneEndpoint",type="string",JSONPath=".spec.controlPlaneEndpoint[0]",description="API Endpoint",priority=1
 // +k8s:defaulter-gen=true
 
@@ -0,0 +1,74 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MicrovmClusterIdentitySpec defines the credentials used to connect to the microvm hosts.
+type MicrovmClusterIdentitySpec struct {
+	// TLSSecretRef is a reference to the name of a secret which contains TLS cert information
+	// for connecting to the flintlock hosts. The secret must be in the namespace of the controller
+	// and has the same format as the TLSSecretRef of a MicrovmCluster.
+	// +optional
+	TLSSecretRef string `json:"tlsSecretRef,omitempty"`
+	// BasicAuthSecret is the name of the secret containing basic auth info for each host. The
+	// secret must be in the namespace of the controller and has the same format as the
+	// BasicAuthSecret of the static pool placement.
+	// +optional
+	BasicAuthSecret string `json:"basicAuthSecret,omitempty"`
+	// AllowedNamespaces is used to identify the namespaces that MicrovmClusters are allowed to use
+	// the identity from. Namespaces can be selected using a list of names or a label selector.
+	// An empty allowedNamespaces allows all namespaces and if it's not set no namespaces are allowed.
+	// +optional
+	AllowedNamespaces *AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+}
+
+// AllowedNamespaces selects the namespaces that are allowed to use an identity. A namespace is
+// allowed if it matches either the list or the selector.
+type AllowedNamespaces struct {
+	// NamespaceList is a list of the names of the allowed namespaces.
+	// +optional
+	NamespaceList []string `json:"list,omitempty"`
+	// Selector is a label selector of the allowed namespaces. An empty selector matches all
+	// namespaces.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// MicrovmClusterIdentityReference is a reference to a MicrovmClusterIdentity.
+type MicrovmClusterIdentityReference struct {
+	// Name is the name of the MicrovmClusterIdentity.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:resource:path=microvmclusteridentities,scope=Cluster,categories=cluster-api,shortName=mvmci
+
+// MicrovmClusterIdentity is the Schema for the microvmclusteridentities API. It holds the
+// credentials for connecting to the microvm hosts so that they can be shared by MicrovmClusters
+// in different namespaces.
+type MicrovmClusterIdentity struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MicrovmClusterIdentitySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MicrovmClusterIdentityList contains a list of MicrovmClusterIdentity.
+type MicrovmClusterIdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MicrovmClusterIdentity `json:"items"`
+}
+
+//nolint:gochecknoinits // Maybe we can remove it, now just ignore.
+func init() {
+	SchemeBuilder.Register(&MicrovmClusterIdentity{}, &MicrovmClusterIdentityList{})
+}
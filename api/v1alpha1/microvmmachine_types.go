@@ -14,6 +14,15 @@ const (
 	// MachineFinalizer allows ReconcileMicrovmMachine to clean up resources associated with MicrovmMachine
 	// before removing it from the apiserver.
 	MachineFinalizer = "microvmmachine.infrastructure.cluster.x-k8s.io"
+
+	// HostIDAnnotation records the ID of the host the microvm was placed on. It lets machines whose
+	// providerID contains the endpoint of the host find the host after the endpoint has changed.
+	HostIDAnnotation = "microvmmachine.infrastructure.cluster.x-k8s.io/host-id"
+
+	// HostEndpointAnnotation records the endpoint of the host the microvm was placed on. Host IDs
+	// are only unique within a cluster, so it lets machines in other clusters tell whether they
+	// share the host.
+	HostEndpointAnnotation = "microvmmachine.infrastructure.cluster.x-k8s.io/host-endpoint"
 )
 
 // MicrovmMachineSpec defines the desired state of MicrovmMachine.
@@ -23,10 +32,34 @@ type MicrovmMachineSpec struct {
 	// SSHPublicKeys is list of SSH public keys that will be used with stated users
 	// on this machine.
 	// If specified they will take precedence over any SSH keys specified at
-	// the cluster level.
+	// the cluster level, unless the SSHPublicKeysPolicy of the cluster is Merge.
 	// +optional
 	SSHPublicKeys []microvm.SSHPublicKey `json:"sshPublicKeys,omitempty"`
 
+	// SSHPublicKeysFrom is a list of references to SSH public keys stored in Secrets or ConfigMaps.
+	// They are combined with SSHPublicKeys and read when the microvm is created.
+	// +optional
+	SSHPublicKeysFrom []SSHPublicKeySource `json:"sshPublicKeysFrom,omitempty"`
+
+	// InstanceMetadata is a map of additional key/values that will be added to the cloud-init
+	// instance metadata of the microvm and can be used from the guest (or bootstrap templates)
+	// via ds.meta_data.<key>. Keys that are set by the provider (e.g. cluster_name, vm_host)
+	// take precedence and cannot be overridden.
+	// +optional
+	InstanceMetadata map[string]string `json:"instanceMetadata,omitempty"`
+
+	// NetworkInterfaceConfigs is used to supply additional provider specific configuration
+	// for the network interfaces in the VMSpec. The configuration is matched to an interface
+	// using the guest device name.
+	// +optional
+	NetworkInterfaceConfigs []NetworkInterfaceConfig `json:"networkInterfaceConfigs,omitempty"`
+
+	// MinFlintlockVersion is the oldest version of flintlock that can create the microvm, e.g.
+	// because the spec uses features that older versions ignore. The machine is only placed on
+	// hosts with a declared version that's at least this version.
+	// +optional
+	MinFlintlockVersion string `json:"minFlintlockVersion,omitempty"`
+
 	// ProviderID is the unique identifier as specified by the cloud provider.
 	ProviderID *string `json:"providerID,omitempty"`
 }
@@ -0,0 +1,167 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package v1alpha1
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// sshKeyTypes are the key types that are allowed in authorized_keys.
+var sshKeyTypes = map[string]bool{
+	"ssh-rsa":                            true,
+	"ssh-dss":                            true,
+	"ssh-ed25519":                        true,
+	"ecdsa-sha2-nistp256":                true,
+	"ecdsa-sha2-nistp384":                true,
+	"ecdsa-sha2-nistp521":                true,
+	"sk-ssh-ed25519@openssh.com":         true,
+	"sk-ecdsa-sha2-nistp256@openssh.com": true,
+}
+
+var (
+	errSSHKeyTypeMissing   = errors.New("no supported key type found")
+	errSSHKeyDataMissing   = errors.New("key data is missing")
+	errSSHKeyDataInvalid   = errors.New("key data is not valid base64")
+	errSSHKeyTypeMismatch  = errors.New("key data doesn't match the key type")
+	errSSHKeyTooManyFields = errors.New("options must come before the key type")
+)
+
+// ParseAuthorizedKeys parses the SSH public keys in the authorized_keys format, with one key per
+// line. Blank lines and comments are ignored.
+func ParseAuthorizedKeys(data string) ([]string, error) {
+	keys := []string{}
+
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := ValidateAuthorizedKey(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		keys = append(keys, line)
+	}
+
+	return keys, nil
+}
+
+// ValidateAuthorizedKey checks that the key is a single line in the authorized_keys format:
+// [options] keytype base64-key [comment].
+func ValidateAuthorizedKey(key string) error {
+	fields := splitAuthorizedKey(key)
+
+	typeIndex := -1
+
+	for i, f := range fields {
+		if sshKeyTypes[f] || strings.HasSuffix(f, "-cert-v01@openssh.com") {
+			typeIndex = i
+
+			break
+		}
+	}
+
+	switch {
+	case typeIndex == -1:
+		return errSSHKeyTypeMissing
+	case typeIndex > 1:
+		return errSSHKeyTooManyFields
+	case typeIndex+1 >= len(fields):
+		return errSSHKeyDataMissing
+	}
+
+	keyType := fields[typeIndex]
+
+	blob, err := base64.StdEncoding.DecodeString(fields[typeIndex+1])
+	if err != nil {
+		return errSSHKeyDataInvalid
+	}
+
+	// The key data starts with the key type as a length prefixed string.
+	const lengthSize = 4
+	if len(blob) < lengthSize {
+		return errSSHKeyTypeMismatch
+	}
+
+	length := binary.BigEndian.Uint32(blob)
+	if uint64(len(blob)-lengthSize) < uint64(length) || string(blob[lengthSize:lengthSize+int(length)]) != keyType {
+		return errSSHKeyTypeMismatch
+	}
+
+	return nil
+}
+
+// splitAuthorizedKey splits the key into its whitespace separated fields. Whitespace inside
+// double quotes (i.e. in the options) doesn't separate fields.
+func splitAuthorizedKey(key string) []string {
+	var (
+		fields  []string
+		current strings.Builder
+		quoted  bool
+	)
+
+	for _, r := range key {
+		switch {
+		case r == '"':
+			quoted = !quoted
+
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
+
+// validateSSHPublicKeys checks the format of the inline keys and the references to keys. The
+// format of the keys in existing isn't checked.
+func validateSSHPublicKeys(
+	fieldPath *field.Path,
+	keys []microvm.SSHPublicKey,
+	sources []SSHPublicKeySource,
+	existing map[string]bool,
+) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, key := range keys {
+		for j, authorizedKey := range key.AuthorizedKeys {
+			if existing[authorizedKey] {
+				continue
+			}
+
+			if err := ValidateAuthorizedKey(authorizedKey); err != nil {
+				keyPath := fieldPath.Child("sshPublicKeys").Index(i).Child("authorizedKeys").Index(j)
+				errs = append(errs, field.Invalid(keyPath, authorizedKey, err.Error()))
+			}
+		}
+	}
+
+	for i, source := range sources {
+		sourcePath := fieldPath.Child("sshPublicKeysFrom").Index(i)
+
+		if (source.SecretKeyRef == nil) == (source.ConfigMapKeyRef == nil) {
+			errs = append(errs, field.Invalid(sourcePath, source.User, "exactly one of secretKeyRef or configMapKeyRef must be set"))
+		}
+	}
+
+	return errs
+}
@@ -4,6 +4,9 @@
 package v1alpha1
 
 import (
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
@@ -43,7 +46,9 @@ type StaticPoolPlacement struct {
 	// BasicAuthSecret is the name of the secret containing basic auth info for each
 	// host listed in Hosts.
 	// The secret should be created in the same namespace as the Cluster.
-	// The secret should contain a data entry for each host Endpoint without the port:
+	// The secret should contain a data entry for each host Endpoint without the port.
+	// Secret keys can't contain colons so IPv6 addresses are written in their canonical form
+	// with the colons replaced by dashes, e.g. fd00--1 for the endpoint [fd00::1]:9090:
 	//
 	// apiVersion: v1
 	// kind: Secret
@@ -54,21 +59,291 @@ type StaticPoolPlacement struct {
 	// data:
 	// 	1.2.4.5: YWRtaW4=
 	// 	myhost: MWYyZDFlMmU2N2Rm
+	// 	fd00--1: ZjJkMWUyZTY3ZGYx
 	BasicAuthSecret string `json:"basicAuthSecret,omitempty"`
 }
 
 type MicrovmHost struct {
-	// Name is an optional name for the host.
+	// Name is an optional name for the host. If set it's used as the stable ID of the host,
+	// which is the failure domain and part of the providerID of the machines on the host, so
+	// that the Endpoint can change without affecting the machines. It must be unique in the
+	// pool. If not set the Endpoint is used as the ID.
 	// +optional
 	Name string `json:"name,omitempty"`
 	// Endpoint is the API endpoint for the microvm service (i.e. flintlock)
-	// including the port.
+	// including the port. IPv6 addresses must be in brackets, e.g. [fd00::1]:9090.
 	// +kubebuilder:validation:Required
 	Endpoint string `json:"endpoint"`
 	// ControlPlaneAllowed marks this host as suitable for running control plane nodes in
 	// addition to worker nodes.
 	// +kubebuilder:default=true
 	ControlPlaneAllowed bool `json:"controlplaneAllowed"`
+	// NetworkOverrides is used to change the network interfaces of microvms that are created
+	// on this host. This allows a cluster to span hosts with different network setups.
+	// +optional
+	NetworkOverrides []HostNetworkOverride `json:"networkOverrides,omitempty"`
+	// Networks maps the named networks used by the microvm network interfaces to the
+	// bridges on this host.
+	// +optional
+	Networks []HostNetwork `json:"networks,omitempty"`
+	// TLSSecretRef is a reference to the name of a secret which contains the TLS cert information
+	// for connecting to this host. It takes precedence over the TLSSecretRef of the MicrovmCluster
+	// and has the same format.
+	// +optional
+	TLSSecretRef string `json:"tlsSecretRef,omitempty"`
+	// Proxy overrides the MicrovmProxy of the MicrovmCluster for this host. It can set a
+	// different proxy or connect to the host directly.
+	// +optional
+	Proxy *HostProxy `json:"proxy,omitempty"`
+	// Capabilities are the version and capabilities of flintlock on this host. Flintlock doesn't
+	// report them over its API so they're declared here. The fields of the microvm spec the host
+	// supports are discovered and recorded in the status of the cluster. Machines aren't placed
+	// on hosts that can't create their microvm.
+	// +optional
+	Capabilities *HostCapabilities `json:"capabilities,omitempty"`
+}
+
+// HostCapabilities are the version and capabilities of flintlock on a host.
+type HostCapabilities struct {
+	// Version is the version of flintlock on the host, e.g. v0.8.0. Machines with a
+	// MinFlintlockVersion aren't placed on hosts without a version.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Providers are the microvm providers (i.e. hypervisors) that are available on the host,
+	// e.g. firecracker or cloudhypervisor. If empty the host is assumed to have all providers.
+	// +optional
+	Providers []string `json:"providers,omitempty"`
+}
+
+// HostProxy is the proxy used to connect to a host.
+type HostProxy struct {
+	// Endpoint is the address of the proxy.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// Direct connects to the host without a proxy, even if the MicrovmCluster has a
+	// MicrovmProxy. It can't be used with Endpoint.
+	// +optional
+	Direct bool `json:"direct,omitempty"`
+}
+
+// ID returns the stable ID of the host, which is its Name or its Endpoint if the host doesn't
+// have a name.
+func (h *MicrovmHost) ID() string {
+	if h.Name != "" {
+		return h.Name
+	}
+
+	return h.Endpoint
+}
+
+// HostNetwork maps a named network to the configuration of a specific host.
+type HostNetwork struct {
+	// Name is the name of the network.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// BridgeName is the name of the Linux bridge on the host that is connected to the network.
+	// Interfaces on the network are created as tap devices attached to the bridge.
+	// +kubebuilder:validation:Required
+	BridgeName string `json:"bridgeName"`
+}
+
+// HostNetworkOverride is a change to the network interfaces in the VMSpec that is applied
+// when the microvm is created on a specific host.
+type HostNetworkOverride struct {
+	// GuestDeviceName is the name of the network interface in the VMSpec to override. If
+	// not set the override applies to all the network interfaces.
+	// +optional
+	GuestDeviceName string `json:"guestDeviceName,omitempty"`
+	// Type overrides the type of the network interface.
+	// +kubebuilder:validation:Enum=macvtap;tap
+	// +optional
+	Type microvm.IfaceType `json:"type,omitempty"`
+	// BridgeName is the name of the Linux bridge on the host that the tap device will be
+	// attached to. This overrides the bridge set in the flintlock configuration of the host.
+	// Type must be set to tap when this is set.
+	// +optional
+	BridgeName string `json:"bridgeName,omitempty"`
+}
+
+// NetworkInterfaceConfig is provider specific configuration for one of the network
+// interfaces specified in the VMSpec.
+type NetworkInterfaceConfig struct {
+	// GuestDeviceName is the name of the network interface in the VMSpec that this
+	// configuration applies to.
+	// +kubebuilder:validation:Required
+	GuestDeviceName string `json:"guestDeviceName"`
+	// AddressFromPool is a reference to an IP pool that a static address for the interface
+	// will be claimed from. The pool must be provided by an IPAM provider that implements
+	// the Cluster API IPAM contract (i.e. IPAddressClaim/IPAddress).
+	// +optional
+	AddressFromPool *corev1.TypedLocalObjectReference `json:"addressFromPool,omitempty"`
+	// Nameservers is a list of DNS servers to configure for the interface in the guest.
+	// +optional
+	Nameservers []string `json:"nameservers,omitempty"`
+	// Gateway is the default gateway for the interface. It's only used when the interface has
+	// a static address and the address doesn't already come with a gateway (i.e. from the pool).
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+	// MTU is the MTU of the interface in the guest.
+	// +kubebuilder:validation:Minimum=576
+	// +kubebuilder:validation:Maximum=9216
+	// +optional
+	MTU *int32 `json:"mtu,omitempty"`
+	// Network is the name of the network the interface is connected to. The network is
+	// mapped to a host specific bridge using the networks of the host the microvm is
+	// placed on.
+	// +optional
+	Network string `json:"network,omitempty"`
+	// VLANID is the id of the VLAN the interface's traffic is tagged with. A VLAN interface
+	// is created in the guest on top of the interface and the address of the interface is
+	// assigned to the VLAN interface instead.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	VLANID *int32 `json:"vlanID,omitempty"`
+	// Routes are additional routes to configure in the guest for the interface.
+	// +optional
+	Routes []Route `json:"routes,omitempty"`
+}
+
+// Route is a route to configure in the guest.
+type Route struct {
+	// To is the destination of the route in CIDR notation.
+	// +kubebuilder:validation:Required
+	To string `json:"to"`
+	// Via is the address of the gateway for the route.
+	// +kubebuilder:validation:Required
+	Via string `json:"via"`
+	// Metric is the metric of the route.
+	// +optional
+	Metric *int32 `json:"metric,omitempty"`
+}
+
+// LoadBalancerType is the type of load balancer used for the control plane endpoint.
+type LoadBalancerType string
+
+const (
+	// LoadBalancerTypeNone means the load balancer isn't managed by the provider (i.e. it's external
+	// or set up via the bootstrap configuration).
+	LoadBalancerTypeNone = LoadBalancerType("none")
+	// LoadBalancerTypeKubeVIPARP means kube-vip is run on the control plane machines and advertises
+	// the control plane endpoint using ARP.
+	LoadBalancerTypeKubeVIPARP = LoadBalancerType("kube-vip-arp")
+	// LoadBalancerTypeKubeVIPBGP means kube-vip is run on the control plane machines and advertises
+	// the control plane endpoint using BGP.
+	LoadBalancerTypeKubeVIPBGP = LoadBalancerType("kube-vip-bgp")
+)
+
+// LoadBalancerSpec represents the configuration of the control plane load balancer.
+type LoadBalancerSpec struct {
+	// Type is the type of load balancer to use.
+	// +kubebuilder:validation:Enum=none;kube-vip-arp;kube-vip-bgp
+	// +kubebuilder:default=none
+	Type LoadBalancerType `json:"type"`
+	// Image is the container image to use for kube-vip. If not set a default image will be used.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Interface is the name of the network interface in the guest that the control plane endpoint
+	// will be advertised on. If not set kube-vip will use the interface of the default route.
+	// +optional
+	Interface string `json:"interface,omitempty"`
+	// BGP is the BGP configuration and is required when using kube-vip-bgp.
+	// +optional
+	BGP *BGPConfig `json:"bgp,omitempty"`
+}
+
+// IsManaged returns true if the load balancer is managed by the provider.
+func (l *LoadBalancerSpec) IsManaged() bool {
+	return l != nil && l.Type != "" && l.Type != LoadBalancerTypeNone
+}
+
+// BGPConfig represents the BGP configuration used by kube-vip.
+type BGPConfig struct {
+	// LocalAS is the AS number used by kube-vip.
+	// +kubebuilder:validation:Required
+	LocalAS uint32 `json:"localAS"`
+	// Peers is the list of BGP peers to advertise the control plane endpoint to.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems:=1
+	Peers []BGPPeer `json:"peers"`
+}
+
+// BGPPeer represents a BGP peer.
+type BGPPeer struct {
+	// Address is the address of the peer.
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+	// AS is the AS number of the peer.
+	// +kubebuilder:validation:Required
+	AS uint32 `json:"as"`
+}
+
+// SSHPublicKeysPolicy is how the SSH public keys of a machine are combined with those of
+// the cluster.
+type SSHPublicKeysPolicy string
+
+const (
+	// SSHPublicKeysPolicyReplace means the keys of a machine replace the keys of the cluster. The
+	// keys of the cluster are only used for machines that don't have any keys.
+	SSHPublicKeysPolicyReplace = SSHPublicKeysPolicy("Replace")
+	// SSHPublicKeysPolicyMerge means the keys of a machine are added to the keys of the cluster
+	// for each user.
+	SSHPublicKeysPolicyMerge = SSHPublicKeysPolicy("Merge")
+)
+
+// SSHPublicKeySource is a reference to the SSH public keys of a user that are stored in a
+// Secret or ConfigMap in the namespace of the cluster. The value must be in the authorized_keys
+// format, with one key per line. Blank lines and lines starting with # are ignored. Exactly one
+// of SecretKeyRef or ConfigMapKeyRef must be set.
+type SSHPublicKeySource struct {
+	// User is the name of the user to add the keys for (eg root, ubuntu).
+	// +kubebuilder:validation:Required
+	User string `json:"user"`
+	// SecretKeyRef selects a key of a Secret containing the public keys.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// ConfigMapKeyRef selects a key of a ConfigMap containing the public keys.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// ClientCertificateSpec is the configuration of a client certificate for connecting to the
+// flintlock hosts that is issued by cert-manager.
+type ClientCertificateSpec struct {
+	// IssuerRef is a reference to the cert-manager issuer of the certificate. The issuer must
+	// populate ca.crt in the secret (i.e. a CA or Vault issuer) so that the hosts can be verified.
+	// +kubebuilder:validation:Required
+	IssuerRef CertificateIssuerReference `json:"issuerRef"`
+	// CommonName is the common name of the certificate subject. Hosts can use the subject to
+	// authorize the cluster. Defaults to capmvm:<namespace>:<name> of the MicrovmCluster.
+	// +optional
+	CommonName string `json:"commonName,omitempty"`
+	// Organizations is the list of organizations of the certificate subject.
+	// +optional
+	Organizations []string `json:"organizations,omitempty"`
+	// Duration is the requested lifetime of the certificate. Defaults to the issuer's default.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// RenewBefore is how long before the certificate expires that it's renewed. Defaults to
+	// cert-manager's default.
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+}
+
+// CertificateIssuerReference is a reference to a cert-manager issuer.
+type CertificateIssuerReference struct {
+	// Name is the name of the issuer.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Kind is the kind of the issuer. An Issuer must be in the namespace of the MicrovmCluster.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Group is the API group of the issuer. Defaults to cert-manager.io. Set it to use an
+	// external issuer.
+	// +optional
+	Group string `json:"group,omitempty"`
 }
 
 // TLSConfig represents config for connecting to TLS enabled hosts.
@@ -77,3 +352,74 @@ type TLSConfig struct {
 	Key    []byte `json:"key"`
 	CACert []byte `json:"caCert"`
 }
+
+// FlintlockCallsSpec is the configuration of the timeouts and retries of the calls to the
+// flintlock hosts.
+type FlintlockCallsSpec struct {
+	// CreateTimeout is how long a call to create a microvm can take.
+	// +optional
+	CreateTimeout *metav1.Duration `json:"createTimeout,omitempty"`
+	// DeleteTimeout is how long a call to delete a microvm can take.
+	// +optional
+	DeleteTimeout *metav1.Duration `json:"deleteTimeout,omitempty"`
+	// GetTimeout is how long a call to get a microvm can take, including its retries.
+	// +optional
+	GetTimeout *metav1.Duration `json:"getTimeout,omitempty"`
+	// ListTimeout is how long a call to list microvms can take, including its retries.
+	// +optional
+	ListTimeout *metav1.Duration `json:"listTimeout,omitempty"`
+	// Retries is how many times a call to get or list microvms is retried if the host is
+	// unavailable or the call times out. Calls that change microvms aren't retried.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+	// RetryBackoff is how long to wait before the first retry. It doubles for each retry after that.
+	// +optional
+	RetryBackoff *metav1.Duration `json:"retryBackoff,omitempty"`
+}
+
+// HostStatus is the observed state of a host that the machines of a cluster can be placed on.
+type HostStatus struct {
+	// ID is the ID of the host, which is the name of its failure domain.
+	ID string `json:"id"`
+	// Endpoint is the endpoint of the host.
+	Endpoint string `json:"endpoint"`
+	// DeclaredVersion is the version of flintlock declared in the capabilities of the host.
+	// Flintlock doesn't report its version, so it's recorded as declared.
+	// +optional
+	DeclaredVersion string `json:"declaredVersion,omitempty"`
+	// DeclaredProviders are the microvm providers declared in the capabilities of the host.
+	// Flintlock doesn't report its providers, so they're recorded as declared.
+	// +optional
+	DeclaredProviders []string `json:"declaredProviders,omitempty"`
+	// MicrovmSpecFields are the fields of the microvm spec in the flintlock API of the host, as
+	// reported by its gRPC server reflection when it was last checked. Hosts running an older
+	// version of flintlock don't have the fields added since. It isn't set if the host doesn't
+	// serve reflection or hasn't responded yet.
+	// +optional
+	MicrovmSpecFields []string `json:"microvmSpecFields,omitempty"`
+	// Reachable is whether the host responded to the last call. It isn't set if the host hasn't
+	// been called yet.
+	// +optional
+	Reachable *bool `json:"reachable,omitempty"`
+	// LastError is the error from the last call to the host, if it failed.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// LastCheckTime is when the host was last called to check that it's reachable. The host
+	// isn't checked again until the host check interval has passed.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// ControlPlaneMicrovms is the number of the cluster's control plane microvms on the host.
+	// +optional
+	ControlPlaneMicrovms int32 `json:"controlPlaneMicrovms,omitempty"`
+	// WorkerMicrovms is the number of the cluster's worker microvms on the host.
+	// +optional
+	WorkerMicrovms int32 `json:"workerMicrovms,omitempty"`
+	// VCPU is the number of vcpus committed to the cluster's microvms on the host.
+	// +optional
+	VCPU int64 `json:"vcpu,omitempty"`
+	// MemoryMb is the memory in megabytes committed to the cluster's microvms on the host.
+	// +optional
+	MemoryMb int64 `json:"memoryMb,omitempty"`
+}
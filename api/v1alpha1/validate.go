@@ -4,7 +4,14 @@
 package v1alpha1
 
 import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/version"
 )
 
 func (p *Placement) Validate() []*field.Error {
@@ -14,6 +21,317 @@ func (p *Placement) Validate() []*field.Error {
 	if p.StaticPool == nil {
 		fieldPath := field.NewPath("spec", "placement")
 		errs = append(errs, field.Forbidden(fieldPath, "you must supply configuration for a placement option"))
+
+		return errs
+	}
+
+	hostIDs := map[string]bool{}
+
+	for i, host := range p.StaticPool.Hosts {
+		hostPath := field.NewPath("spec", "placement", "staticPool", "hosts").Index(i)
+
+		if strings.Contains(host.Name, "/") {
+			errs = append(errs, field.Invalid(hostPath.Child("name"), host.Name, "must not contain a slash"))
+		}
+
+		if hostIDs[host.ID()] {
+			errs = append(errs, field.Duplicate(hostPath.Child("name"), host.ID()))
+		}
+
+		hostIDs[host.ID()] = true
+
+		endpointPath := field.NewPath("spec", "placement", "staticPool", "hosts").Index(i).Child("endpoint")
+		if err := validateHostEndpoint(endpointPath, host.Endpoint); err != nil {
+			errs = append(errs, err)
+		}
+
+		if host.Capabilities != nil && host.Capabilities.Version != "" {
+			if _, err := version.ParseGeneric(host.Capabilities.Version); err != nil {
+				errs = append(errs, field.Invalid(hostPath.Child("capabilities", "version"),
+					host.Capabilities.Version, "must be a version, e.g. v0.8.0"))
+			}
+		}
+
+		if host.Proxy != nil {
+			proxyPath := hostPath.Child("proxy")
+
+			switch {
+			case host.Proxy.Direct && host.Proxy.Endpoint != "":
+				errs = append(errs, field.Forbidden(proxyPath.Child("endpoint"), "cannot be used with direct"))
+			case !host.Proxy.Direct && host.Proxy.Endpoint == "":
+				errs = append(errs, field.Required(proxyPath, "either endpoint or direct must be set"))
+			}
+		}
+
+		networks := map[string]bool{}
+
+		for j, network := range host.Networks {
+			if networks[network.Name] {
+				fieldPath := field.NewPath("spec", "placement", "staticPool", "hosts").Index(i).
+					Child("networks").Index(j).Child("name")
+				errs = append(errs, field.Duplicate(fieldPath, network.Name))
+			}
+
+			networks[network.Name] = true
+		}
+
+		for j, override := range host.NetworkOverrides {
+			// The type is required so that the bridge can't be applied to macvtap interfaces
+			// of the VMSpec.
+			if override.BridgeName != "" && override.Type != microvm.IfaceTypeTap {
+				fieldPath := field.NewPath("spec", "placement", "staticPool", "hosts").Index(i).
+					Child("networkOverrides").Index(j).Child("bridgeName")
+				errs = append(errs, field.Invalid(fieldPath, override.BridgeName, "bridgeName can only be used when type is tap"))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateHostEndpoint checks that the endpoint of a host is in the host:port form understood by
+// net.SplitHostPort.
+func validateHostEndpoint(fieldPath *field.Path, endpoint string) *field.Error {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return field.Invalid(fieldPath, endpoint, "must be in the form host:port, with IPv6 addresses in brackets (e.g. [fd00::1]:9090)")
+	}
+
+	if host == "" {
+		return field.Invalid(fieldPath, endpoint, "host must not be empty")
+	}
+
+	if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+		return field.Invalid(fieldPath, endpoint, "host must be a valid IPv6 address")
+	}
+
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return field.Invalid(fieldPath, endpoint, "port must be a number between 1 and 65535")
+	}
+
+	return nil
+}
+
+// Validate checks the MicrovmCluster spec for errors that can't be expressed using
+// OpenAPI validation.
+func (s *MicrovmClusterSpec) Validate() field.ErrorList {
+	return s.validate(nil)
+}
+
+// ValidateUpdate checks the updated MicrovmCluster spec like Validate, except that only the
+// SSH public keys that were added or changed since old are checked. This allows clusters with
+// keys that were accepted before their format was checked to still be updated. The
+// controlPlaneEndpoint host can be set along with controlPlaneEndpointFromPool, as it's filled
+// in by the controller once the endpoint is allocated, but it can't be changed afterwards.
+func (s *MicrovmClusterSpec) ValidateUpdate(old *MicrovmClusterSpec) field.ErrorList {
+	return s.validate(old)
+}
+
+// validate checks the spec. If old is set the spec is being updated from it.
+func (s *MicrovmClusterSpec) validate(old *MicrovmClusterSpec) field.ErrorList {
+	errs := s.Placement.Validate()
+
+	existingKeys := map[string]bool{}
+
+	if old != nil {
+		for _, key := range old.SSHPublicKeys {
+			for _, authorizedKey := range key.AuthorizedKeys {
+				existingKeys[authorizedKey] = true
+			}
+		}
+	}
+
+	if s.ControlPlaneEndpointFromPool != nil && s.ControlPlaneEndpoint.Host != "" {
+		fieldPath := field.NewPath("spec", "controlPlaneEndpointFromPool")
+
+		switch {
+		case old == nil || old.ControlPlaneEndpointFromPool == nil:
+			errs = append(errs, field.Forbidden(fieldPath, "cannot be used when the controlPlaneEndpoint host is set"))
+		case old.ControlPlaneEndpoint.Host != "" && old.ControlPlaneEndpoint.Host != s.ControlPlaneEndpoint.Host:
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "controlPlaneEndpoint", "host"),
+				"cannot be changed once allocated from the controlPlaneEndpointFromPool"))
+		}
+	}
+
+	if strings.ContainsAny(s.ControlPlaneEndpoint.Host, "[]") {
+		fieldPath := field.NewPath("spec", "controlPlaneEndpoint", "host")
+		errs = append(errs, field.Invalid(fieldPath, s.ControlPlaneEndpoint.Host, "IPv6 addresses must not be in brackets"))
+	}
+
+	if s.IdentityRef != nil {
+		errs = append(errs, s.validateIdentityRef()...)
+	}
+
+	if s.ClientCertificate != nil {
+		errs = append(errs, s.validateClientCertificate()...)
+	}
+
+	errs = append(errs, validateSSHPublicKeys(field.NewPath("spec"), s.SSHPublicKeys, s.SSHPublicKeysFrom, existingKeys)...)
+
+	if s.FlintlockCalls != nil {
+		errs = append(errs, s.FlintlockCalls.validate(field.NewPath("spec", "flintlockCalls"))...)
+	}
+
+	if s.LoadBalancer != nil && s.LoadBalancer.Type == LoadBalancerTypeKubeVIPBGP && s.LoadBalancer.BGP == nil {
+		fieldPath := field.NewPath("spec", "loadBalancer", "bgp")
+		errs = append(errs, field.Required(fieldPath, "bgp configuration is required for kube-vip-bgp"))
+	}
+
+	return errs
+}
+
+// Validate checks the MicrovmMachine spec for errors that can't be expressed using
+// OpenAPI validation.
+func (s *MicrovmMachineSpec) Validate(fieldPath *field.Path) field.ErrorList {
+	errs := validateSSHPublicKeys(fieldPath, s.SSHPublicKeys, s.SSHPublicKeysFrom, nil)
+
+	if s.MinFlintlockVersion != "" {
+		if _, err := version.ParseGeneric(s.MinFlintlockVersion); err != nil {
+			errs = append(errs, field.Invalid(fieldPath.Child("minFlintlockVersion"),
+				s.MinFlintlockVersion, "must be a version, e.g. v0.8.0"))
+		}
+	}
+
+	devices := map[string]bool{}
+	macs := map[string]bool{}
+
+	for i, iface := range s.NetworkInterfaces {
+		devices[iface.GuestDeviceName] = true
+
+		if iface.GuestMAC == "" {
+			continue
+		}
+
+		macPath := fieldPath.Child("networkInterfaces").Index(i).Child("guestMac")
+
+		mac, err := net.ParseMAC(iface.GuestMAC)
+		if err != nil {
+			errs = append(errs, field.Invalid(macPath, iface.GuestMAC, "must be a valid mac address"))
+
+			continue
+		}
+
+		if mac[0]&0x01 != 0 {
+			errs = append(errs, field.Invalid(macPath, iface.GuestMAC, "must be a unicast mac address"))
+		}
+
+		if macs[mac.String()] {
+			errs = append(errs, field.Duplicate(macPath, iface.GuestMAC))
+		}
+
+		macs[mac.String()] = true
+	}
+
+	seen := map[string]bool{}
+
+	for i, cfg := range s.NetworkInterfaceConfigs {
+		cfgPath := fieldPath.Child("networkInterfaceConfigs").Index(i).Child("guestDeviceName")
+
+		if !devices[cfg.GuestDeviceName] {
+			errs = append(errs, field.NotFound(cfgPath, cfg.GuestDeviceName))
+		}
+
+		if seen[cfg.GuestDeviceName] {
+			errs = append(errs, field.Duplicate(cfgPath, cfg.GuestDeviceName))
+		}
+
+		seen[cfg.GuestDeviceName] = true
+
+		if cfg.Gateway != "" && net.ParseIP(cfg.Gateway) == nil {
+			gatewayPath := fieldPath.Child("networkInterfaceConfigs").Index(i).Child("gateway")
+			errs = append(errs, field.Invalid(gatewayPath, cfg.Gateway, "must be an IP address"))
+		}
+
+		for j, nameserver := range cfg.Nameservers {
+			if net.ParseIP(nameserver) == nil {
+				nameserverPath := fieldPath.Child("networkInterfaceConfigs").Index(i).Child("nameservers").Index(j)
+				errs = append(errs, field.Invalid(nameserverPath, nameserver, "must be an IP address"))
+			}
+		}
+
+		for j, route := range cfg.Routes {
+			routePath := fieldPath.Child("networkInterfaceConfigs").Index(i).Child("routes").Index(j)
+
+			if _, _, err := net.ParseCIDR(route.To); err != nil {
+				errs = append(errs, field.Invalid(routePath.Child("to"), route.To, "must be in CIDR notation"))
+			}
+
+			if net.ParseIP(route.Via) == nil {
+				errs = append(errs, field.Invalid(routePath.Child("via"), route.Via, "must be an IP address"))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateIdentityRef checks that credentials aren't also set in the namespace of the cluster
+// when an identity is used.
+func (s *MicrovmClusterSpec) validateIdentityRef() field.ErrorList {
+	var errs field.ErrorList
+
+	const forbidden = "cannot be used with identityRef"
+
+	if s.TLSSecretRef != "" {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "tlsSecretRef"), forbidden))
+	}
+
+	if s.Placement.StaticPool == nil {
+		return errs
+	}
+
+	if s.Placement.StaticPool.BasicAuthSecret != "" {
+		fieldPath := field.NewPath("spec", "placement", "staticPool", "basicAuthSecret")
+		errs = append(errs, field.Forbidden(fieldPath, forbidden))
+	}
+
+	for i, host := range s.Placement.StaticPool.Hosts {
+		if host.TLSSecretRef != "" {
+			fieldPath := field.NewPath("spec", "placement", "staticPool", "hosts").Index(i).Child("tlsSecretRef")
+			errs = append(errs, field.Forbidden(fieldPath, forbidden))
+		}
+	}
+
+	return errs
+}
+
+// validateClientCertificate checks that other cluster wide client credentials aren't also set
+// when the client certificate is issued by cert-manager.
+func (s *MicrovmClusterSpec) validateClientCertificate() field.ErrorList {
+	var errs field.ErrorList
+
+	const forbidden = "cannot be used with clientCertificate"
+
+	if s.TLSSecretRef != "" {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "tlsSecretRef"), forbidden))
+	}
+
+	if s.IdentityRef != nil {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "identityRef"), forbidden))
+	}
+
+	return errs
+}
+
+// validate checks that the timeouts and backoff are positive.
+func (c *FlintlockCallsSpec) validate(fieldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	durations := []struct {
+		name     string
+		duration *metav1.Duration
+	}{
+		{name: "createTimeout", duration: c.CreateTimeout},
+		{name: "deleteTimeout", duration: c.DeleteTimeout},
+		{name: "getTimeout", duration: c.GetTimeout},
+		{name: "listTimeout", duration: c.ListTimeout},
+		{name: "retryBackoff", duration: c.RetryBackoff},
+	}
+
+	for _, d := range durations {
+		if d.duration != nil && d.duration.Duration <= 0 {
+			errs = append(errs, field.Invalid(fieldPath.Child(d.name), d.duration.String(), "must be greater than zero"))
+		}
 	}
 
 	return errs
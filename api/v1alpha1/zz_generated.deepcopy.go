@@ -10,11 +10,283 @@ package v1alpha1
 import (
 	"github.com/liquidmetal-dev/controller-pkg/client"
 	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/errors"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllowedNamespaces) DeepCopyInto(out *AllowedNamespaces) {
+	*out = *in
+	if in.NamespaceList != nil {
+		in, out := &in.NamespaceList, &out.NamespaceList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllowedNamespaces.
+func (in *AllowedNamespaces) DeepCopy() *AllowedNamespaces {
+	if in == nil {
+		return nil
+	}
+	out := new(AllowedNamespaces)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPConfig) DeepCopyInto(out *BGPConfig) {
+	*out = *in
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]BGPPeer, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPConfig.
+func (in *BGPConfig) DeepCopy() *BGPConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPPeer) DeepCopyInto(out *BGPPeer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeer.
+func (in *BGPPeer) DeepCopy() *BGPPeer {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPPeer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateIssuerReference) DeepCopyInto(out *CertificateIssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateIssuerReference.
+func (in *CertificateIssuerReference) DeepCopy() *CertificateIssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateIssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientCertificateSpec) DeepCopyInto(out *ClientCertificateSpec) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+	if in.Organizations != nil {
+		in, out := &in.Organizations, &out.Organizations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientCertificateSpec.
+func (in *ClientCertificateSpec) DeepCopy() *ClientCertificateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientCertificateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlintlockCallsSpec) DeepCopyInto(out *FlintlockCallsSpec) {
+	*out = *in
+	if in.CreateTimeout != nil {
+		in, out := &in.CreateTimeout, &out.CreateTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DeleteTimeout != nil {
+		in, out := &in.DeleteTimeout, &out.DeleteTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.GetTimeout != nil {
+		in, out := &in.GetTimeout, &out.GetTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ListTimeout != nil {
+		in, out := &in.ListTimeout, &out.ListTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RetryBackoff != nil {
+		in, out := &in.RetryBackoff, &out.RetryBackoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlintlockCallsSpec.
+func (in *FlintlockCallsSpec) DeepCopy() *FlintlockCallsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FlintlockCallsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostCapabilities) DeepCopyInto(out *HostCapabilities) {
+	*out = *in
+	if in.Providers != nil {
+		in, out := &in.Providers, &out.Providers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostCapabilities.
+func (in *HostCapabilities) DeepCopy() *HostCapabilities {
+	if in == nil {
+		return nil
+	}
+	out := new(HostCapabilities)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostNetwork) DeepCopyInto(out *HostNetwork) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostNetwork.
+func (in *HostNetwork) DeepCopy() *HostNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(HostNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostNetworkOverride) DeepCopyInto(out *HostNetworkOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostNetworkOverride.
+func (in *HostNetworkOverride) DeepCopy() *HostNetworkOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(HostNetworkOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostProxy) DeepCopyInto(out *HostProxy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostProxy.
+func (in *HostProxy) DeepCopy() *HostProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(HostProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostStatus) DeepCopyInto(out *HostStatus) {
+	*out = *in
+	if in.DeclaredProviders != nil {
+		in, out := &in.DeclaredProviders, &out.DeclaredProviders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MicrovmSpecFields != nil {
+		in, out := &in.MicrovmSpecFields, &out.MicrovmSpecFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Reachable != nil {
+		in, out := &in.Reachable, &out.Reachable
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostStatus.
+func (in *HostStatus) DeepCopy() *HostStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HostStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerSpec) DeepCopyInto(out *LoadBalancerSpec) {
+	*out = *in
+	if in.BGP != nil {
+		in, out := &in.BGP, &out.BGP
+		*out = new(BGPConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerSpec.
+func (in *LoadBalancerSpec) DeepCopy() *LoadBalancerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MicrovmCluster) DeepCopyInto(out *MicrovmCluster) {
 	*out = *in
@@ -42,6 +314,99 @@ func (in *MicrovmCluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MicrovmClusterIdentity) DeepCopyInto(out *MicrovmClusterIdentity) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrovmClusterIdentity.
+func (in *MicrovmClusterIdentity) DeepCopy() *MicrovmClusterIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(MicrovmClusterIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MicrovmClusterIdentity) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MicrovmClusterIdentityList) DeepCopyInto(out *MicrovmClusterIdentityList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MicrovmClusterIdentity, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrovmClusterIdentityList.
+func (in *MicrovmClusterIdentityList) DeepCopy() *MicrovmClusterIdentityList {
+	if in == nil {
+		return nil
+	}
+	out := new(MicrovmClusterIdentityList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MicrovmClusterIdentityList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MicrovmClusterIdentityReference) DeepCopyInto(out *MicrovmClusterIdentityReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrovmClusterIdentityReference.
+func (in *MicrovmClusterIdentityReference) DeepCopy() *MicrovmClusterIdentityReference {
+	if in == nil {
+		return nil
+	}
+	out := new(MicrovmClusterIdentityReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MicrovmClusterIdentitySpec) DeepCopyInto(out *MicrovmClusterIdentitySpec) {
+	*out = *in
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(AllowedNamespaces)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrovmClusterIdentitySpec.
+func (in *MicrovmClusterIdentitySpec) DeepCopy() *MicrovmClusterIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MicrovmClusterIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MicrovmClusterList) DeepCopyInto(out *MicrovmClusterList) {
 	*out = *in
@@ -78,6 +443,16 @@ func (in *MicrovmClusterList) DeepCopyObject() runtime.Object {
 func (in *MicrovmClusterSpec) DeepCopyInto(out *MicrovmClusterSpec) {
 	*out = *in
 	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	if in.ControlPlaneEndpointFromPool != nil {
+		in, out := &in.ControlPlaneEndpointFromPool, &out.ControlPlaneEndpointFromPool
+		*out = new(v1.TypedLocalObjectReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LoadBalancer != nil {
+		in, out := &in.LoadBalancer, &out.LoadBalancer
+		*out = new(LoadBalancerSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.SSHPublicKeys != nil {
 		in, out := &in.SSHPublicKeys, &out.SSHPublicKeys
 		*out = make([]microvm.SSHPublicKey, len(*in))
@@ -85,12 +460,34 @@ func (in *MicrovmClusterSpec) DeepCopyInto(out *MicrovmClusterSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SSHPublicKeysFrom != nil {
+		in, out := &in.SSHPublicKeysFrom, &out.SSHPublicKeysFrom
+		*out = make([]SSHPublicKeySource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Placement.DeepCopyInto(&out.Placement)
 	if in.MicrovmProxy != nil {
 		in, out := &in.MicrovmProxy, &out.MicrovmProxy
 		*out = new(client.Proxy)
 		**out = **in
 	}
+	if in.IdentityRef != nil {
+		in, out := &in.IdentityRef, &out.IdentityRef
+		*out = new(MicrovmClusterIdentityReference)
+		**out = **in
+	}
+	if in.ClientCertificate != nil {
+		in, out := &in.ClientCertificate, &out.ClientCertificate
+		*out = new(ClientCertificateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FlintlockCalls != nil {
+		in, out := &in.FlintlockCalls, &out.FlintlockCalls
+		*out = new(FlintlockCallsSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrovmClusterSpec.
@@ -120,6 +517,13 @@ func (in *MicrovmClusterStatus) DeepCopyInto(out *MicrovmClusterStatus) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]HostStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrovmClusterStatus.
@@ -135,6 +539,26 @@ func (in *MicrovmClusterStatus) DeepCopy() *MicrovmClusterStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MicrovmHost) DeepCopyInto(out *MicrovmHost) {
 	*out = *in
+	if in.NetworkOverrides != nil {
+		in, out := &in.NetworkOverrides, &out.NetworkOverrides
+		*out = make([]HostNetworkOverride, len(*in))
+		copy(*out, *in)
+	}
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]HostNetwork, len(*in))
+		copy(*out, *in)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(HostProxy)
+		**out = **in
+	}
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = new(HostCapabilities)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MicrovmHost.
@@ -217,6 +641,27 @@ func (in *MicrovmMachineSpec) DeepCopyInto(out *MicrovmMachineSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SSHPublicKeysFrom != nil {
+		in, out := &in.SSHPublicKeysFrom, &out.SSHPublicKeysFrom
+		*out = make([]SSHPublicKeySource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InstanceMetadata != nil {
+		in, out := &in.InstanceMetadata, &out.InstanceMetadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NetworkInterfaceConfigs != nil {
+		in, out := &in.NetworkInterfaceConfigs, &out.NetworkInterfaceConfigs
+		*out = make([]NetworkInterfaceConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ProviderID != nil {
 		in, out := &in.ProviderID, &out.ProviderID
 		*out = new(string)
@@ -367,6 +812,48 @@ func (in *MicrovmMachineTemplateSpec) DeepCopy() *MicrovmMachineTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkInterfaceConfig) DeepCopyInto(out *NetworkInterfaceConfig) {
+	*out = *in
+	if in.AddressFromPool != nil {
+		in, out := &in.AddressFromPool, &out.AddressFromPool
+		*out = new(v1.TypedLocalObjectReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(int32)
+		**out = **in
+	}
+	if in.VLANID != nil {
+		in, out := &in.VLANID, &out.VLANID
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]Route, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterfaceConfig.
+func (in *NetworkInterfaceConfig) DeepCopy() *NetworkInterfaceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkInterfaceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Placement) DeepCopyInto(out *Placement) {
 	*out = *in
@@ -387,6 +874,26 @@ func (in *Placement) DeepCopy() *Placement {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route) DeepCopyInto(out *Route) {
+	*out = *in
+	if in.Metric != nil {
+		in, out := &in.Metric, &out.Metric
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route.
+func (in *Route) DeepCopy() *Route {
+	if in == nil {
+		return nil
+	}
+	out := new(Route)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSHPublicKey) DeepCopyInto(out *SSHPublicKey) {
 	*out = *in
@@ -407,13 +914,40 @@ func (in *SSHPublicKey) DeepCopy() *SSHPublicKey {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHPublicKeySource) DeepCopyInto(out *SSHPublicKeySource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHPublicKeySource.
+func (in *SSHPublicKeySource) DeepCopy() *SSHPublicKeySource {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHPublicKeySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StaticPoolPlacement) DeepCopyInto(out *StaticPoolPlacement) {
 	*out = *in
 	if in.Hosts != nil {
 		in, out := &in.Hosts, &out.Hosts
 		*out = make([]MicrovmHost, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -0,0 +1,56 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// The reasons of the events recorded for MicrovmMachines. Events about errors that are also
+// reported in the MicrovmReady condition use the reason of the condition.
+const (
+	// HostSelectedEventReason is recorded when the host a microvm will be created on is chosen.
+	HostSelectedEventReason = "HostSelected"
+	// CreateRequestedEventReason is recorded when a host has been asked to create a microvm.
+	CreateRequestedEventReason = "CreateRequested"
+	// CreateFailedEventReason is recorded when a host couldn't be asked to create a microvm.
+	CreateFailedEventReason = "CreateFailed"
+	// MicrovmPendingEventReason is recorded when a microvm is waiting to be started.
+	MicrovmPendingEventReason = "MicrovmPending"
+	// MicrovmRunningEventReason is recorded when a microvm has been created and is running.
+	MicrovmRunningEventReason = "MicrovmRunning"
+	// MicrovmFailedEventReason is recorded when a host reports that a microvm has failed.
+	MicrovmFailedEventReason = "MicrovmFailed"
+	// DeleteRequestedEventReason is recorded when a host has been asked to delete a microvm.
+	DeleteRequestedEventReason = "DeleteRequested"
+	// DeleteFailedEventReason is recorded when a host couldn't be asked to delete a microvm.
+	DeleteFailedEventReason = "DeleteFailed"
+	// DeletedEventReason is recorded when a microvm no longer exists and the machine can be removed.
+	DeletedEventReason = "Deleted"
+)
+
+// The reasons of the events recorded for MicrovmClusters. Events about credentials and client
+// certificates use the reason of the condition they're reported in.
+const (
+	// HostUnreachableEventReason is recorded when a host stops responding.
+	HostUnreachableEventReason = "HostUnreachable"
+	// HostReachableEventReason is recorded when a host that wasn't responding responds again.
+	HostReachableEventReason = "HostReachable"
+)
+
+// recordEvent records an event for the object. Reconcilers created without a recorder, such as
+// in tests, don't record events.
+func recordEvent(
+	recorder record.EventRecorder,
+	object runtime.Object,
+	eventType, reason, messageFmt string,
+	args ...interface{},
+) {
+	if recorder == nil {
+		return
+	}
+
+	recorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
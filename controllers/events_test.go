@@ -0,0 +1,328 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	fakeremote "sigs.k8s.io/cluster-api/controllers/remote/fake"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/circuitbreaker"
+)
+
+func TestMachineReconcileCreateEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	recorder := record.NewFakeRecorder(10)
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+
+	_, err := reconcileMachineWithRecorder(client, &fakeAPIClient, recorder)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(recordedEvents(recorder)).To(Equal([]string{
+		"Normal HostSelected creating microvm on host host1",
+		"Normal CreateRequested microvm ABCDEF123456 requested on host host1",
+		"Normal MicrovmPending microvm is pending on host host1",
+	}))
+
+	withExistingMicrovm(&fakeAPIClient, flintlocktypes.MicroVMStatus_PENDING)
+
+	_, err = reconcileMachineWithRecorder(client, &fakeAPIClient, recorder)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recordedEvents(recorder)).To(BeEmpty(), "expected no events while the microvm stays pending")
+}
+
+func TestMachineReconcileStateEvents(t *testing.T) {
+	testCases := []struct {
+		name     string
+		state    flintlocktypes.MicroVMStatus_MicroVMState
+		expected string
+	}{
+		{
+			name:     "running",
+			state:    flintlocktypes.MicroVMStatus_CREATED,
+			expected: "Normal MicrovmRunning microvm is running on host host1",
+		},
+		{
+			name:     "failed",
+			state:    flintlocktypes.MicroVMStatus_FAILED,
+			expected: "Warning MicrovmFailed microvm failed on host host1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			apiObjects := defaultClusterObjects()
+
+			fakeAPIClient := fakes.FakeClient{}
+			withExistingMicrovm(&fakeAPIClient, tc.state)
+
+			recorder := record.NewFakeRecorder(10)
+			client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+
+			_, _ = reconcileMachineWithRecorder(client, &fakeAPIClient, recorder)
+			g.Expect(recordedEvents(recorder)).To(Equal([]string{tc.expected}))
+
+			_, _ = reconcileMachineWithRecorder(client, &fakeAPIClient, recorder)
+			g.Expect(recordedEvents(recorder)).To(BeEmpty(), "expected no events while the state is unchanged")
+		})
+	}
+}
+
+func TestMachineReconcileDeleteEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.DeletionTimestamp = &metav1.Time{
+		Time: time.Now(),
+	}
+	apiObjects.MvmMachine.Finalizers = []string{infrav1.MachineFinalizer}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withExistingMicrovm(&fakeAPIClient, flintlocktypes.MicroVMStatus_CREATED)
+
+	recorder := record.NewFakeRecorder(10)
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+
+	_, err := reconcileMachineWithRecorder(client, &fakeAPIClient, recorder)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recordedEvents(recorder)).To(Equal([]string{
+		"Normal DeleteRequested deleting microvm on host host1",
+	}))
+
+	withMissingMicrovm(&fakeAPIClient)
+
+	_, err = reconcileMachineWithRecorder(client, &fakeAPIClient, recorder)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recordedEvents(recorder)).To(Equal([]string{
+		"Normal Deleted microvm deleted from host host1",
+	}))
+}
+
+func TestMachineReconcileCreateFailedEvent(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	fakeAPIClient.CreateMicroVMReturns(nil, status.Error(codes.Internal, "out of disk"))
+
+	recorder := record.NewFakeRecorder(10)
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+
+	_, err := reconcileMachineWithRecorder(client, &fakeAPIClient, recorder)
+	g.Expect(err).To(HaveOccurred())
+
+	events := recordedEvents(recorder)
+	g.Expect(events).To(HaveLen(2))
+	g.Expect(events[0]).To(Equal("Normal HostSelected creating microvm on host host1"))
+	g.Expect(events[1]).To(HavePrefix("Warning CreateFailed creating microvm on host host1: "))
+	g.Expect(events[1]).To(ContainSubstring("out of disk"))
+}
+
+func TestMachineReconcileConditionWarningEventsRecordedOnce(t *testing.T) {
+	testCases := []struct {
+		name     string
+		setup    func(apiObjects clusterObjects, fakeAPIClient *fakes.FakeClient)
+		breakers *circuitbreaker.Breakers
+		reason   string
+	}{
+		{
+			name: "no compatible host",
+			setup: func(apiObjects clusterObjects, _ *fakes.FakeClient) {
+				apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].Capabilities = &infrav1.HostCapabilities{
+					Providers: []string{"firecracker"},
+				}
+				apiObjects.MvmMachine.Spec.ProviderID = nil
+				apiObjects.MvmMachine.Spec.Provider = "cloudhypervisor"
+			},
+			reason: infrav1.NoCompatibleHostReason,
+		},
+		{
+			name: "credentials error",
+			setup: func(apiObjects clusterObjects, _ *fakes.FakeClient) {
+				apiObjects.MvmCluster.Spec.TLSSecretRef = "missing-tls"
+			},
+			reason: infrav1.CredentialsErrorReason,
+		},
+		{
+			name: "flintlock timeout",
+			setup: func(_ clusterObjects, fakeAPIClient *fakes.FakeClient) {
+				fakeAPIClient.GetMicroVMReturns(nil, status.Error(codes.DeadlineExceeded, "deadline exceeded"))
+			},
+			reason: infrav1.FlintlockTimeoutReason,
+		},
+		{
+			name: "host unavailable",
+			setup: func(_ clusterObjects, fakeAPIClient *fakes.FakeClient) {
+				fakeAPIClient.GetMicroVMReturns(nil, status.Error(codes.Unavailable, "connection refused"))
+			},
+			breakers: circuitbreaker.New(circuitbreaker.Config{FailureThreshold: 1, Cooldown: time.Minute}),
+			reason:   infrav1.HostUnavailableReason,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			apiObjects := defaultClusterObjects()
+			fakeAPIClient := &fakes.FakeClient{}
+			tc.setup(apiObjects, fakeAPIClient)
+
+			recorder := record.NewFakeRecorder(10)
+			client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+			machineController := &controllers.MicrovmMachineReconciler{
+				Client: client,
+				MvmClientFunc: func(_ string, _ ...flclient.Options) (flclient.Client, error) {
+					return fakeAPIClient, nil
+				},
+				CircuitBreakers:   tc.breakers,
+				IdentityNamespace: testIdentityNamespace,
+				Recorder:          recorder,
+			}
+			request := ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: testMachineName, Namespace: testClusterNamespace},
+			}
+
+			reconcileUntilReason := func() {
+				// The circuit breaker opens after the first failed call, so the host is only
+				// reported as unavailable from the second reconcile.
+				for i := 0; i < 3; i++ {
+					_, _ = machineController.Reconcile(context.TODO(), request)
+
+					reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+					g.Expect(err).NotTo(HaveOccurred())
+
+					if conditions.GetReason(reconciled, infrav1.MicrovmReadyCondition) == tc.reason {
+						return
+					}
+				}
+			}
+
+			reconcileUntilReason()
+			g.Expect(recordedEvents(recorder)).To(ContainElement(HavePrefix("Warning " + tc.reason + " ")))
+
+			_, _ = machineController.Reconcile(context.TODO(), request)
+			g.Expect(recordedEvents(recorder)).NotTo(ContainElement(HavePrefix("Warning "+tc.reason+" ")),
+				"expected the problem to only be reported once")
+		})
+	}
+}
+
+func TestClusterReconcileCredentialsEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.TLSSecretRef = "cluster-tls"
+
+	recorder := record.NewFakeRecorder(10)
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+
+	_, err := reconcileClusterWithRecorder(client, recorder)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	events := recordedEvents(recorder)
+	g.Expect(events).To(ContainElement(HavePrefix("Warning " + infrav1.CredentialsSecretNotFoundReason + " ")))
+	g.Expect(events).To(ContainElement(HavePrefix("Warning " + infrav1.TLSCertificateInvalidReason + " ")))
+
+	_, err = reconcileClusterWithRecorder(client, recorder)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recordedEvents(recorder)).To(BeEmpty(), "expected the problems to only be reported once")
+}
+
+func TestClusterReconcileHostReachabilityEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+
+	hostClient := &fakes.FakeClient{}
+	hostClient.ListMicroVMsReturns(nil, status.Error(codes.Unavailable, "connection refused"))
+
+	recorder := record.NewFakeRecorder(10)
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	clusterController := &controllers.MicrovmClusterReconciler{
+		Client:             client,
+		RemoteClientGetter: fakeremote.NewClusterClient,
+		IdentityNamespace:  testIdentityNamespace,
+		Recorder:           recorder,
+		MvmClientFunc: func(_ string, _ ...flclient.Options) (flclient.Client, error) {
+			return hostClient, nil
+		},
+		// Check the host on every reconcile.
+		HostCheckInterval: time.Nanosecond,
+	}
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace},
+	}
+
+	_, err := clusterController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	events := recordedEvents(recorder)
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0]).To(HavePrefix("Warning HostUnreachable host host1 (127.0.0.1:9090) isn't responding: "))
+
+	_, err = clusterController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recordedEvents(recorder)).To(BeEmpty(), "expected an unreachable host to only be reported once")
+
+	hostClient.ListMicroVMsReturns(nil, nil)
+
+	_, err = clusterController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recordedEvents(recorder)).To(Equal([]string{
+		"Normal HostReachable host host1 (127.0.0.1:9090) is responding again",
+	}))
+}
+
+// recordedEvents returns the events recorded since it was last called.
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	events := []string{}
+
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
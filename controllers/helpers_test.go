@@ -4,9 +4,12 @@
 package controllers_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"gopkg.in/yaml.v2"
@@ -17,9 +20,11 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	fakeremote "sigs.k8s.io/cluster-api/controllers/remote/fake"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,6 +33,7 @@ import (
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/tlstest"
 	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
 	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
 	"github.com/liquidmetal-dev/flintlock/client/cloudinit/userdata"
@@ -40,6 +46,8 @@ const (
 	testMachineUID          = "ABCDEF123456"
 	testBootstrapSecretName = "bootstrap"
 	testbootStrapData       = "somesamplebootstrapsdata"
+	testIdentityNamespace   = "capmvm-system"
+	ed25519KeySize          = 32
 )
 
 func defaultClusterObjects() clusterObjects {
@@ -85,11 +93,21 @@ func (co clusterObjects) AsRuntimeObjects() []runtime.Object {
 }
 
 func reconcileMachine(client client.Client, mockAPIClient flclient.Client) (ctrl.Result, error) {
+	return reconcileMachineWithRecorder(client, mockAPIClient, nil)
+}
+
+func reconcileMachineWithRecorder(
+	client client.Client,
+	mockAPIClient flclient.Client,
+	recorder record.EventRecorder,
+) (ctrl.Result, error) {
 	machineController := &controllers.MicrovmMachineReconciler{
 		Client: client,
 		MvmClientFunc: func(address string, opts ...flclient.Options) (flclient.Client, error) {
 			return mockAPIClient, nil
 		},
+		IdentityNamespace: testIdentityNamespace,
+		Recorder:          recorder,
 	}
 
 	request := ctrl.Request{
@@ -103,9 +121,33 @@ func reconcileMachine(client client.Client, mockAPIClient flclient.Client) (ctrl
 }
 
 func reconcileCluster(client client.Client) (ctrl.Result, error) {
+	return reconcileClusterWithLoadBalancer(client, nil)
+}
+
+func reconcileClusterWithRecorder(client client.Client, recorder record.EventRecorder) (ctrl.Result, error) {
 	clusterController := &controllers.MicrovmClusterReconciler{
 		Client:             client,
 		RemoteClientGetter: fakeremote.NewClusterClient,
+		IdentityNamespace:  testIdentityNamespace,
+		Recorder:           recorder,
+	}
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      testClusterName,
+			Namespace: testClusterNamespace,
+		},
+	}
+
+	return clusterController.Reconcile(context.TODO(), request)
+}
+
+func reconcileClusterWithLoadBalancer(client client.Client, lbCheck controllers.LoadBalancerCheckFunc) (ctrl.Result, error) {
+	clusterController := &controllers.MicrovmClusterReconciler{
+		Client:              client,
+		RemoteClientGetter:  fakeremote.NewClusterClient,
+		LoadBalancerChecker: lbCheck,
+		IdentityNamespace:   testIdentityNamespace,
 	}
 
 	request := ctrl.Request{
@@ -162,14 +204,32 @@ func getMachine(c client.Client, name, namespace string) (*clusterv1.Machine, er
 	return machine, err
 }
 
+func getIPAddressClaim(c client.Client, name, namespace string) (*ipamv1.IPAddressClaim, error) {
+	claimKey := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
+	}
+
+	claim := &ipamv1.IPAddressClaim{}
+	err := c.Get(context.TODO(), claimKey, claim)
+	return claim, err
+}
+
 func createFakeClient(g *WithT, objects []runtime.Object) client.Client {
 	scheme := runtime.NewScheme()
 
 	g.Expect(infrav1.AddToScheme(scheme)).To(Succeed())
 	g.Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
 	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
-
-	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()
+	g.Expect(ipamv1.AddToScheme(scheme)).To(Succeed())
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(objects...).
+		WithIndex(&infrav1.MicrovmCluster{}, controllers.MicrovmClusterSecretIndex, controllers.IndexMicrovmClusterBySecret).
+		WithIndex(&infrav1.MicrovmCluster{}, controllers.MicrovmClusterIdentityIndex, controllers.IndexMicrovmClusterByIdentity).
+		WithStatusSubresource(&infrav1.MicrovmCluster{}, &infrav1.MicrovmMachine{}, &ipamv1.IPAddressClaim{}).
+		Build()
 }
 
 func createMicrovmCluster() *infrav1.MicrovmCluster {
@@ -218,7 +278,7 @@ func createCluster() *clusterv1.Cluster {
 		Status: clusterv1.ClusterStatus{
 			InfrastructureReady: true,
 			FailureDomains: clusterv1.FailureDomains{
-				"127.0.0.1:9090": clusterv1.FailureDomainSpec{
+				"host1": clusterv1.FailureDomainSpec{
 					ControlPlane: true,
 				},
 			},
@@ -270,7 +330,7 @@ func createMicrovmMachine() *infrav1.MicrovmMachine {
 }
 
 func createMachine() *clusterv1.Machine {
-	testFailureDomain := "127.0.0.1:9090"
+	testFailureDomain := "host1"
 	return &clusterv1.Machine{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      testMachineName,
@@ -311,6 +371,43 @@ func createBootsrapSecret() *corev1.Secret {
 	}
 }
 
+func createTLSSecret(g *WithT, name string, notAfter time.Time) *corev1.Secret {
+	data, err := tlstest.NewSecretData(notAfter)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testClusterNamespace,
+		},
+		Data: data,
+	}
+}
+
+func createMicrovmClusterIdentity(name string, allowed *infrav1.AllowedNamespaces) *infrav1.MicrovmClusterIdentity {
+	return &infrav1.MicrovmClusterIdentity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: infrav1.MicrovmClusterIdentitySpec{
+			BasicAuthSecret:   "shared-basic-auth",
+			AllowedNamespaces: allowed,
+		},
+	}
+}
+
+// createSSHPublicKey returns a syntactically valid ed25519 public key in the authorized_keys format.
+func createSSHPublicKey(seed byte, comment string) string {
+	const keyType = "ssh-ed25519"
+
+	blob := binary.BigEndian.AppendUint32(nil, uint32(len(keyType)))
+	blob = append(blob, keyType...)
+	blob = binary.BigEndian.AppendUint32(blob, ed25519KeySize)
+	blob = append(blob, bytes.Repeat([]byte{seed}, ed25519KeySize)...)
+
+	return fmt.Sprintf("%s %s %s", keyType, base64.StdEncoding.EncodeToString(blob), comment)
+}
+
 func withExistingMicrovm(fc *fakes.FakeClient, mvmState flintlocktypes.MicroVMStatus_MicroVMState) {
 	fc.GetMicroVMReturns(&flintlockv1.GetMicroVMResponse{
 		Microvm: &flintlocktypes.MicroVM{
@@ -364,7 +461,7 @@ func assertMachineReconciled(g *WithT, reconciled *infrav1.MicrovmMachine) {
 	assertMachineVMState(g, reconciled, microvm.VMStateRunning)
 	assertMachineFinalizer(g, reconciled)
 	g.Expect(reconciled.Spec.ProviderID).ToNot(BeNil())
-	expectedProviderID := fmt.Sprintf("microvm://127.0.0.1:9090/%s", testMachineUID)
+	expectedProviderID := fmt.Sprintf("microvm://host1/%s", testMachineUID)
 	g.Expect(*reconciled.Spec.ProviderID).To(Equal(expectedProviderID))
 	g.Expect(reconciled.Status.Ready).To(BeTrue(), "The Ready property must be true when the machine has been reconciled")
 }
@@ -413,11 +510,19 @@ func assertVendorData(g *WithT, vendorDataRaw string, expectedSSHKeys []microvm.
 	for i, user := range users {
 		g.Expect(user.Name).To(Equal(expectedSSHKeys[i].User))
 
-		keys := user.SSHAuthorizedKeys
-		g.Expect(keys).To(HaveLen(1))
-		g.Expect(keys[0]).To(Equal(expectedSSHKeys[i].AuthorizedKeys[0]))
+		g.Expect(user.SSHAuthorizedKeys).To(Equal(expectedSSHKeys[i].AuthorizedKeys))
 	}
 
 	vendorDataStr := string(data)
 	g.Expect(vendorDataStr).To(ContainSubstring("#cloud-config\n"))
 }
+
+func decodeInstanceData(g *WithT, instanceDataRaw string) map[string]interface{} {
+	data, err := base64.StdEncoding.DecodeString(instanceDataRaw)
+	g.Expect(err).NotTo(HaveOccurred(), "expect instance data to be base64 encoded")
+
+	instanceData := map[string]interface{}{}
+	g.Expect(yaml.Unmarshal(data, &instanceData)).To(Succeed(), "expect instance data to unmarshall to a map")
+
+	return instanceData
+}
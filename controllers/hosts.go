@@ -0,0 +1,77 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package controllers
+
+import (
+	"fmt"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/circuitbreaker"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+// hostClients creates the clients used to call the flintlock hosts. The controllers share the
+// pool and circuit breakers so a host that is down is only called again once it may have
+// recovered, whichever controller calls it.
+type hostClients struct {
+	factory  flclient.FactoryFunc
+	pool     *clientpool.Pool
+	breakers *circuitbreaker.Breakers
+}
+
+// enabled returns true if clients can be created.
+func (h hostClients) enabled() bool {
+	return h.factory != nil || h.pool != nil
+}
+
+// newClient returns a client for the host of the cluster at the address that records the duration
+// and result of its calls in the metrics, traces and the circuit breaker of the host, and uses the
+// timeouts and retries of the policy. It returns an OpenError if the host shouldn't be called.
+func (h hostClients) newClient(
+	mvmCluster *infrav1.MicrovmCluster,
+	hostID string,
+	addr string,
+	creds clientpool.Credentials,
+	policy flintlock.CallPolicy,
+) (flclient.Client, error) {
+	if !h.enabled() {
+		return nil, errClientFactoryFuncRequired
+	}
+
+	if h.breakers != nil {
+		if err := h.breakers.Allow(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		client flclient.Client
+		err    error
+	)
+
+	if h.pool != nil {
+		client, err = h.pool.Get(addr, creds)
+	} else {
+		client, err = h.factory(addr, creds.Options()...)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("creating microvm client: %w", err)
+	}
+
+	client = flintlock.NewMetricsClient(client, mvmCluster.Namespace, mvmCluster.Name, hostID)
+	client = flintlock.NewTracingClient(client, addr)
+	client = flintlock.NewPolicyClient(client, policy)
+
+	// The circuit breaker wraps the policy so that a call is recorded once, whether or not it
+	// was retried. Otherwise the retries of a single call could open the circuit breaker.
+	if h.breakers != nil {
+		client = h.breakers.Wrap(addr, client)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+// MicrovmClusterSecretIndex is the field index of MicrovmClusters by the names of the
+// secrets they reference for connecting to the hosts.
+const MicrovmClusterSecretIndex = "spec.credentialsSecrets"
+
+// MicrovmClusterIdentityIndex is the field index of MicrovmClusters by the name of the
+// MicrovmClusterIdentity they reference.
+const MicrovmClusterIdentityIndex = "spec.identityRef.name"
+
+// SetupIndexes adds the field indexes used by the controllers to the manager. It must be
+// called before the controllers are set up.
+func SetupIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(
+		ctx,
+		&infrav1.MicrovmCluster{},
+		MicrovmClusterSecretIndex,
+		IndexMicrovmClusterBySecret,
+	); err != nil {
+		return fmt.Errorf("indexing microvm clusters by secret: %w", err)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(
+		ctx,
+		&infrav1.MicrovmCluster{},
+		MicrovmClusterIdentityIndex,
+		IndexMicrovmClusterByIdentity,
+	); err != nil {
+		return fmt.Errorf("indexing microvm clusters by identity: %w", err)
+	}
+
+	return nil
+}
+
+// IndexMicrovmClusterBySecret returns the names of the credentials secrets referenced by a
+// MicrovmCluster for use in MicrovmClusterSecretIndex.
+func IndexMicrovmClusterBySecret(o client.Object) []string {
+	mvmCluster, ok := o.(*infrav1.MicrovmCluster)
+	if !ok {
+		return nil
+	}
+
+	return scope.CredentialsSecretNames(mvmCluster)
+}
+
+// IndexMicrovmClusterByIdentity returns the name of the MicrovmClusterIdentity referenced by a
+// MicrovmCluster for use in MicrovmClusterIdentityIndex.
+func IndexMicrovmClusterByIdentity(o client.Object) []string {
+	mvmCluster, ok := o.(*infrav1.MicrovmCluster)
+	if !ok || mvmCluster.Spec.IdentityRef == nil {
+		return nil
+	}
+
+	return []string{mvmCluster.Spec.IdentityRef.Name}
+}
+
+// getMicrovmClustersForSecret returns the MicrovmClusters that use the secret, either directly
+// or, if the secret is in the identity namespace, via a MicrovmClusterIdentity.
+func getMicrovmClustersForSecret(
+	ctx context.Context,
+	c client.Client,
+	secret client.Object,
+	identityNamespace string,
+) ([]infrav1.MicrovmCluster, error) {
+	clusters := &infrav1.MicrovmClusterList{}
+	if err := c.List(
+		ctx,
+		clusters,
+		client.InNamespace(secret.GetNamespace()),
+		client.MatchingFields{MicrovmClusterSecretIndex: secret.GetName()},
+	); err != nil {
+		return nil, fmt.Errorf("listing microvm clusters for secret: %w", err)
+	}
+
+	result := clusters.Items
+
+	if identityNamespace == "" || secret.GetNamespace() != identityNamespace {
+		return result, nil
+	}
+
+	identities := &infrav1.MicrovmClusterIdentityList{}
+	if err := c.List(ctx, identities); err != nil {
+		return nil, fmt.Errorf("listing microvm cluster identities: %w", err)
+	}
+
+	for i := range identities.Items {
+		spec := identities.Items[i].Spec
+		if spec.TLSSecretRef != secret.GetName() && spec.BasicAuthSecret != secret.GetName() {
+			continue
+		}
+
+		identityClusters, err := getMicrovmClustersForIdentity(ctx, c, identities.Items[i].Name)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, identityClusters...)
+	}
+
+	return result, nil
+}
+
+// getMicrovmClustersForIdentity returns the MicrovmClusters that reference the identity.
+func getMicrovmClustersForIdentity(
+	ctx context.Context,
+	c client.Client,
+	identityName string,
+) ([]infrav1.MicrovmCluster, error) {
+	clusters := &infrav1.MicrovmClusterList{}
+	if err := c.List(
+		ctx,
+		clusters,
+		client.MatchingFields{MicrovmClusterIdentityIndex: identityName},
+	); err != nil {
+		return nil, fmt.Errorf("listing microvm clusters for identity: %w", err)
+	}
+
+	return clusters.Items, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+// microvmStateDeleting is the state of microvms that are being deleted by the controller. It's
+// the same as the state flintlock reports once it has started deleting them.
+const microvmStateDeleting = "deleting"
+
+// recordMicrovmState records the state of the microvm of the machine and, if it has just reached
+// the CREATED state, how long it took.
+func recordMicrovmState(
+	machineScope *scope.MachineScope,
+	host string,
+	previousState *microvm.VMState,
+	mvm *flintlocktypes.MicroVM,
+) {
+	mvmCluster := machineScope.MvmCluster
+	state := strings.ToLower(mvm.Status.State.String())
+
+	metrics.SetMicrovmState(mvmCluster.Namespace, mvmCluster.Name, machineScope.Name(), host, state)
+
+	if mvm.Status.State != flintlocktypes.MicroVMStatus_CREATED {
+		return
+	}
+
+	if previousState != nil && *previousState == microvm.VMStateRunning {
+		return
+	}
+
+	if createdAt := mvm.Spec.GetCreatedAt(); createdAt != nil {
+		metrics.ObserveMicrovmCreated(mvmCluster.Namespace, mvmCluster.Name, host, time.Since(createdAt.AsTime()))
+	}
+}
+
+// recordMicrovmError counts the machine becoming not ready because of an error or warning. Only
+// changes of the reason are counted, so a machine that stays failed across reconciles is only
+// counted once.
+func recordMicrovmError(machineScope *scope.MachineScope, previousReason string) {
+	condition := conditions.Get(machineScope.MvmMachine, infrav1.MicrovmReadyCondition)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason == previousReason {
+		return
+	}
+
+	if condition.Severity != clusterv1.ConditionSeverityError && condition.Severity != clusterv1.ConditionSeverityWarning {
+		return
+	}
+
+	mvmCluster := machineScope.MvmCluster
+	metrics.IncMicrovmErrors(mvmCluster.Namespace, mvmCluster.Name, metricsHost(machineScope), condition.Reason)
+}
+
+// metricsHost returns the ID of the host the machine is on, or the failure domain chosen by
+// Cluster API if it hasn't been placed yet. It's empty if neither is known.
+func metricsHost(machineScope *scope.MachineScope) string {
+	if hostID := machineScope.MvmMachine.GetAnnotations()[infrav1.HostIDAnnotation]; hostID != "" {
+		return hostID
+	}
+
+	if failureDomain := machineScope.Machine.Spec.FailureDomain; failureDomain != nil {
+		return *failureDomain
+	}
+
+	return ""
+}
@@ -5,15 +5,25 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-logr/logr"
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/remote"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
@@ -22,19 +32,46 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/circuitbreaker"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/defaults"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/identity"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/tracing"
 )
 
 const (
 	requeuePeriod = 30 * time.Second
+
+	loadBalancerDialTimeout = 5 * time.Second
+
+	// DefaultTLSExpiryWarningPeriod is how long before a client certificate expires that it's
+	// reported as expiring.
+	DefaultTLSExpiryWarningPeriod = 30 * 24 * time.Hour
+
+	// DefaultHostCheckInterval is how often the hosts of a MicrovmCluster are checked once the
+	// cluster is ready.
+	DefaultHostCheckInterval = time.Minute
+
+	// DefaultHostCheckTimeout is how long the call made to check that a host is reachable can
+	// take.
+	DefaultHostCheckTimeout = 5 * time.Second
 )
 
+// LoadBalancerCheckFunc is used to check that a load balancer managed by the provider is
+// serving the control plane endpoint.
+type LoadBalancerCheckFunc func(ctx context.Context, endpoint clusterv1.APIEndpoint) error
+
 // MicrovmClusterReconciler reconciles a MicrovmCluster object.
 type MicrovmClusterReconciler struct {
 	client.Client
@@ -42,26 +79,69 @@ type MicrovmClusterReconciler struct {
 	Recorder         record.EventRecorder
 	WatchFilterValue string
 
-	RemoteClientGetter remote.ClusterClientGetter
+	RemoteClientGetter  remote.ClusterClientGetter
+	LoadBalancerChecker LoadBalancerCheckFunc
+
+	// TLSExpiryWarningPeriod is how long before a client certificate expires that it's reported
+	// as expiring. Defaults to DefaultTLSExpiryWarningPeriod.
+	TLSExpiryWarningPeriod time.Duration
+	// HostCheckInterval is how often the hosts of a ready cluster are checked, so that their
+	// status stays current. Defaults to DefaultHostCheckInterval.
+	HostCheckInterval time.Duration
+	// HostCheckTimeout is how long the call made to check that a host is reachable can take. It
+	// replaces the list timeout of the call policy, so that a host that isn't responding doesn't
+	// hold up the reconcile. Defaults to DefaultHostCheckTimeout.
+	HostCheckTimeout time.Duration
+	// IdentityNamespace is the namespace that the secrets of MicrovmClusterIdentities are in.
+	IdentityNamespace string
+	// ClientPool is the connection pool shared with the machine controller. The connections to
+	// the hosts of a deleted cluster are closed by the pool once they're idle, as they may be
+	// shared with other clusters.
+	ClientPool *clientpool.Pool
+
+	// MvmClientFunc creates the clients used to check that the hosts are reachable if ClientPool
+	// isn't set. If neither is set the hosts aren't checked.
+	MvmClientFunc flclient.FactoryFunc
+	// DescribeHostFunc finds out what the API of a host supports when the host is checked. If it
+	// isn't set the hosts aren't described.
+	DescribeHostFunc flintlock.DescribeHostFunc
+	// CircuitBreakers are the circuit breakers shared with the machine controller.
+	CircuitBreakers *circuitbreaker.Breakers
+	// CallPolicy is the default timeouts of the calls to the hosts. MicrovmClusters can override
+	// it. Calls made to check the hosts aren't retried.
+	CallPolicy flintlock.CallPolicy
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmclusters/finalizers,verbs=update
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmmachines,verbs=get;list;watch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddressclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddresses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmclusteridentities,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.8.3/pkg/reconcile
-func (r *MicrovmClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *MicrovmClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	ctx, span := tracing.StartReconcile(ctx, "MicrovmCluster", req.NamespacedName)
+	defer func() { tracing.End(span, reterr) }()
+
 	log := log.FromContext(ctx)
 	mvmCluster := &infrav1.MicrovmCluster{}
 
 	err := r.Get(ctx, req.NamespacedName, mvmCluster)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			metrics.DeleteClusterMetrics(req.Namespace, req.Name)
+
 			return ctrl.Result{}, nil
 		}
 
@@ -92,7 +172,8 @@ func (r *MicrovmClusterReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	scope, err := scope.NewClusterScope(cluster,
 		mvmCluster,
 		r.Client,
-		scope.WithClusterLogger(log.WithValues("microvmcluster", req.NamespacedName)))
+		scope.WithClusterLogger(log.WithValues("microvmcluster", req.NamespacedName)),
+		scope.WithClusterIdentityNamespace(r.IdentityNamespace))
 	if err != nil {
 		log.Error(err, "creating cluster scope")
 
@@ -115,12 +196,17 @@ func (r *MicrovmClusterReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 }
 
 func (r *MicrovmClusterReconciler) reconcileDelete(
-	_ context.Context,
+	ctx context.Context,
 	clusterScope *scope.ClusterScope,
 ) (reconcile.Result, error) {
 	clusterScope.Info("Reconciling MicrovmCluster delete")
 
-	// We currently do not do any Cluster creation so there is nothing to delete.
+	if err := clusterScope.ReleaseControlPlaneEndpoint(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("releasing control plane endpoint: %w", err)
+	}
+
+	controllerutil.RemoveFinalizer(clusterScope.MvmCluster, infrav1.ClusterFinalizer)
+	metrics.DeleteClusterMetrics(clusterScope.MvmCluster.Namespace, clusterScope.MvmCluster.Name)
 
 	return reconcile.Result{}, nil
 }
@@ -131,16 +217,78 @@ func (r *MicrovmClusterReconciler) reconcileNormal(
 ) (reconcile.Result, error) {
 	cScope.Info("Reconciling MicrovmCluster")
 
+	if cScope.MvmCluster.Spec.ControlPlaneEndpointFromPool != nil {
+		controllerutil.AddFinalizer(cScope.MvmCluster, infrav1.ClusterFinalizer)
+
+		if err := cScope.Patch(); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		allocated, err := cScope.ReconcileControlPlaneEndpoint(ctx)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("reconciling control plane endpoint: %w", err)
+		}
+
+		if !allocated {
+			cScope.Info("Control plane endpoint is not allocated yet")
+			conditions.MarkFalse(
+				cScope.MvmCluster,
+				infrav1.LoadBalancerAvailableCondition,
+				infrav1.WaitingForControlPlaneEndpointReason,
+				clusterv1.ConditionSeverityInfo,
+				"",
+			)
+
+			return reconcile.Result{}, nil
+		}
+	}
+
 	if cScope.Cluster.Spec.ControlPlaneEndpoint.IsZero() && cScope.MvmCluster.Spec.ControlPlaneEndpoint.IsZero() {
 		return reconcile.Result{}, errControlplaneEndpointRequired
 	}
 
+	if cScope.MvmCluster.Spec.ClientCertificate != nil {
+		ready, err := cScope.ReconcileClientCertificate(ctx)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("reconciling client certificate: %w", err)
+		}
+
+		if !ready {
+			cScope.Info("Client certificate is not ready yet")
+			conditions.MarkFalse(
+				cScope.MvmCluster,
+				infrav1.ClientCertificateReadyCondition,
+				infrav1.WaitingForClientCertificateReason,
+				clusterv1.ConditionSeverityInfo,
+				"",
+			)
+
+			return reconcile.Result{RequeueAfter: requeuePeriod}, nil
+		}
+
+		conditions.MarkTrue(cScope.MvmCluster, infrav1.ClientCertificateReadyCondition)
+	}
+
 	cScope.MvmCluster.Status.Ready = true
 
 	if err := r.setFailureDomains(cScope); err != nil {
 		return reconcile.Result{}, fmt.Errorf("setting failuredomains: %w", err)
 	}
 
+	if err := r.reconcileCredentials(ctx, cScope); err != nil {
+		return reconcile.Result{}, fmt.Errorf("checking credentials: %w", err)
+	}
+
+	r.reconcileTLSCertificates(ctx, cScope)
+
+	if err := r.reconcileHosts(ctx, cScope); err != nil {
+		return reconcile.Result{}, fmt.Errorf("reconciling hosts: %w", err)
+	}
+
+	if cScope.MvmCluster.Spec.LoadBalancer.IsManaged() {
+		return r.reconcileLoadBalancer(ctx, cScope)
+	}
+
 	available := r.isAPIServerAvailable(ctx, cScope)
 	if !available {
 		conditions.MarkFalse(
@@ -156,7 +304,213 @@ func (r *MicrovmClusterReconciler) reconcileNormal(
 
 	conditions.MarkTrue(cScope.MvmCluster, infrav1.LoadBalancerAvailableCondition)
 
-	return reconcile.Result{}, nil
+	return r.hostCheckResult(cScope), nil
+}
+
+func (r *MicrovmClusterReconciler) reconcileLoadBalancer(
+	ctx context.Context,
+	cScope *scope.ClusterScope,
+) (reconcile.Result, error) {
+	endpoint := cScope.LoadBalancerEndpoint()
+
+	cScope.
+		V(defaults.LogLevelDebug).
+		Info("checking if load balancer is available", "type", cScope.MvmCluster.Spec.LoadBalancer.Type, "endpoint", endpoint.String())
+
+	if err := r.LoadBalancerChecker(ctx, endpoint); err != nil {
+		conditions.MarkFalse(
+			cScope.MvmCluster,
+			infrav1.LoadBalancerAvailableCondition,
+			infrav1.LoadBalancerNotAvailableReason,
+			clusterv1.ConditionSeverityInfo,
+			"control plane load balancer isn't available: %s", err,
+		)
+
+		return reconcile.Result{RequeueAfter: requeuePeriod}, nil
+	}
+
+	conditions.MarkTrue(cScope.MvmCluster, infrav1.LoadBalancerAvailableCondition)
+
+	return r.hostCheckResult(cScope), nil
+}
+
+// hostCheckResult requeues a ready cluster so that its hosts are checked again, as nothing
+// else triggers a reconcile when a host stops or starts responding.
+func (r *MicrovmClusterReconciler) hostCheckResult(cScope *scope.ClusterScope) reconcile.Result {
+	placement := cScope.Placement()
+	if placement.StaticPool == nil || len(placement.StaticPool.Hosts) == 0 {
+		return reconcile.Result{}
+	}
+
+	return reconcile.Result{RequeueAfter: r.hostCheckInterval()}
+}
+
+func (r *MicrovmClusterReconciler) hostCheckInterval() time.Duration {
+	if r.HostCheckInterval == 0 {
+		return DefaultHostCheckInterval
+	}
+
+	return r.HostCheckInterval
+}
+
+func (r *MicrovmClusterReconciler) hostCheckTimeout() time.Duration {
+	if r.HostCheckTimeout == 0 {
+		return DefaultHostCheckTimeout
+	}
+
+	return r.HostCheckTimeout
+}
+
+// reconcileCredentials checks that the secrets used to connect to the hosts exist and contain
+// the required keys, and reports exactly what is missing.
+func (r *MicrovmClusterReconciler) reconcileCredentials(ctx context.Context, cScope *scope.ClusterScope) error {
+	missing, err := cScope.GetMissingCredentials(ctx)
+	if err != nil {
+		return r.reconcileIdentityError(cScope, err)
+	}
+
+	if len(missing) == 0 {
+		conditions.MarkTrue(cScope.MvmCluster, infrav1.CredentialsReadyCondition)
+
+		return nil
+	}
+
+	reason := infrav1.CredentialsSecretKeyMissingReason
+	problems := make([]string, 0, len(missing))
+
+	for _, m := range missing {
+		if m.Key == "" {
+			reason = infrav1.CredentialsSecretNotFoundReason
+		}
+
+		problems = append(problems, m.String())
+	}
+
+	cScope.Info("host credentials are missing", "problems", problems)
+	r.recordConditionWarning(cScope, infrav1.CredentialsReadyCondition, reason, "%s", strings.Join(problems, "; "))
+	conditions.MarkFalse(
+		cScope.MvmCluster,
+		infrav1.CredentialsReadyCondition,
+		reason,
+		clusterv1.ConditionSeverityError,
+		"%s", strings.Join(problems, "; "),
+	)
+
+	return nil
+}
+
+// recordConditionWarning records a warning event if the condition of the cluster is changing to
+// the reason, so a problem that lasts across reconciles is only reported once.
+func (r *MicrovmClusterReconciler) recordConditionWarning(
+	cScope *scope.ClusterScope,
+	conditionType clusterv1.ConditionType,
+	reason, messageFmt string,
+	args ...interface{},
+) {
+	if conditions.GetReason(cScope.MvmCluster, conditionType) == reason {
+		return
+	}
+
+	recordEvent(r.Recorder, cScope.MvmCluster, corev1.EventTypeWarning, reason, messageFmt, args...)
+}
+
+// reconcileIdentityError reports the MicrovmClusterIdentity referenced by the cluster not being
+// usable in the CredentialsReady condition. Other errors are returned.
+func (r *MicrovmClusterReconciler) reconcileIdentityError(cScope *scope.ClusterScope, err error) error {
+	var reason string
+
+	switch {
+	case errors.Is(err, identity.ErrNotFound):
+		reason = infrav1.IdentityNotFoundReason
+	case errors.Is(err, identity.ErrNamespaceNotAllowed):
+		reason = infrav1.IdentityNotAllowedReason
+	default:
+		return err
+	}
+
+	cScope.Info("microvm cluster identity can't be used", "error", err.Error())
+	r.recordConditionWarning(cScope, infrav1.CredentialsReadyCondition, reason, "%s", err.Error())
+	conditions.MarkFalse(
+		cScope.MvmCluster,
+		infrav1.CredentialsReadyCondition,
+		reason,
+		clusterv1.ConditionSeverityError,
+		"%s", err.Error(),
+	)
+
+	return nil
+}
+
+// reconcileTLSCertificates checks the client certificates used to connect to the hosts and
+// reports any that have expired or are about to expire. Problems with the certificates don't
+// stop the cluster being reconciled as they only affect the hosts that use them.
+func (r *MicrovmClusterReconciler) reconcileTLSCertificates(ctx context.Context, cScope *scope.ClusterScope) {
+	// The certificates that could be read are still checked when others can't be.
+	certs, err := cScope.GetTLSCertificates(ctx)
+	if err != nil {
+		cScope.Error(err, "checking tls certificates")
+	}
+
+	if len(certs) == 0 && err == nil {
+		conditions.Delete(cScope.MvmCluster, infrav1.TLSCertificatesValidCondition)
+
+		return
+	}
+
+	warningPeriod := r.TLSExpiryWarningPeriod
+	if warningPeriod == 0 {
+		warningPeriod = DefaultTLSExpiryWarningPeriod
+	}
+
+	now := time.Now()
+	expired := []string{}
+	expiring := []string{}
+
+	for _, cert := range certs {
+		metrics.SetTLSCertificateExpiry(cScope.MvmCluster.Namespace, cScope.MvmCluster.Name, cert.HostID, cert.SecretName, cert.NotAfter)
+
+		switch {
+		case now.After(cert.NotAfter):
+			expired = append(expired, cert.Host)
+		case now.Add(warningPeriod).After(cert.NotAfter):
+			expiring = append(expiring, cert.Host)
+		}
+	}
+
+	switch {
+	case err != nil:
+		r.recordConditionWarning(cScope, infrav1.TLSCertificatesValidCondition,
+			infrav1.TLSCertificateInvalidReason, "%s", err.Error())
+		conditions.MarkFalse(
+			cScope.MvmCluster,
+			infrav1.TLSCertificatesValidCondition,
+			infrav1.TLSCertificateInvalidReason,
+			clusterv1.ConditionSeverityError,
+			"%s", err.Error(),
+		)
+	case len(expired) > 0:
+		r.recordConditionWarning(cScope, infrav1.TLSCertificatesValidCondition, infrav1.TLSCertificateExpiredReason,
+			"client certificates for hosts %s have expired", strings.Join(expired, ", "))
+		conditions.MarkFalse(
+			cScope.MvmCluster,
+			infrav1.TLSCertificatesValidCondition,
+			infrav1.TLSCertificateExpiredReason,
+			clusterv1.ConditionSeverityError,
+			"client certificates for hosts %s have expired", strings.Join(expired, ", "),
+		)
+	case len(expiring) > 0:
+		r.recordConditionWarning(cScope, infrav1.TLSCertificatesValidCondition, infrav1.TLSCertificateExpiringReason,
+			"client certificates for hosts %s expire within %s", strings.Join(expiring, ", "), warningPeriod)
+		conditions.MarkFalse(
+			cScope.MvmCluster,
+			infrav1.TLSCertificatesValidCondition,
+			infrav1.TLSCertificateExpiringReason,
+			clusterv1.ConditionSeverityWarning,
+			"client certificates for hosts %s expire within %s", strings.Join(expiring, ", "), warningPeriod,
+		)
+	default:
+		conditions.MarkTrue(cScope.MvmCluster, infrav1.TLSCertificatesValidCondition)
+	}
 }
 
 func (r *MicrovmClusterReconciler) isAPIServerAvailable(ctx context.Context, clusterScope *scope.ClusterScope) bool {
@@ -207,13 +561,17 @@ func (r *MicrovmClusterReconciler) setFailureDomains(clusterScope *scope.Cluster
 				V(defaults.LogLevelTrace).
 				Info(
 					"adding failure domain",
+					"id", host.ID(),
 					"endpoint", host.Endpoint,
 					"name", host.Name,
 					"controlplane", host.ControlPlaneAllowed,
 				)
 
-			failureDomains[host.Endpoint] = clusterv1.FailureDomainSpec{
+			failureDomains[host.ID()] = clusterv1.FailureDomainSpec{
 				ControlPlane: host.ControlPlaneAllowed,
+				Attributes: map[string]string{
+					infrav1.FailureDomainEndpointAttribute: host.Endpoint,
+				},
 			}
 		}
 
@@ -224,6 +582,300 @@ func (r *MicrovmClusterReconciler) setFailureDomains(clusterScope *scope.Cluster
 	return nil
 }
 
+// reconcileHosts records the declared capabilities of the hosts and the cluster's microvms on
+// them in the status of the cluster and, if clients can be created, checks that the hosts are
+// reachable and describes their API. A host is only checked again once the host check interval
+// has passed since it was last checked. Flintlock doesn't report its version or providers, so the
+// declared ones are recorded as they are.
+func (r *MicrovmClusterReconciler) reconcileHosts(ctx context.Context, cScope *scope.ClusterScope) error {
+	placement := cScope.Placement()
+	if placement.StaticPool == nil {
+		cScope.MvmCluster.Status.Hosts = nil
+		cScope.MvmCluster.Status.HostCount = 0
+		cScope.MvmCluster.Status.ReachableHostCount = 0
+
+		return nil
+	}
+
+	inventory, err := r.microvmsByHost(ctx, cScope)
+	if err != nil {
+		return err
+	}
+
+	previous := map[string]infrav1.HostStatus{}
+	for _, status := range cScope.MvmCluster.Status.Hosts {
+		previous[status.ID] = status
+	}
+
+	hosts := make([]infrav1.HostStatus, 0, len(placement.StaticPool.Hosts))
+
+	for _, host := range placement.StaticPool.Hosts {
+		status := infrav1.HostStatus{
+			ID:       host.ID(),
+			Endpoint: host.Endpoint,
+		}
+
+		if host.Capabilities != nil {
+			status.DeclaredVersion = host.Capabilities.Version
+			status.DeclaredProviders = host.Capabilities.Providers
+		}
+
+		if prev, ok := previous[status.ID]; ok && prev.Endpoint == status.Endpoint {
+			status.Reachable = prev.Reachable
+			status.LastError = prev.LastError
+			status.LastCheckTime = prev.LastCheckTime
+			status.MicrovmSpecFields = prev.MicrovmSpecFields
+		}
+
+		microvms := inventory[status.ID]
+		status.ControlPlaneMicrovms = microvms.controlPlane
+		status.WorkerMicrovms = microvms.workers
+		status.VCPU = microvms.vcpu
+		status.MemoryMb = microvms.memoryMb
+
+		metrics.SetHostInfo(cScope.MvmCluster.Namespace, cScope.MvmCluster.Name, status.ID, status.Endpoint)
+
+		hosts = append(hosts, status)
+	}
+
+	r.probeHosts(ctx, cScope, hosts)
+
+	for id := range previous {
+		if !slices.ContainsFunc(hosts, func(status infrav1.HostStatus) bool { return status.ID == id }) {
+			metrics.DeleteHost(cScope.MvmCluster.Namespace, cScope.MvmCluster.Name, id)
+		}
+	}
+
+	var hostCount, reachableCount int32
+
+	for _, status := range hosts {
+		hostCount++
+
+		if status.Reachable != nil && *status.Reachable {
+			reachableCount++
+		}
+	}
+
+	cScope.MvmCluster.Status.Hosts = hosts
+	cScope.MvmCluster.Status.HostCount = hostCount
+	cScope.MvmCluster.Status.ReachableHostCount = reachableCount
+
+	return nil
+}
+
+// hostMicrovms is the number of the cluster's microvms on a host and the resources committed
+// to them.
+type hostMicrovms struct {
+	controlPlane int32
+	workers      int32
+	vcpu         int64
+	memoryMb     int64
+}
+
+// microvmsByHost totals the cluster's MicrovmMachines by the host they've been placed on.
+// Machines that haven't been placed yet aren't counted.
+func (r *MicrovmClusterReconciler) microvmsByHost(
+	ctx context.Context,
+	cScope *scope.ClusterScope,
+) (map[string]hostMicrovms, error) {
+	machines := &infrav1.MicrovmMachineList{}
+	if err := r.List(ctx, machines,
+		client.InNamespace(cScope.MvmCluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: cScope.Cluster.Name},
+	); err != nil {
+		return nil, fmt.Errorf("listing microvm machines: %w", err)
+	}
+
+	inventory := map[string]hostMicrovms{}
+
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+
+		hostID := machine.Annotations[infrav1.HostIDAnnotation]
+		if hostID == "" {
+			continue
+		}
+
+		microvms := inventory[hostID]
+
+		if _, ok := machine.Labels[clusterv1.MachineControlPlaneLabel]; ok {
+			microvms.controlPlane++
+		} else {
+			microvms.workers++
+		}
+
+		microvms.vcpu += machine.Spec.VCPU
+		microvms.memoryMb += machine.Spec.MemoryMb
+		inventory[hostID] = microvms
+	}
+
+	return inventory, nil
+}
+
+// hostProbe is the result of checking a host.
+type hostProbe struct {
+	// err is the error from listing the microvms on the host.
+	err error
+	// description is the description of the API of the host, if the host was described.
+	description *flintlock.HostDescription
+	// describeErr is the error from describing the host.
+	describeErr error
+}
+
+// probeHosts checks the hosts that are due to be checked at the same time, so that a host that
+// isn't responding doesn't delay the others. Each host is checked by listing the microvms of the
+// cluster's namespace on it, which isn't retried and has to finish within the host check
+// timeout. Hosts that respond are then described. Hosts with an open circuit breaker aren't
+// called and are reported as unreachable.
+func (r *MicrovmClusterReconciler) probeHosts(
+	ctx context.Context,
+	cScope *scope.ClusterScope,
+	hosts []infrav1.HostStatus,
+) {
+	clients := hostClients{factory: r.MvmClientFunc, pool: r.ClientPool, breakers: r.CircuitBreakers}
+	if !clients.enabled() {
+		return
+	}
+
+	policy := cScope.GetCallPolicy(r.CallPolicy)
+	policy.Retries = 0
+	policy.ListTimeout = r.hostCheckTimeout()
+
+	now := metav1.Now()
+	interval := r.hostCheckInterval()
+	probes := make([]*hostProbe, len(hosts))
+
+	var wg sync.WaitGroup
+
+	for i := range hosts {
+		status := &hosts[i]
+
+		if status.LastCheckTime != nil && now.Sub(status.LastCheckTime.Time) < interval {
+			if status.Reachable != nil {
+				metrics.SetHostReachable(cScope.MvmCluster.Namespace, cScope.MvmCluster.Name, status.ID, *status.Reachable)
+			}
+
+			continue
+		}
+
+		// The credentials are read before the calls are made as the scope isn't safe to use
+		// concurrently.
+		creds, err := hostCredentials(ctx, cScope, status.Endpoint)
+		if err != nil {
+			// The host wasn't called so whether it's reachable isn't known.
+			status.LastError = err.Error()
+
+			continue
+		}
+
+		probe := &hostProbe{}
+		probes[i] = probe
+
+		wg.Add(1)
+
+		go func(id, addr string) {
+			defer wg.Done()
+
+			probe.err = func() error {
+				client, err := clients.newClient(cScope.MvmCluster, id, addr, creds, policy)
+				if err != nil {
+					return err
+				}
+				defer client.Close()
+
+				_, err = client.ListMicroVMs(ctx, &flintlockv1.ListMicroVMsRequest{Namespace: cScope.Namespace()})
+
+				return err
+			}()
+
+			if probe.err != nil || r.DescribeHostFunc == nil {
+				return
+			}
+
+			describeCtx, cancel := context.WithTimeout(ctx, policy.ListTimeout)
+			defer cancel()
+
+			probe.description, probe.describeErr = r.DescribeHostFunc(describeCtx, addr, creds)
+		}(status.ID, status.Endpoint)
+	}
+
+	wg.Wait()
+
+	for i, probe := range probes {
+		if probe != nil {
+			r.recordProbe(cScope, &hosts[i], probe, now)
+		}
+	}
+}
+
+// recordProbe records the result of checking the host in its status, the metrics and, if whether
+// the host is reachable changed, an event. The description of the host is kept if describing it
+// failed for a reason other than it not serving reflection.
+func (r *MicrovmClusterReconciler) recordProbe(
+	cScope *scope.ClusterScope,
+	status *infrav1.HostStatus,
+	probe *hostProbe,
+	checkTime metav1.Time,
+) {
+	err := probe.err
+
+	reachable := !circuitbreaker.IsHostFailure(err)
+	if openErr := (&circuitbreaker.OpenError{}); errors.As(err, &openErr) {
+		reachable = false
+	}
+
+	if status.Reachable == nil || *status.Reachable != reachable {
+		cScope.Info("host reachability changed", "host", status.ID, "reachable", reachable)
+
+		switch {
+		case !reachable:
+			recordEvent(r.Recorder, cScope.MvmCluster, corev1.EventTypeWarning,
+				HostUnreachableEventReason, "host %s (%s) isn't responding: %s", status.ID, status.Endpoint, err)
+		case status.Reachable != nil:
+			recordEvent(r.Recorder, cScope.MvmCluster, corev1.EventTypeNormal,
+				HostReachableEventReason, "host %s (%s) is responding again", status.ID, status.Endpoint)
+		}
+	}
+
+	metrics.SetHostReachable(cScope.MvmCluster.Namespace, cScope.MvmCluster.Name, status.ID, reachable)
+
+	status.Reachable = &reachable
+	status.LastCheckTime = &checkTime
+	status.LastError = ""
+
+	if err != nil {
+		status.LastError = err.Error()
+	}
+
+	switch {
+	case probe.description != nil:
+		status.MicrovmSpecFields = probe.description.MicrovmSpecFields
+	case errors.Is(probe.describeErr, flintlock.ErrReflectionNotServed):
+		status.MicrovmSpecFields = nil
+	case probe.describeErr != nil:
+		cScope.Error(probe.describeErr, "describing host", "host", status.ID)
+	}
+}
+
+// hostCredentials returns the credentials used to connect to the host at the address.
+func hostCredentials(ctx context.Context, cScope *scope.ClusterScope, addr string) (clientpool.Credentials, error) {
+	token, err := cScope.GetBasicAuthToken(ctx, addr)
+	if err != nil {
+		return clientpool.Credentials{}, fmt.Errorf("getting basic auth token: %w", err)
+	}
+
+	tls, err := cScope.GetTLSConfig(ctx, addr)
+	if err != nil {
+		return clientpool.Credentials{}, fmt.Errorf("getting tls config: %w", err)
+	}
+
+	return clientpool.Credentials{
+		BasicAuthToken: token,
+		TLS:            tls,
+		Proxy:          cScope.GetProxy(addr),
+	}, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *MicrovmClusterReconciler) SetupWithManager(
 	ctx context.Context,
@@ -236,11 +888,29 @@ func (r *MicrovmClusterReconciler) SetupWithManager(
 		r.RemoteClientGetter = remote.NewClusterClient
 	}
 
+	if r.LoadBalancerChecker == nil {
+		r.LoadBalancerChecker = dialLoadBalancer
+	}
+
 	builder := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(options).
 		For(&infrav1.MicrovmCluster{}).
+		Owns(&ipamv1.IPAddressClaim{}).
 		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue)).
 		WithEventFilter(predicates.ResourceIsNotExternallyManaged(mgr.GetScheme(), log)).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.SecretToMicrovmClusters(log)),
+		).
+		Watches(
+			&infrav1.MicrovmClusterIdentity{},
+			handler.EnqueueRequestsFromMapFunc(r.MicrovmClusterIdentityToMicrovmClusters(log)),
+		).
+		Watches(
+			&infrav1.MicrovmMachine{},
+			handler.EnqueueRequestsFromMapFunc(r.MicrovmMachineToMicrovmCluster(log)),
+			builder.WithPredicates(microvmMachinePlacementChanged()),
+		).
 		Watches(
 			&clusterv1.Cluster{},
 			handler.EnqueueRequestsFromMapFunc(
@@ -261,3 +931,98 @@ func (r *MicrovmClusterReconciler) SetupWithManager(
 
 	return nil
 }
+
+// SecretToMicrovmClusters is called when there is a change to a Secret. Its job is to
+// identify the MicrovmClusters that use the secret for their host credentials and queue
+// requests for them to be reconciled, so that fixed or rotated credentials are picked up.
+func (r *MicrovmClusterReconciler) SecretToMicrovmClusters(log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []ctrl.Request {
+		clusters, err := getMicrovmClustersForSecret(ctx, r.Client, o, r.IdentityNamespace)
+		if err != nil {
+			log.Error(err, "failed to get microvm clusters for secret", "secret", client.ObjectKeyFromObject(o))
+
+			return nil
+		}
+
+		return microvmClusterRequests(clusters)
+	}
+}
+
+// MicrovmClusterIdentityToMicrovmClusters is called when there is a change to a
+// MicrovmClusterIdentity. Its job is to queue requests for the MicrovmClusters that reference
+// the identity so that changes to its credentials or allowed namespaces are picked up.
+func (r *MicrovmClusterReconciler) MicrovmClusterIdentityToMicrovmClusters(log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []ctrl.Request {
+		clusters, err := getMicrovmClustersForIdentity(ctx, r.Client, o.GetName())
+		if err != nil {
+			log.Error(err, "failed to get microvm clusters for identity", "identity", o.GetName())
+
+			return nil
+		}
+
+		return microvmClusterRequests(clusters)
+	}
+}
+
+// MicrovmMachineToMicrovmCluster is called when a MicrovmMachine is placed on a host or
+// removed. Its job is to queue a request for the machine's MicrovmCluster so that the number of
+// microvms on each host is updated.
+func (r *MicrovmClusterReconciler) MicrovmMachineToMicrovmCluster(log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []ctrl.Request {
+		cluster, err := util.GetClusterFromMetadata(ctx, r.Client, metav1.ObjectMeta{
+			Namespace: o.GetNamespace(),
+			Labels:    o.GetLabels(),
+		})
+		if err != nil {
+			log.V(defaults.LogLevelDebug).Info("failed to get cluster for microvm machine",
+				"machine", client.ObjectKeyFromObject(o), "error", err.Error())
+
+			return nil
+		}
+
+		infraRef := cluster.Spec.InfrastructureRef
+		if infraRef == nil || infraRef.Kind != "MicrovmCluster" {
+			return nil
+		}
+
+		return []ctrl.Request{{
+			NamespacedName: client.ObjectKey{Namespace: cluster.Namespace, Name: infraRef.Name},
+		}}
+	}
+}
+
+// microvmMachinePlacementChanged filters the MicrovmMachine events to the ones that change the
+// number of microvms on a host, so the cluster isn't reconciled every time the status of one
+// of its machines changes.
+func microvmMachinePlacementChanged() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetAnnotations()[infrav1.HostIDAnnotation] !=
+				e.ObjectNew.GetAnnotations()[infrav1.HostIDAnnotation]
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func microvmClusterRequests(clusters []infrav1.MicrovmCluster) []ctrl.Request {
+	result := make([]ctrl.Request, 0, len(clusters))
+	for i := range clusters {
+		result = append(result, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&clusters[i])})
+	}
+
+	return result
+}
+
+// dialLoadBalancer checks that a TCP connection can be made to the control plane endpoint.
+func dialLoadBalancer(ctx context.Context, endpoint clusterv1.APIEndpoint) error {
+	dialer := &net.Dialer{Timeout: loadBalancerDialTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint.String())
+	if err != nil {
+		return fmt.Errorf("dialing control plane endpoint %s: %w", endpoint.String(), err)
+	}
+
+	return conn.Close()
+}
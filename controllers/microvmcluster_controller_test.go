@@ -5,20 +5,43 @@ package controllers_test
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	. "github.com/onsi/gomega"
 
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	fakeremote "sigs.k8s.io/cluster-api/controllers/remote/fake"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
 )
 
 func TestClusterReconciliationNoEndpoint(t *testing.T) {
@@ -74,7 +97,7 @@ func TestClusterReconciliationWithClusterEndpoint(t *testing.T) {
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
-	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+	g.Expect(result.RequeueAfter).To(Equal(controllers.DefaultHostCheckInterval), "expected the hosts to be checked again")
 
 	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
 	g.Expect(err).NotTo(HaveOccurred())
@@ -121,7 +144,7 @@ func TestClusterReconciliationWithMvmClusterEndpoint(t *testing.T) {
 
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(result.Requeue).To(BeFalse())
-	g.Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+	g.Expect(result.RequeueAfter).To(Equal(controllers.DefaultHostCheckInterval), "expected the hosts to be checked again")
 
 	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
 	g.Expect(err).NotTo(HaveOccurred())
@@ -276,3 +299,1078 @@ func TestClusterReconciliationDelete(t *testing.T) {
 	_, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
 	g.Expect(err).NotTo(HaveOccurred())
 }
+
+func TestClusterReconciliationWithEndpointFromPool(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpointFromPool = &corev1.TypedLocalObjectReference{
+		Kind: "InClusterIPPool",
+		Name: "vip-pool",
+	}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+	}
+
+	client := createFakeClient(g, objects)
+	result, err := reconcileCluster(client)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.IsZero()).To(BeTrue(), "Expect no requeue as the claim is watched")
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Finalizers).To(ContainElement(infrav1.ClusterFinalizer))
+	g.Expect(reconciled.Spec.ControlPlaneEndpoint.IsZero()).To(BeTrue())
+	assertConditionFalse(g, reconciled, infrav1.LoadBalancerAvailableCondition, infrav1.WaitingForControlPlaneEndpointReason)
+
+	claim, err := getIPAddressClaim(client, testClusterName+"-control-plane", testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred(), "Expect ip address claim to be created")
+	g.Expect(claim.Spec.PoolRef.Name).To(Equal("vip-pool"))
+
+	claim.Status.AddressRef.Name = "vip"
+	g.Expect(client.Status().Update(context.TODO(), claim)).To(Succeed())
+	g.Expect(client.Create(context.TODO(), &ipamv1.IPAddress{
+		ObjectMeta: metav1.ObjectMeta{Name: "vip", Namespace: testClusterNamespace},
+		Spec:       ipamv1.IPAddressSpec{Address: "192.168.8.15", Prefix: 24},
+	})).To(Succeed())
+
+	_, err = reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Spec.ControlPlaneEndpoint).To(Equal(clusterv1.APIEndpoint{Host: "192.168.8.15", Port: 6443}))
+}
+
+func TestClusterReconciliationDeleteReleasesEndpoint(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpointFromPool = &corev1.TypedLocalObjectReference{
+		Kind: "InClusterIPPool",
+		Name: "vip-pool",
+	}
+	mvmCluster.ObjectMeta.DeletionTimestamp = &metav1.Time{
+		Time: time.Now(),
+	}
+	mvmCluster.Finalizers = []string{
+		infrav1.ClusterFinalizer,
+		"somefinalizer",
+	}
+
+	claim := &ipamv1.IPAddressClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testClusterName + "-control-plane",
+			Namespace: testClusterNamespace,
+		},
+	}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		claim,
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = getIPAddressClaim(client, claim.Name, testClusterNamespace)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "Expect ip address claim to be deleted")
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Finalizers).NotTo(ContainElement(infrav1.ClusterFinalizer))
+}
+
+func TestClusterReconciliationWithKubeVIP(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.LoadBalancer = &infrav1.LoadBalancerSpec{
+		Type: infrav1.LoadBalancerTypeKubeVIPARP,
+	}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+	}
+
+	var checkedEndpoint clusterv1.APIEndpoint
+	lbCheck := func(_ context.Context, endpoint clusterv1.APIEndpoint) error {
+		checkedEndpoint = endpoint
+
+		return nil
+	}
+
+	client := createFakeClient(g, objects)
+	result, err := reconcileClusterWithLoadBalancer(client, lbCheck)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(controllers.DefaultHostCheckInterval))
+	g.Expect(checkedEndpoint).To(Equal(mvmCluster.Spec.ControlPlaneEndpoint))
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionTrue(g, reconciled, infrav1.LoadBalancerAvailableCondition)
+}
+
+func TestClusterReconciliationHostCheckInterval(t *testing.T) {
+	tt := []struct {
+		name          string
+		noHosts       bool
+		expectRequeue time.Duration
+	}{
+		{
+			name:          "static pool hosts",
+			expectRequeue: 5 * time.Minute,
+		},
+		{
+			name:          "no hosts",
+			noHosts:       true,
+			expectRequeue: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mvmCluster := createMicrovmCluster()
+			mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+				Host: "192.168.8.15",
+				Port: 6443,
+			}
+			mvmCluster.Spec.LoadBalancer = &infrav1.LoadBalancerSpec{
+				Type: infrav1.LoadBalancerTypeKubeVIPARP,
+			}
+
+			if tc.noHosts {
+				mvmCluster.Spec.Placement.StaticPool.Hosts = nil
+			}
+
+			client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+			clusterController := &controllers.MicrovmClusterReconciler{
+				Client:              client,
+				RemoteClientGetter:  fakeremote.NewClusterClient,
+				LoadBalancerChecker: func(_ context.Context, _ clusterv1.APIEndpoint) error { return nil },
+				IdentityNamespace:   testIdentityNamespace,
+				HostCheckInterval:   5 * time.Minute,
+			}
+
+			result, err := clusterController.Reconcile(context.TODO(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace},
+			})
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result.RequeueAfter).To(Equal(tc.expectRequeue))
+		})
+	}
+}
+
+func TestClusterReconciliationWithKubeVIPDefaultPort(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+	}
+	mvmCluster.Spec.LoadBalancer = &infrav1.LoadBalancerSpec{
+		Type: infrav1.LoadBalancerTypeKubeVIPARP,
+	}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+	}
+
+	var checkedEndpoint clusterv1.APIEndpoint
+	lbCheck := func(_ context.Context, endpoint clusterv1.APIEndpoint) error {
+		checkedEndpoint = endpoint
+
+		return nil
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileClusterWithLoadBalancer(client, lbCheck)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(checkedEndpoint).To(Equal(clusterv1.APIEndpoint{Host: "192.168.8.15", Port: 6443}))
+}
+
+func TestClusterReconciliationWithKubeVIPNotAvailable(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.LoadBalancer = &infrav1.LoadBalancerSpec{
+		Type: infrav1.LoadBalancerTypeKubeVIPARP,
+	}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+	}
+
+	lbCheck := func(_ context.Context, _ clusterv1.APIEndpoint) error {
+		return errors.New("connection refused")
+	}
+
+	client := createFakeClient(g, objects)
+	result, err := reconcileClusterWithLoadBalancer(client, lbCheck)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", time.Duration(0)))
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, infrav1.LoadBalancerAvailableCondition, infrav1.LoadBalancerNotAvailableReason)
+}
+
+func TestClusterReconciliationFailureDomainsUseHostID(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "192.168.8.15", Port: 6443}
+	mvmCluster.Spec.Placement.StaticPool.Hosts = append(mvmCluster.Spec.Placement.StaticPool.Hosts, infrav1.MicrovmHost{
+		Endpoint: "127.0.0.2:9090",
+	})
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.FailureDomains).To(HaveLen(2))
+	g.Expect(reconciled.Status.FailureDomains).To(HaveKey("host1"))
+	g.Expect(reconciled.Status.FailureDomains["host1"].Attributes).To(
+		HaveKeyWithValue(infrav1.FailureDomainEndpointAttribute, "127.0.0.1:9090"),
+	)
+	g.Expect(reconciled.Status.FailureDomains).To(HaveKey("127.0.0.2:9090"), "Expect the endpoint to be used for hosts without a name")
+}
+
+func TestClusterReconciliationWithIPv6Endpoints(t *testing.T) {
+	g := NewWithT(t)
+
+	hostEndpoint := "[fd00::1]:9090"
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].Name = ""
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].Endpoint = hostEndpoint
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "fd00::10",
+		Port: 6443,
+	}
+	mvmCluster.Spec.LoadBalancer = &infrav1.LoadBalancerSpec{
+		Type: infrav1.LoadBalancerTypeKubeVIPARP,
+	}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+	}
+
+	var checkedEndpoint clusterv1.APIEndpoint
+	lbCheck := func(_ context.Context, endpoint clusterv1.APIEndpoint) error {
+		checkedEndpoint = endpoint
+
+		return nil
+	}
+
+	client := createFakeClient(g, objects)
+	result, err := reconcileClusterWithLoadBalancer(client, lbCheck)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(controllers.DefaultHostCheckInterval))
+	g.Expect(checkedEndpoint.String()).To(Equal("[fd00::10]:6443"))
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Ready).To(BeTrue())
+	g.Expect(reconciled.Status.FailureDomains).To(HaveKey(hostEndpoint))
+	assertConditionTrue(g, reconciled, infrav1.LoadBalancerAvailableCondition)
+}
+
+func TestClusterReconciliationTLSCertificates(t *testing.T) {
+	tt := []struct {
+		name           string
+		notAfter       time.Time
+		expectedReason string
+	}{
+		{
+			name:     "valid certificate",
+			notAfter: time.Now().Add(365 * 24 * time.Hour),
+		},
+		{
+			name:           "certificate about to expire",
+			notAfter:       time.Now().Add(24 * time.Hour),
+			expectedReason: infrav1.TLSCertificateExpiringReason,
+		},
+		{
+			name:           "expired certificate",
+			notAfter:       time.Now().Add(-time.Hour),
+			expectedReason: infrav1.TLSCertificateExpiredReason,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mvmCluster := createMicrovmCluster()
+			mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+				Host: "192.168.8.15",
+				Port: 6443,
+			}
+			mvmCluster.Spec.Placement.StaticPool.Hosts[0].TLSSecretRef = "host1-tls"
+
+			objects := []runtime.Object{
+				createCluster(),
+				mvmCluster,
+				createTLSSecret(g, "host1-tls", tc.notAfter),
+			}
+
+			client := createFakeClient(g, objects)
+			_, err := reconcileCluster(client)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(reconciled.Status.Ready).To(BeTrue(), "Expect certificate problems not to stop the cluster being ready")
+
+			if tc.expectedReason == "" {
+				assertConditionTrue(g, reconciled, infrav1.TLSCertificatesValidCondition)
+			} else {
+				assertConditionFalse(g, reconciled, infrav1.TLSCertificatesValidCondition, tc.expectedReason)
+			}
+
+			expiry := testutil.ToFloat64(metrics.TLSCertificateExpiry.WithLabelValues(
+				testClusterNamespace, testClusterName, "host1", "host1-tls",
+			))
+			g.Expect(expiry).To(BeNumerically("==", tc.notAfter.Unix()))
+		})
+	}
+}
+
+func TestClusterReconciliationTLSSecretInvalidForOneHost(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].TLSSecretRef = "host1-tls"
+	mvmCluster.Spec.Placement.StaticPool.Hosts = append(mvmCluster.Spec.Placement.StaticPool.Hosts, infrav1.MicrovmHost{
+		Endpoint:     "127.0.0.2:9090",
+		TLSSecretRef: "host2-tls",
+	})
+
+	notAfter := time.Now().Add(365 * 24 * time.Hour)
+	invalidSecret := createTLSSecret(g, "host2-tls", notAfter)
+	invalidSecret.Data["tls.crt"] = []byte("invalid")
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		createTLSSecret(g, "host1-tls", notAfter),
+		invalidSecret,
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, infrav1.TLSCertificatesValidCondition, infrav1.TLSCertificateInvalidReason)
+	g.Expect(conditions.GetMessage(reconciled, infrav1.TLSCertificatesValidCondition)).To(ContainSubstring("127.0.0.2:9090"))
+
+	expiry := testutil.ToFloat64(metrics.TLSCertificateExpiry.WithLabelValues(
+		testClusterNamespace, testClusterName, "host1", "host1-tls",
+	))
+	g.Expect(expiry).To(BeNumerically("==", notAfter.Unix()), "expect the expiry of the valid certificate to be recorded")
+}
+
+func TestClusterReconciliationTLSSecretMissing(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.TLSSecretRef = "cluster-tls"
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, infrav1.TLSCertificatesValidCondition, infrav1.TLSCertificateInvalidReason)
+	assertConditionFalse(g, reconciled, infrav1.CredentialsReadyCondition, infrav1.CredentialsSecretNotFoundReason)
+	g.Expect(conditions.GetMessage(reconciled, infrav1.CredentialsReadyCondition)).To(Equal("secret cluster-tls not found"))
+}
+
+func TestClusterReconciliationCredentialsKeyMissing(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].TLSSecretRef = "host1-tls"
+
+	tlsSecret := createTLSSecret(g, "host1-tls", time.Now().Add(365*24*time.Hour))
+	delete(tlsSecret.Data, "tls.key")
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		tlsSecret,
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, infrav1.CredentialsReadyCondition, infrav1.CredentialsSecretKeyMissingReason)
+	g.Expect(conditions.GetMessage(reconciled, infrav1.CredentialsReadyCondition)).To(Equal("secret host1-tls is missing key tls.key"))
+}
+
+func TestClusterReconciliationCredentialsReady(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.Placement.StaticPool.BasicAuthSecret = "basic-auth"
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "basic-auth", Namespace: testClusterNamespace},
+			Data:       map[string][]byte{"127.0.0.1": []byte("token")},
+		},
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionTrue(g, reconciled, infrav1.CredentialsReadyCondition)
+}
+
+func TestClusterReconciliationIdentity(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.IdentityRef = &infrav1.MicrovmClusterIdentityReference{Name: "shared"}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		createMicrovmClusterIdentity("shared", &infrav1.AllowedNamespaces{NamespaceList: []string{testClusterNamespace}}),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-basic-auth", Namespace: testIdentityNamespace},
+			Data:       map[string][]byte{"127.0.0.1": []byte("token")},
+		},
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionTrue(g, reconciled, infrav1.CredentialsReadyCondition)
+}
+
+func TestClusterReconciliationIdentitySecretMissing(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.IdentityRef = &infrav1.MicrovmClusterIdentityReference{Name: "shared"}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		createMicrovmClusterIdentity("shared", &infrav1.AllowedNamespaces{}),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-basic-auth", Namespace: testClusterNamespace},
+			Data:       map[string][]byte{"127.0.0.1": []byte("token")},
+		},
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, infrav1.CredentialsReadyCondition, infrav1.CredentialsSecretNotFoundReason)
+}
+
+func TestClusterReconciliationIdentityNotAllowed(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.IdentityRef = &infrav1.MicrovmClusterIdentityReference{Name: "shared"}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		createMicrovmClusterIdentity("shared", &infrav1.AllowedNamespaces{NamespaceList: []string{"other"}}),
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, infrav1.CredentialsReadyCondition, infrav1.IdentityNotAllowedReason)
+}
+
+func TestClusterReconciliationIdentityNotFound(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.IdentityRef = &infrav1.MicrovmClusterIdentityReference{Name: "missing"}
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, infrav1.CredentialsReadyCondition, infrav1.IdentityNotFoundReason)
+}
+
+func TestClusterReconciliationClientCertificate(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.ClientCertificate = &infrav1.ClientCertificateSpec{
+		IssuerRef: infrav1.CertificateIssuerReference{Name: "flintlock-ca"},
+	}
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	result, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", time.Duration(0)))
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Ready).To(BeFalse(), "expected the cluster to wait for the client certificate")
+	assertConditionFalse(g, reconciled, infrav1.ClientCertificateReadyCondition, infrav1.WaitingForClientCertificateReason)
+
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(scope.CertificateGVK)
+	key := types.NamespacedName{Name: testClusterName + "-flintlock-client", Namespace: testClusterNamespace}
+	g.Expect(client.Get(context.TODO(), key, cert)).To(Succeed())
+	g.Expect(unstructured.SetNestedSlice(cert.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions")).To(Succeed())
+	g.Expect(client.Update(context.TODO(), cert)).To(Succeed())
+
+	tlsSecret := createTLSSecret(g, key.Name, time.Now().Add(365*24*time.Hour))
+	g.Expect(client.Create(context.TODO(), tlsSecret)).To(Succeed())
+
+	_, err = reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Ready).To(BeTrue())
+	assertConditionTrue(g, reconciled, infrav1.ClientCertificateReadyCondition)
+	assertConditionTrue(g, reconciled, infrav1.CredentialsReadyCondition)
+	assertConditionTrue(g, reconciled, infrav1.TLSCertificatesValidCondition)
+}
+
+func TestSecretToMicrovmClusters(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.TLSSecretRef = "cluster-tls"
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].TLSSecretRef = "host1-tls"
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	reconciler := &controllers.MicrovmClusterReconciler{Client: client}
+	mapFunc := reconciler.SecretToMicrovmClusters(logr.Discard())
+
+	expected := ctrl.Request{NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace}}
+
+	for _, name := range []string{"cluster-tls", "host1-tls"} {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testClusterNamespace}}
+		g.Expect(mapFunc(context.TODO(), secret)).To(ConsistOf(expected), "expect secret %s to map to the cluster", name)
+	}
+
+	otherSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testClusterNamespace}}
+	g.Expect(mapFunc(context.TODO(), otherSecret)).To(BeEmpty())
+
+	otherNamespace := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-tls", Namespace: "other"}}
+	g.Expect(mapFunc(context.TODO(), otherNamespace)).To(BeEmpty())
+}
+
+func TestSecretToMicrovmClustersWithIdentity(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.IdentityRef = &infrav1.MicrovmClusterIdentityReference{Name: "shared"}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		createMicrovmClusterIdentity("shared", &infrav1.AllowedNamespaces{}),
+	}
+
+	client := createFakeClient(g, objects)
+	reconciler := &controllers.MicrovmClusterReconciler{Client: client, IdentityNamespace: testIdentityNamespace}
+
+	expected := ctrl.Request{NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace}}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "shared-basic-auth", Namespace: testIdentityNamespace}}
+	g.Expect(reconciler.SecretToMicrovmClusters(logr.Discard())(context.TODO(), secret)).To(ConsistOf(expected))
+
+	otherNamespace := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "shared-basic-auth", Namespace: "other"}}
+	g.Expect(reconciler.SecretToMicrovmClusters(logr.Discard())(context.TODO(), otherNamespace)).To(BeEmpty())
+
+	identity := createMicrovmClusterIdentity("shared", nil)
+	g.Expect(reconciler.MicrovmClusterIdentityToMicrovmClusters(logr.Discard())(context.TODO(), identity)).To(ConsistOf(expected))
+}
+
+func TestClusterReconciliationHosts(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].Capabilities = &infrav1.HostCapabilities{
+		Version:   "v0.8.0",
+		Providers: []string{"firecracker"},
+	}
+	mvmCluster.Spec.Placement.StaticPool.Hosts = append(mvmCluster.Spec.Placement.StaticPool.Hosts,
+		infrav1.MicrovmHost{Name: "host2", Endpoint: "127.0.0.2:9090"},
+	)
+
+	reachableClient := &fakes.FakeClient{}
+	unreachableClient := &fakes.FakeClient{}
+	unreachableClient.ListMicroVMsReturns(nil, status.Error(codes.Unavailable, "connection refused"))
+
+	var describedHosts []string
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	clusterController := &controllers.MicrovmClusterReconciler{
+		Client:             client,
+		RemoteClientGetter: fakeremote.NewClusterClient,
+		IdentityNamespace:  testIdentityNamespace,
+		MvmClientFunc: func(address string, _ ...flclient.Options) (flclient.Client, error) {
+			if address == "127.0.0.2:9090" {
+				return unreachableClient, nil
+			}
+
+			return reachableClient, nil
+		},
+		DescribeHostFunc: func(_ context.Context, address string, _ clientpool.Credentials) (*flintlock.HostDescription, error) {
+			describedHosts = append(describedHosts, address)
+
+			return &flintlock.HostDescription{MicrovmSpecFields: []string{"id", "provider"}}, nil
+		},
+	}
+
+	_, err := clusterController.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(reachableClient.ListMicroVMsCallCount()).To(Equal(1))
+	_, req, _ := reachableClient.ListMicroVMsArgsForCall(0)
+	g.Expect(req.Namespace).To(Equal(testClusterNamespace))
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Hosts).To(HaveLen(2))
+
+	host1 := reconciled.Status.Hosts[0]
+	g.Expect(host1.ID).To(Equal("host1"))
+	g.Expect(host1.Endpoint).To(Equal("127.0.0.1:9090"))
+	g.Expect(host1.DeclaredVersion).To(Equal("v0.8.0"))
+	g.Expect(host1.DeclaredProviders).To(ConsistOf("firecracker"))
+	g.Expect(host1.MicrovmSpecFields).To(ConsistOf("id", "provider"))
+	g.Expect(host1.Reachable).To(Equal(pointer.Bool(true)))
+	g.Expect(host1.LastError).To(BeEmpty())
+
+	host2 := reconciled.Status.Hosts[1]
+	g.Expect(host2.ID).To(Equal("host2"))
+	g.Expect(host2.Reachable).To(Equal(pointer.Bool(false)))
+	g.Expect(host2.LastError).To(ContainSubstring("connection refused"))
+	g.Expect(host2.MicrovmSpecFields).To(BeEmpty())
+
+	g.Expect(describedHosts).To(ConsistOf("127.0.0.1:9090"), "expected only the reachable host to be described")
+
+	g.Expect(reconciled.Status.HostCount).To(BeNumerically("==", 2))
+	g.Expect(reconciled.Status.ReachableHostCount).To(BeNumerically("==", 1))
+
+	g.Expect(testutil.ToFloat64(metrics.HostReachable.WithLabelValues(testClusterNamespace, testClusterName, "host1"))).
+		To(BeNumerically("==", 1))
+	g.Expect(testutil.ToFloat64(metrics.HostReachable.WithLabelValues(testClusterNamespace, testClusterName, "host2"))).
+		To(BeNumerically("==", 0))
+	g.Expect(testutil.ToFloat64(metrics.HostInfo.WithLabelValues(testClusterNamespace, testClusterName, "host2", "127.0.0.2:9090"))).
+		To(BeNumerically("==", 1))
+
+	// The calls are labelled with the host ID, like the other host metrics, rather than the endpoint.
+	g.Expect(metrics.FlintlockRequestDuration.DeletePartialMatch(prometheus.Labels{
+		"namespace": testClusterNamespace,
+		"cluster":   testClusterName,
+		"host":      "host2",
+		"method":    "ListMicroVMs",
+		"code":      "Unavailable",
+	})).To(Equal(1))
+}
+
+func TestClusterReconciliationHostsCheckedOncePerInterval(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+
+	hostClient := &fakes.FakeClient{}
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	clusterController := &controllers.MicrovmClusterReconciler{
+		Client:             client,
+		RemoteClientGetter: fakeremote.NewClusterClient,
+		IdentityNamespace:  testIdentityNamespace,
+		HostCheckInterval:  time.Hour,
+		MvmClientFunc: func(_ string, _ ...flclient.Options) (flclient.Client, error) {
+			return hostClient, nil
+		},
+	}
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace},
+	}
+
+	_, err := clusterController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hostClient.ListMicroVMsCallCount()).To(Equal(1))
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Hosts).To(HaveLen(1))
+	g.Expect(reconciled.Status.Hosts[0].LastCheckTime).NotTo(BeNil())
+
+	_, err = clusterController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hostClient.ListMicroVMsCallCount()).To(Equal(1), "expected the host not to be checked again within the interval")
+
+	reconciled, err = getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Hosts[0].Reachable).To(Equal(pointer.Bool(true)), "expected the last result to be kept")
+
+	reconciled.Status.Hosts[0].LastCheckTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	g.Expect(client.Status().Update(context.TODO(), reconciled)).To(Succeed())
+
+	_, err = clusterController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hostClient.ListMicroVMsCallCount()).To(Equal(2), "expected the host to be checked once the interval has passed")
+}
+
+func TestClusterReconciliationHostsCheckedConcurrently(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.Placement.StaticPool.Hosts = append(mvmCluster.Spec.Placement.StaticPool.Hosts,
+		infrav1.MicrovmHost{Name: "host2", Endpoint: "127.0.0.2:9090"},
+	)
+
+	// Each call only returns once both hosts have been called, so the hosts are only reachable
+	// if they're checked at the same time.
+	var called sync.WaitGroup
+	called.Add(2)
+
+	allCalled := make(chan struct{})
+	go func() {
+		called.Wait()
+		close(allCalled)
+	}()
+
+	hostClient := &fakes.FakeClient{}
+	hostClient.ListMicroVMsStub = func(
+		ctx context.Context,
+		_ *flintlockv1.ListMicroVMsRequest,
+		_ ...grpc.CallOption,
+	) (*flintlockv1.ListMicroVMsResponse, error) {
+		called.Done()
+
+		select {
+		case <-allCalled:
+			return &flintlockv1.ListMicroVMsResponse{}, nil
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	clusterController := &controllers.MicrovmClusterReconciler{
+		Client:             client,
+		RemoteClientGetter: fakeremote.NewClusterClient,
+		IdentityNamespace:  testIdentityNamespace,
+		HostCheckTimeout:   5 * time.Second,
+		MvmClientFunc: func(_ string, _ ...flclient.Options) (flclient.Client, error) {
+			return hostClient, nil
+		},
+	}
+
+	_, err := clusterController.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.ReachableHostCount).To(BeNumerically("==", 2))
+}
+
+func TestClusterReconciliationHostCheckTimeout(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+
+	hostClient := &fakes.FakeClient{}
+	hostClient.ListMicroVMsStub = func(
+		ctx context.Context,
+		_ *flintlockv1.ListMicroVMsRequest,
+		_ ...grpc.CallOption,
+	) (*flintlockv1.ListMicroVMsResponse, error) {
+		<-ctx.Done()
+
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	clusterController := &controllers.MicrovmClusterReconciler{
+		Client:             client,
+		RemoteClientGetter: fakeremote.NewClusterClient,
+		IdentityNamespace:  testIdentityNamespace,
+		HostCheckTimeout:   100 * time.Millisecond,
+		MvmClientFunc: func(_ string, _ ...flclient.Options) (flclient.Client, error) {
+			return hostClient, nil
+		},
+	}
+
+	start := time.Now()
+	_, err := clusterController.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second), "expected the check to use the host check timeout")
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Hosts[0].Reachable).To(Equal(pointer.Bool(false)))
+}
+
+func TestClusterReconciliationHostDescription(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+
+	var (
+		description *flintlock.HostDescription
+		describeErr error
+	)
+
+	client := createFakeClient(g, []runtime.Object{createCluster(), mvmCluster})
+	clusterController := &controllers.MicrovmClusterReconciler{
+		Client:             client,
+		RemoteClientGetter: fakeremote.NewClusterClient,
+		IdentityNamespace:  testIdentityNamespace,
+		// Check the host on every reconcile.
+		HostCheckInterval: time.Nanosecond,
+		MvmClientFunc: func(_ string, _ ...flclient.Options) (flclient.Client, error) {
+			return &fakes.FakeClient{}, nil
+		},
+		DescribeHostFunc: func(_ context.Context, _ string, _ clientpool.Credentials) (*flintlock.HostDescription, error) {
+			return description, describeErr
+		},
+	}
+
+	specFields := func() []string {
+		_, err := clusterController.Reconcile(context.TODO(), ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace},
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reconciled.Status.Hosts).To(HaveLen(1))
+
+		return reconciled.Status.Hosts[0].MicrovmSpecFields
+	}
+
+	description = &flintlock.HostDescription{MicrovmSpecFields: []string{"id", "provider"}}
+	g.Expect(specFields()).To(ConsistOf("id", "provider"))
+
+	description, describeErr = nil, errors.New("stream reset")
+	g.Expect(specFields()).To(ConsistOf("id", "provider"), "expected the last description to be kept")
+
+	describeErr = flintlock.ErrReflectionNotServed
+	g.Expect(specFields()).To(BeEmpty(), "expected the description to be removed")
+}
+
+func TestClusterReconciliationHostInventory(t *testing.T) {
+	g := NewWithT(t)
+
+	mvmCluster := createMicrovmCluster()
+	mvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{
+		Host: "192.168.8.15",
+		Port: 6443,
+	}
+	mvmCluster.Spec.Placement.StaticPool.Hosts = append(mvmCluster.Spec.Placement.StaticPool.Hosts,
+		infrav1.MicrovmHost{Name: "host2", Endpoint: "127.0.0.2:9090"},
+		infrav1.MicrovmHost{Name: "host3", Endpoint: "127.0.0.3:9090"},
+	)
+
+	placedMachine := func(name, cluster, host string, controlPlane bool) *infrav1.MicrovmMachine {
+		machine := createMicrovmMachine()
+		machine.Name = name
+		machine.Labels = map[string]string{clusterv1.ClusterNameLabel: cluster}
+		machine.Annotations = map[string]string{}
+
+		if host != "" {
+			machine.Annotations[infrav1.HostIDAnnotation] = host
+		}
+
+		if controlPlane {
+			machine.Labels[clusterv1.MachineControlPlaneLabel] = ""
+		}
+
+		return machine
+	}
+
+	objects := []runtime.Object{
+		createCluster(),
+		mvmCluster,
+		placedMachine("cp1", testClusterName, "host1", true),
+		placedMachine("worker1", testClusterName, "host1", false),
+		placedMachine("worker2", testClusterName, "host2", false),
+		placedMachine("unplaced", testClusterName, "", false),
+		placedMachine("other-cluster", "other", "host1", true),
+	}
+
+	client := createFakeClient(g, objects)
+	_, err := reconcileCluster(client)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Status.Hosts).To(HaveLen(3))
+	g.Expect(reconciled.Status.HostCount).To(BeNumerically("==", 3))
+	g.Expect(reconciled.Status.ReachableHostCount).To(BeZero(), "hosts that haven't been called aren't reachable")
+
+	host1 := reconciled.Status.Hosts[0]
+	g.Expect(host1.ControlPlaneMicrovms).To(BeNumerically("==", 1))
+	g.Expect(host1.WorkerMicrovms).To(BeNumerically("==", 1))
+	g.Expect(host1.VCPU).To(BeNumerically("==", 4))
+	g.Expect(host1.MemoryMb).To(BeNumerically("==", 4096))
+
+	host2 := reconciled.Status.Hosts[1]
+	g.Expect(host2.ControlPlaneMicrovms).To(BeZero())
+	g.Expect(host2.WorkerMicrovms).To(BeNumerically("==", 1))
+	g.Expect(host2.VCPU).To(BeNumerically("==", 2))
+	g.Expect(host2.MemoryMb).To(BeNumerically("==", 2048))
+
+	host3 := reconciled.Status.Hosts[2]
+	g.Expect(host3.ControlPlaneMicrovms).To(BeZero())
+	g.Expect(host3.WorkerMicrovms).To(BeZero())
+	g.Expect(host3.VCPU).To(BeZero())
+	g.Expect(host3.MemoryMb).To(BeZero())
+}
+
+func TestMicrovmMachineToMicrovmCluster(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := createCluster()
+	cluster.Spec.InfrastructureRef.Kind = "MicrovmCluster"
+
+	client := createFakeClient(g, []runtime.Object{cluster, createMicrovmCluster()})
+	reconciler := &controllers.MicrovmClusterReconciler{Client: client}
+	mapFunc := reconciler.MicrovmMachineToMicrovmCluster(logr.Discard())
+
+	machine := createMicrovmMachine()
+	machine.Labels = map[string]string{clusterv1.ClusterNameLabel: testClusterName}
+
+	expected := ctrl.Request{NamespacedName: types.NamespacedName{Name: testClusterName, Namespace: testClusterNamespace}}
+	g.Expect(mapFunc(context.TODO(), machine)).To(ConsistOf(expected))
+
+	machine.Labels = nil
+	g.Expect(mapFunc(context.TODO(), machine)).To(BeEmpty(), "expect machines without a cluster not to be mapped")
+}
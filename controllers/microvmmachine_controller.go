@@ -5,6 +5,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -13,10 +14,13 @@ import (
 	flservice "github.com/liquidmetal-dev/controller-pkg/services/microvm"
 	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
 	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/collections"
@@ -32,8 +36,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/circuitbreaker"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/defaults"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/tracing"
 )
 
 // MicrovmMachineReconciler reconciles a MicrovmMachine object.
@@ -44,24 +53,45 @@ type MicrovmMachineReconciler struct {
 	WatchFilterValue string
 
 	MvmClientFunc flclient.FactoryFunc
+	// ClientPool caches the connections to the hosts. If it isn't set a new client is created
+	// using MvmClientFunc for every reconcile.
+	ClientPool *clientpool.Pool
+	// CircuitBreakers stop hosts that are down being called. If they aren't set the hosts are
+	// always called.
+	CircuitBreakers *circuitbreaker.Breakers
+	// CallPolicy is the default timeouts and retries of the calls to the hosts. MicrovmClusters
+	// can override it.
+	CallPolicy flintlock.CallPolicy
+
+	// IdentityNamespace is the namespace that the secrets of MicrovmClusterIdentities are in.
+	IdentityNamespace string
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmmachines,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmmachines/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmmachines/finalizers,verbs=update
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddressclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddresses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=microvmclusteridentities,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=secrets;,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *MicrovmMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *MicrovmMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	ctx, span := tracing.StartReconcile(ctx, "MicrovmMachine", req.NamespacedName)
+	defer func() { tracing.End(span, reterr) }()
+
 	log := log.FromContext(ctx)
 
 	mvmMachine := &infrav1.MicrovmMachine{}
 	if err := r.Get(ctx, req.NamespacedName, mvmMachine); err != nil {
 		if apierrors.IsNotFound(err) {
+			metrics.DeleteMicrovm(req.Namespace, req.Name)
+
 			return ctrl.Result{}, nil
 		}
 
@@ -125,14 +155,24 @@ func (r *MicrovmMachineReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		MicroVMMachine: mvmMachine,
 		Client:         r.Client,
 		Context:        ctx,
-	})
+	}, scope.WithMachineIdentityNamespace(r.IdentityNamespace))
 	if err != nil {
 		log.Error(err, "failed to create machine scope")
 
 		return ctrl.Result{}, fmt.Errorf("failed to create machine scope: %w", err)
 	}
 
+	previousReason := conditions.GetReason(mvmMachine, infrav1.MicrovmReadyCondition)
+
 	defer func() {
+		recordMicrovmError(machineScope, previousReason)
+		span.SetAttributes(
+			attribute.String("capmvm.cluster", cluster.Name),
+			attribute.String("capmvm.host", metricsHost(machineScope)),
+			attribute.String("capmvm.microvm_ready_reason",
+				conditions.GetReason(machineScope.MvmMachine, infrav1.MicrovmReadyCondition)),
+		)
+
 		if patchErr := machineScope.Patch(); patchErr != nil {
 			log.Error(patchErr, "failed to patch microvm machine")
 		}
@@ -162,6 +202,10 @@ func (r *MicrovmMachineReconciler) reconcileDelete(
 
 	mvmSvc, err := r.getMicrovmService(failureDomain, machineScope)
 	if err != nil {
+		if openErr := (&circuitbreaker.OpenError{}); errors.As(err, &openErr) {
+			return r.hostUnavailable(machineScope, openErr), nil
+		}
+
 		machineScope.Error(err, "failed to get microvm service")
 
 		return ctrl.Result{}, nil
@@ -171,12 +215,17 @@ func (r *MicrovmMachineReconciler) reconcileDelete(
 	microvm, err := mvmSvc.Get(ctx)
 	if err != nil && !isSpecNotFound(err) {
 		machineScope.Error(err, "failed getting microvm")
+		r.markTimeout(machineScope, err)
 
 		return ctrl.Result{}, fmt.Errorf("failed getting microvm: %w", err)
 	}
 
 	if microvm != nil {
 		machineScope.Info("deleting microvm")
+		metrics.SetMicrovmState(
+			machineScope.MvmCluster.Namespace, machineScope.MvmCluster.Name,
+			machineScope.Name(), failureDomain, microvmStateDeleting,
+		)
 
 		// Mark the machine as no longer ready before we delete.
 		machineScope.SetNotReady(infrav1.MicrovmDeletingReason, clusterv1.ConditionSeverityInfo, "")
@@ -190,17 +239,32 @@ func (r *MicrovmMachineReconciler) reconcileDelete(
 		if microvm.Status.State != flintlocktypes.MicroVMStatus_DELETING {
 			if _, err := mvmSvc.Delete(ctx); err != nil {
 				machineScope.SetNotReady(infrav1.MicrovmDeleteFailedReason, clusterv1.ConditionSeverityError, "")
+				recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeWarning,
+					DeleteFailedEventReason, "deleting microvm on host %s: %s", failureDomain, err)
+				r.markTimeout(machineScope, err)
 
 				return ctrl.Result{}, err
 			}
+
+			recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeNormal,
+				DeleteRequestedEventReason, "deleting microvm on host %s", failureDomain)
 		}
 
 		return ctrl.Result{RequeueAfter: requeuePeriod}, nil
 	}
 
-	// By this point Flintlock has no record of the MvM, so we are good to clear
-	// the finalizer
+	// By this point Flintlock has no record of the MvM, so we can give back any
+	// addresses and then clear the finalizer
+	if err := machineScope.ReleaseIPAddresses(); err != nil {
+		machineScope.Error(err, "failed to release ip addresses")
+
+		return ctrl.Result{}, err
+	}
+
 	controllerutil.RemoveFinalizer(machineScope.MvmMachine, infrav1.MachineFinalizer)
+	metrics.DeleteMicrovm(machineScope.Namespace(), machineScope.Name())
+	recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeNormal,
+		DeletedEventReason, "microvm deleted from host %s", failureDomain)
 
 	machineScope.Info("microvm deleted")
 
@@ -240,20 +304,62 @@ func (r *MicrovmMachineReconciler) reconcileNormal(
 		"machine", machineScope.MvmMachine.Name,
 		"secret", machineScope.Machine.Spec.Bootstrap.DataSecretName)
 
+	addresses, allocated, err := machineScope.ReconcileIPAddresses()
+	if err != nil {
+		machineScope.Error(err, "failed to reconcile ip addresses")
+
+		return ctrl.Result{}, err
+	}
+
+	if !allocated {
+		machineScope.Info("IP addresses are not allocated yet")
+		conditions.MarkFalse(
+			machineScope.MvmMachine, infrav1.MicrovmReadyCondition,
+			infrav1.WaitingForIPAddressReason, clusterv1.ConditionSeverityInfo,
+			"",
+		)
+
+		return ctrl.Result{}, nil
+	}
+
+	_, placementSpan := tracing.Tracer().Start(ctx, "MicrovmMachine.Placement")
 	failureDomain, err := machineScope.GetFailureDomain()
+	placementSpan.SetAttributes(attribute.String("capmvm.host", failureDomain))
+	tracing.End(placementSpan, err)
+
 	if err != nil {
+		if errors.Is(err, scope.ErrNoCompatibleHost) {
+			machineScope.Info("no host can create the microvm", "reason", err.Error())
+			r.recordConditionWarning(machineScope, infrav1.NoCompatibleHostReason, "%s", err.Error())
+			conditions.MarkFalse(
+				machineScope.MvmMachine, infrav1.MicrovmReadyCondition,
+				infrav1.NoCompatibleHostReason, clusterv1.ConditionSeverityError,
+				"%s", err.Error(),
+			)
+
+			return ctrl.Result{RequeueAfter: requeuePeriod}, nil
+		}
+
 		machineScope.Error(err, "failed to get the failure domain")
 
 		return ctrl.Result{}, err
 	}
 
-	mvmSvc, err := r.getMicrovmService(failureDomain, machineScope)
+	hostEndpoint := machineScope.GetHostEndpoint(failureDomain)
+
+	hostClient, err := r.getHostClient(failureDomain, machineScope)
 	if err != nil {
+		if openErr := (&circuitbreaker.OpenError{}); errors.As(err, &openErr) {
+			return r.hostUnavailable(machineScope, openErr), nil
+		}
+
 		machineScope.Error(err, "failed to get microvm service")
 
 		return ctrl.Result{}, err
 	}
-	defer mvmSvc.Close()
+	defer hostClient.Close()
+
+	mvmSvc := flservice.New(machineScope, hostClient, hostEndpoint)
 
 	var microvm *flintlocktypes.MicroVM
 
@@ -264,6 +370,7 @@ func (r *MicrovmMachineReconciler) reconcileNormal(
 		microvm, err = mvmSvc.Get(ctx)
 		if err != nil && !isSpecNotFound(err) {
 			machineScope.Error(err, "failed checking if microvm exists")
+			r.markTimeout(machineScope, err)
 
 			return ctrl.Result{}, err
 		}
@@ -278,17 +385,74 @@ func (r *MicrovmMachineReconciler) reconcileNormal(
 	}
 
 	if microvm == nil {
+		duplicates, err := machineScope.GetDuplicateMACAddresses(failureDomain)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("checking for duplicate mac addresses: %w", err)
+		}
+
+		if len(duplicates) > 0 {
+			machineScope.Info("mac addresses already in use on host", "macs", duplicates, "host", failureDomain)
+			r.recordConditionWarning(machineScope, infrav1.DuplicateMACAddressReason,
+				"mac addresses %s are already in use on host %s", strings.Join(duplicates, ", "), failureDomain)
+			conditions.MarkFalse(
+				machineScope.MvmMachine, infrav1.MicrovmReadyCondition,
+				infrav1.DuplicateMACAddressReason, clusterv1.ConditionSeverityWarning,
+				"mac addresses %s are already in use on host %s", strings.Join(duplicates, ", "), failureDomain,
+			)
+
+			return ctrl.Result{RequeueAfter: requeuePeriod}, nil
+		}
+
+		if err := machineScope.ResolveSSHPublicKeys(); err != nil {
+			machineScope.Error(err, "failed to get ssh public keys")
+			r.recordConditionWarning(machineScope, infrav1.SSHPublicKeysUnavailableReason, "%s", err.Error())
+			conditions.MarkFalse(
+				machineScope.MvmMachine, infrav1.MicrovmReadyCondition,
+				infrav1.SSHPublicKeysUnavailableReason, clusterv1.ConditionSeverityError,
+				"%s", err.Error(),
+			)
+
+			return ctrl.Result{}, err
+		}
+
+		// The mutators are only needed to create the microvm, so changes to the hosts or the
+		// bootstrap data don't affect the reconciles of existing microvms.
+		mutators, err := r.getSpecMutators(failureDomain, addresses, machineScope)
+		if err != nil {
+			machineScope.Error(err, "failed to get microvm spec mutators")
+
+			return ctrl.Result{}, err
+		}
+
 		machineScope.Info("creating microvm")
+		recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeNormal,
+			HostSelectedEventReason, "creating microvm on host %s", failureDomain)
 
 		var createErr error
 
-		microvm, createErr = mvmSvc.Create(ctx)
+		createSvc := flservice.New(machineScope, flintlock.NewMutatingClient(hostClient, mutators...), hostEndpoint)
+
+		microvm, createErr = createSvc.Create(ctx)
 		if createErr != nil {
+			recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeWarning,
+				CreateFailedEventReason, "creating microvm on host %s: %s", failureDomain, createErr)
+			r.markTimeout(machineScope, createErr)
+
 			return ctrl.Result{}, createErr
 		}
+
+		recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeNormal,
+			CreateRequestedEventReason, "microvm %s requested on host %s", microvm.Spec.GetUid(), failureDomain)
+	}
+
+	// The providerID of an existing microvm isn't changed as it's also set on the node, where it
+	// can't be updated. Machines created before hosts had stable IDs keep the endpoint of the host
+	// in their providerID and the host ID is recorded so they can find the host if it changes.
+	if machineScope.GetInstanceID() != *microvm.Spec.Uid {
+		machineScope.SetProviderID(failureDomain, *microvm.Spec.Uid)
 	}
 
-	machineScope.SetProviderID(failureDomain, *microvm.Spec.Uid)
+	machineScope.SetHostID(failureDomain)
 
 	if err := machineScope.Patch(); err != nil {
 		machineScope.Error(err, "unable to patch microvm machine")
@@ -296,39 +460,174 @@ func (r *MicrovmMachineReconciler) reconcileNormal(
 		return ctrl.Result{}, err
 	}
 
-	return r.parseMicroVMState(machineScope, microvm.Status.State)
+	previousState := machineScope.MvmMachine.Status.VMState
+
+	result, err := r.parseMicroVMState(machineScope, microvm.Status.State)
+	recordMicrovmState(machineScope, failureDomain, previousState, microvm)
+	r.recordStateEvent(machineScope, failureDomain, previousState)
+
+	return result, err
+}
+
+// getSpecMutators returns the changes that need to be made to the microvm spec generated by
+// the microvm service before it's created on the host.
+func (r *MicrovmMachineReconciler) getSpecMutators(
+	failureDomain string,
+	addresses map[string]*flintlocktypes.StaticAddress,
+	machineScope *scope.MachineScope,
+) ([]flintlock.SpecMutator, error) {
+	delivery, err := machineScope.GetBootstrapDelivery()
+	if err != nil {
+		return nil, fmt.Errorf("getting bootstrap delivery: %w", err)
+	}
+
+	machineScope.V(defaults.LogLevelDebug).Info("using bootstrap delivery", "delivery", delivery.Name())
+
+	overrides, err := machineScope.GetNetworkOverrides(failureDomain)
+	if err != nil {
+		return nil, fmt.Errorf("getting network overrides: %w", err)
+	}
+
+	mutators := []flintlock.SpecMutator{
+		flintlock.WithInstanceMetadata(machineScope.GetInstanceMetadata(failureDomain)),
+		flintlock.WithStaticAddresses(addresses),
+		flintlock.WithInterfaceOverrides(overrides...),
+	}
+
+	return append(mutators, delivery.Mutators()...), nil
 }
 
 func (r *MicrovmMachineReconciler) getMicrovmService(
-	addr string,
+	failureDomain string,
 	machineScope *scope.MachineScope,
 ) (*flservice.Service, error) {
-	if r.MvmClientFunc == nil {
+	hostClient, err := r.getHostClient(failureDomain, machineScope)
+	if err != nil {
+		return nil, err
+	}
+
+	return flservice.New(machineScope, hostClient, machineScope.GetHostEndpoint(failureDomain)), nil
+}
+
+// getHostClient returns a client for the flintlock host in the failure domain, using the
+// credentials of the machine's cluster.
+func (r *MicrovmMachineReconciler) getHostClient(
+	failureDomain string,
+	machineScope *scope.MachineScope,
+) (flclient.Client, error) {
+	addr := machineScope.GetHostEndpoint(failureDomain)
+
+	clients := hostClients{factory: r.MvmClientFunc, pool: r.ClientPool, breakers: r.CircuitBreakers}
+	if !clients.enabled() {
 		return nil, errClientFactoryFuncRequired
 	}
 
 	token, err := machineScope.GetBasicAuthToken(addr)
 	if err != nil {
+		r.credentialsError(machineScope, "getting basic auth token for host %s: %s", addr, err)
+
 		return nil, fmt.Errorf("getting basic auth token: %w", err)
 	}
 
-	tls, err := machineScope.GetTLSConfig()
+	tls, err := machineScope.GetTLSConfig(addr)
 	if err != nil {
+		r.credentialsError(machineScope, "getting tls config for host %s: %s", addr, err)
+
 		return nil, fmt.Errorf("getting tls config: %w", err)
 	}
 
-	clientOpts := []flclient.Options{
-		flclient.WithProxy(machineScope.MvmCluster.Spec.MicrovmProxy),
-		flclient.WithBasicAuth(token),
-		flclient.WithTLS(tls),
+	creds := clientpool.Credentials{
+		BasicAuthToken: token,
+		TLS:            tls,
+		Proxy:          machineScope.GetProxy(addr),
 	}
 
-	client, err := r.MvmClientFunc(addr, clientOpts...)
-	if err != nil {
-		return nil, fmt.Errorf("creating microvm client: %w", err)
+	return clients.newClient(machineScope.MvmCluster, failureDomain, addr, creds, machineScope.GetCallPolicy(r.CallPolicy))
+}
+
+// hostUnavailable marks the machine as waiting for its host to recover and requeues it for when
+// the host can be tried again.
+func (r *MicrovmMachineReconciler) hostUnavailable(
+	machineScope *scope.MachineScope,
+	openErr *circuitbreaker.OpenError,
+) ctrl.Result {
+	machineScope.Info("flintlock host is unavailable", "host", openErr.Host, "retryAfter", openErr.RetryAfter)
+	r.recordConditionWarning(machineScope, infrav1.HostUnavailableReason, "%s", openErr.Error())
+	conditions.MarkFalse(
+		machineScope.MvmMachine, infrav1.MicrovmReadyCondition,
+		infrav1.HostUnavailableReason, clusterv1.ConditionSeverityWarning,
+		"%s", openErr.Error(),
+	)
+
+	return ctrl.Result{RequeueAfter: openErr.RetryAfter}
+}
+
+// markTimeout marks the machine as not ready if the error is because a call to its host timed out.
+func (r *MicrovmMachineReconciler) markTimeout(machineScope *scope.MachineScope, err error) {
+	if !flintlock.IsTimeout(err) {
+		return
+	}
+
+	r.recordConditionWarning(machineScope, infrav1.FlintlockTimeoutReason, "%s", err.Error())
+	conditions.MarkFalse(
+		machineScope.MvmMachine, infrav1.MicrovmReadyCondition,
+		infrav1.FlintlockTimeoutReason, clusterv1.ConditionSeverityWarning,
+		"%s", err.Error(),
+	)
+}
+
+// credentialsError marks the machine as not ready because the credentials used to connect to
+// its host can't be read.
+func (r *MicrovmMachineReconciler) credentialsError(
+	machineScope *scope.MachineScope,
+	messageFmt string,
+	args ...interface{},
+) {
+	r.recordConditionWarning(machineScope, infrav1.CredentialsErrorReason, messageFmt, args...)
+	conditions.MarkFalse(
+		machineScope.MvmMachine, infrav1.MicrovmReadyCondition,
+		infrav1.CredentialsErrorReason, clusterv1.ConditionSeverityError,
+		messageFmt, args...,
+	)
+}
+
+// recordConditionWarning records a warning event for the machine unless the MicrovmReady
+// condition already has the reason, so that a problem that lasts for several reconciles is only
+// reported once.
+func (r *MicrovmMachineReconciler) recordConditionWarning(
+	machineScope *scope.MachineScope,
+	reason, messageFmt string,
+	args ...interface{},
+) {
+	if conditions.GetReason(machineScope.MvmMachine, infrav1.MicrovmReadyCondition) == reason {
+		return
 	}
 
-	return flservice.New(machineScope, client, addr), nil
+	recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeWarning, reason, messageFmt, args...)
+}
+
+// recordStateEvent records an event when the state of the microvm changes.
+func (r *MicrovmMachineReconciler) recordStateEvent(
+	machineScope *scope.MachineScope,
+	host string,
+	previousState *microvm.VMState,
+) {
+	state := machineScope.MvmMachine.Status.VMState
+	if state == nil || (previousState != nil && *previousState == *state) {
+		return
+	}
+
+	switch *state {
+	case microvm.VMStatePending:
+		recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeNormal,
+			MicrovmPendingEventReason, "microvm is pending on host %s", host)
+	case microvm.VMStateRunning:
+		recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeNormal,
+			MicrovmRunningEventReason, "microvm is running on host %s", host)
+	case microvm.VMStateFailed:
+		recordEvent(r.Recorder, machineScope.MvmMachine, corev1.EventTypeWarning,
+			MicrovmFailedEventReason, "microvm failed on host %s", host)
+	}
 }
 
 func (r *MicrovmMachineReconciler) parseMicroVMState(
@@ -398,6 +697,7 @@ func (r *MicrovmMachineReconciler) SetupWithManager(
 	builder := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(options).
 		For(&infrav1.MicrovmMachine{}).
+		Owns(&ipamv1.IPAddressClaim{}).
 		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(mgr.GetScheme(), log, r.WatchFilterValue)).
 		WithEventFilter(predicates.ResourceIsNotExternallyManaged(mgr.GetScheme(), log)).
 		Watches(
@@ -410,6 +710,10 @@ func (r *MicrovmMachineReconciler) SetupWithManager(
 			&infrav1.MicrovmCluster{},
 			handler.EnqueueRequestsFromMapFunc(r.MicroVMClusterToMicrovmMachine(ctx, log)),
 		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.SecretToMicrovmMachines(ctx, log)),
+		).
 		Watches(
 			&clusterv1.Cluster{},
 			handler.EnqueueRequestsFromMapFunc(clusterToObjectFunc),
@@ -486,6 +790,33 @@ func (r *MicrovmMachineReconciler) MicroVMClusterToMicrovmMachine(
 	}
 }
 
+// SecretToMicrovmMachines is called when there is a change to a Secret. Its job is to
+// identify the MicrovmMachines of the MicrovmClusters that use the secret for their host
+// credentials and queue requests for them to be reconciled.
+func (r *MicrovmMachineReconciler) SecretToMicrovmMachines(
+	ctx context.Context,
+	log logr.Logger,
+) handler.MapFunc {
+	clusterToMachines := r.MicroVMClusterToMicrovmMachine(ctx, log)
+
+	return func(ctx context.Context, o client.Object) []ctrl.Request {
+		clusters, err := getMicrovmClustersForSecret(ctx, r.Client, o, r.IdentityNamespace)
+		if err != nil {
+			log.Error(err, "failed to get microvm clusters for secret", "secret", client.ObjectKeyFromObject(o))
+
+			return nil
+		}
+
+		var result []ctrl.Request
+
+		for i := range clusters {
+			result = append(result, clusterToMachines(ctx, &clusters[i])...)
+		}
+
+		return result
+	}
+}
+
 func isSpecNotFound(err error) bool {
 	return strings.Contains(err.Error(), "not found")
 }
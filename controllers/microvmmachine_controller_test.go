@@ -4,6 +4,7 @@
 package controllers_test
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -12,16 +13,40 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
 
+	"github.com/go-logr/logr"
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
 	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
 	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers"
 	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/circuitbreaker"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/kubevip"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -191,6 +216,165 @@ func TestMachineReconcileMachineExistsAndRunning(t *testing.T) {
 	// assertMachineReconciled(g, reconciled)
 }
 
+func TestMachineReconcileMachineExistsWithUnsupportedBootstrapFormat(t *testing.T) {
+	g := NewWithT(t)
+
+	// The spec mutators are only needed to create the microvm, so a bootstrap secret that can't
+	// be delivered any more doesn't stop an existing microvm being reconciled.
+	apiObjects := defaultClusterObjects()
+	apiObjects.BootstrapSecret.Data["format"] = []byte("ignition")
+
+	fakeAPIClient := fakes.FakeClient{}
+	withExistingMicrovm(&fakeAPIClient, flintlocktypes.MicroVMStatus_CREATED)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	result, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling an existing microvm should not use the bootstrap data")
+	g.Expect(result.IsZero()).To(BeTrue(), "Expect no requeue to be requested")
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(0))
+}
+
+func TestMachineReconcileReusesPooledClient(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+
+	fakeAPIClient := fakes.FakeClient{}
+	withExistingMicrovm(&fakeAPIClient, flintlocktypes.MicroVMStatus_CREATED)
+
+	clientsCreated := 0
+	pool := clientpool.New(func(address string, opts ...flclient.Options) (flclient.Client, error) {
+		clientsCreated++
+
+		return &fakeAPIClient, nil
+	}, clientpool.Config{})
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	machineController := &controllers.MicrovmMachineReconciler{
+		Client:            client,
+		ClientPool:        pool,
+		IdentityNamespace: testIdentityNamespace,
+	}
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      testMachineName,
+			Namespace: testClusterNamespace,
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := machineController.Reconcile(context.TODO(), request)
+		g.Expect(err).NotTo(HaveOccurred(), "Reconciling when microvm service exists should not return error")
+	}
+
+	g.Expect(clientsCreated).To(Equal(1), "expect the connection to the host to be reused")
+	g.Expect(fakeAPIClient.CloseCallCount()).To(Equal(0), "expect the pooled connection to stay open")
+	g.Expect(pool.Len()).To(Equal(1))
+}
+
+func TestMachineReconcileHostUnavailable(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+
+	fakeAPIClient := fakes.FakeClient{}
+	fakeAPIClient.GetMicroVMReturns(nil, status.Error(codes.Unavailable, "connection refused"))
+
+	clientsCreated := 0
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	machineController := &controllers.MicrovmMachineReconciler{
+		Client: client,
+		MvmClientFunc: func(address string, opts ...flclient.Options) (flclient.Client, error) {
+			clientsCreated++
+
+			return &fakeAPIClient, nil
+		},
+		CircuitBreakers:   circuitbreaker.New(circuitbreaker.Config{FailureThreshold: 1, Cooldown: time.Minute}),
+		IdentityNamespace: testIdentityNamespace,
+	}
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      testMachineName,
+			Namespace: testClusterNamespace,
+		},
+	}
+
+	_, err := machineController.Reconcile(context.TODO(), request)
+	g.Expect(err).To(HaveOccurred(), "Reconciling when the host is unavailable should return error")
+
+	result, err := machineController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when the circuit breaker is open should not return error")
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", 0), "Expect requeue to be requested")
+	g.Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute))
+
+	g.Expect(clientsCreated).To(Equal(1), "expect the host not to be called when the circuit breaker is open")
+	g.Expect(fakeAPIClient.GetMicroVMCallCount()).To(Equal(1))
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred(), "Getting microvm machine should not fail")
+	assertConditionFalse(g, reconciled, v1alpha1.MicrovmReadyCondition, v1alpha1.HostUnavailableReason)
+}
+
+func TestMachineReconcileRetriedCallRecordedOnceByCircuitBreaker(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+
+	fakeAPIClient := fakes.FakeClient{}
+	fakeAPIClient.GetMicroVMReturns(nil, status.Error(codes.Unavailable, "connection refused"))
+
+	breakers := circuitbreaker.New(circuitbreaker.Config{})
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	machineController := &controllers.MicrovmMachineReconciler{
+		Client: client,
+		MvmClientFunc: func(address string, opts ...flclient.Options) (flclient.Client, error) {
+			return &fakeAPIClient, nil
+		},
+		CircuitBreakers: breakers,
+		CallPolicy: flintlock.CallPolicy{
+			GetTimeout:   flintlock.DefaultGetTimeout,
+			Retries:      flintlock.DefaultRetries,
+			RetryBackoff: time.Millisecond,
+		},
+		IdentityNamespace: testIdentityNamespace,
+	}
+
+	_, err := machineController.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testMachineName, Namespace: testClusterNamespace},
+	})
+	g.Expect(err).To(HaveOccurred(), "Reconciling when the host is unavailable should return error")
+
+	g.Expect(fakeAPIClient.GetMicroVMCallCount()).To(Equal(flintlock.DefaultRetries + 1))
+	g.Expect(breakers.State("127.0.0.1:9090")).To(Equal(circuitbreaker.StateClosed),
+		"expect the retries of one call not to open the circuit breaker")
+}
+
+func TestMachineReconcileGetTimeout(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmCluster.Spec.FlintlockCalls = &v1alpha1.FlintlockCallsSpec{
+		Retries:      pointer.Int32(1),
+		RetryBackoff: &metav1.Duration{Duration: time.Millisecond},
+	}
+
+	fakeAPIClient := fakes.FakeClient{}
+	fakeAPIClient.GetMicroVMReturns(nil, status.Error(codes.DeadlineExceeded, "deadline exceeded"))
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).To(HaveOccurred(), "Reconciling when getting the microvm times out should return error")
+
+	g.Expect(fakeAPIClient.GetMicroVMCallCount()).To(Equal(2), "expect get to be retried")
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred(), "Getting microvm machine should not fail")
+	assertConditionFalse(g, reconciled, v1alpha1.MicrovmReadyCondition, v1alpha1.FlintlockTimeoutReason)
+}
+
 func TestMachineReconcileMachineExistsAndPending(t *testing.T) {
 	g := NewWithT(t)
 
@@ -274,15 +458,19 @@ func TestMachineReconcileNoVmCreateSucceeds(t *testing.T) {
 	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
 	g.Expect(createReq.Microvm).ToNot(BeNil())
 	g.Expect(createReq.Microvm.Labels).To(HaveLen(1))
-	g.Expect(createReq.Microvm.Metadata).To(HaveLen(3))
+	g.Expect(createReq.Microvm.Metadata).To(HaveLen(4))
 	expectedBootstrapData := base64.StdEncoding.EncodeToString([]byte(testbootStrapData))
 	g.Expect(createReq.Microvm.Metadata).To(HaveKeyWithValue("user-data", expectedBootstrapData))
+	g.Expect(createReq.Microvm.Metadata).To(HaveKey("network-config"), "expect cloud-init network-config to be created")
+	g.Expect(createReq.Microvm.Kernel.AddNetworkConfig).To(BeFalse())
 
 	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
 	g.Expect(err).NotTo(HaveOccurred(), "Getting microvm machine should not fail")
 
-	expectedProviderID := fmt.Sprintf("microvm://127.0.0.1:9090/%s", testMachineUID)
+	expectedProviderID := fmt.Sprintf("microvm://host1/%s", testMachineUID)
 	g.Expect(reconciled.Spec.ProviderID).To(Equal(pointer.String(expectedProviderID)))
+	g.Expect(reconciled.Annotations).To(HaveKeyWithValue(v1alpha1.HostIDAnnotation, "host1"))
+	g.Expect(reconciled.Annotations).To(HaveKeyWithValue(v1alpha1.HostEndpointAnnotation, "127.0.0.1:9090"))
 
 	// TODO: renable these assertions when moved to envtest
 	// assertConditionFalse(g, reconciled, infrav1.MicrovmReadyCondition, infrav1.MicrovmPendingReason)
@@ -290,6 +478,449 @@ func TestMachineReconcileNoVmCreateSucceeds(t *testing.T) {
 	// assertMachineFinalizer(g, reconciled)
 }
 
+func TestMachineReconcileNoVmCreateInstanceMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.InstanceMetadata = map[string]string{"rack": "r42"}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm).ToNot(BeNil())
+	g.Expect(createReq.Microvm.Metadata).To(HaveKey("meta-data"), "expect cloud-init meta-data to be created")
+
+	instanceData := decodeInstanceData(g, createReq.Microvm.Metadata["meta-data"])
+	g.Expect(instanceData).To(HaveKeyWithValue("vm_host", "127.0.0.1:9090"))
+	g.Expect(instanceData).To(HaveKeyWithValue("cluster_name", testClusterName))
+	g.Expect(instanceData).To(HaveKeyWithValue("cluster_namespace", testClusterNamespace))
+	g.Expect(instanceData).To(HaveKeyWithValue("machine_name", testMachineName))
+	g.Expect(instanceData).To(HaveKeyWithValue("failure_domain", "host1"))
+	g.Expect(instanceData).To(HaveKeyWithValue("control_plane", "false"))
+	g.Expect(instanceData).To(HaveKeyWithValue("rack", "r42"))
+	g.Expect(instanceData).To(HaveKey("labels"))
+}
+
+func TestMachineReconcileNoVmCreateTalosBootstrap(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.BootstrapSecret.Data["format"] = []byte("talos")
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm).ToNot(BeNil())
+	g.Expect(createReq.Microvm.Kernel.Cmdline).To(HaveKeyWithValue("talos.platform", "nocloud"))
+	g.Expect(createReq.Microvm.Metadata).To(HaveKey("user-data"))
+	g.Expect(createReq.Microvm.Metadata).To(HaveKey("meta-data"))
+	g.Expect(createReq.Microvm.Metadata).NotTo(HaveKey("vendor-data"), "expect no cloud-init vendor-data for talos")
+}
+
+func TestMachineReconcileNoVmCreateKubeVIP(t *testing.T) {
+	tt := []struct {
+		name             string
+		version          *string
+		command          string
+		expectKubeconfig string
+		expectRunCmd     []string
+	}{
+		{
+			name:             "init node",
+			version:          pointer.String("v1.30.1"),
+			command:          "kubeadm init",
+			expectKubeconfig: "/etc/kubernetes/super-admin.conf",
+			expectRunCmd:     []string{"kubeadm init", kubevip.RestoreKubeconfigCommand},
+		},
+		{
+			name:             "init node before kubernetes 1.29",
+			version:          pointer.String("v1.28.9"),
+			command:          "kubeadm init",
+			expectKubeconfig: "/etc/kubernetes/admin.conf",
+			expectRunCmd:     []string{"kubeadm init"},
+		},
+		{
+			name:             "joining node",
+			version:          pointer.String("v1.30.1"),
+			command:          "kubeadm join",
+			expectKubeconfig: "/etc/kubernetes/admin.conf",
+			expectRunCmd:     []string{"kubeadm join"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			apiObjects := defaultClusterObjects()
+			apiObjects.MvmMachine.Spec.ProviderID = nil
+			apiObjects.Machine.Labels[clusterv1.MachineControlPlaneLabel] = ""
+			apiObjects.Machine.Spec.Version = tc.version
+			apiObjects.MvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "192.168.8.15", Port: 6443}
+			apiObjects.MvmCluster.Spec.LoadBalancer = &v1alpha1.LoadBalancerSpec{Type: v1alpha1.LoadBalancerTypeKubeVIPARP}
+			apiObjects.BootstrapSecret.Data["value"] = []byte("#cloud-config\nruncmd:\n- " + tc.command + "\n")
+
+			fakeAPIClient := fakes.FakeClient{}
+			withMissingMicrovm(&fakeAPIClient)
+			withCreateMicrovmSuccess(&fakeAPIClient)
+
+			client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+			_, err := reconcileMachine(client, &fakeAPIClient)
+			g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+
+			_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+			g.Expect(createReq.Microvm).ToNot(BeNil())
+
+			data, err := base64.StdEncoding.DecodeString(createReq.Microvm.Metadata["user-data"])
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(data)).To(HavePrefix("#cloud-config\n"))
+
+			userData := struct {
+				WriteFiles []flintlock.File `json:"write_files"`
+				RunCmd     []string         `json:"runcmd"`
+			}{}
+			g.Expect(yaml.Unmarshal(data, &userData)).To(Succeed())
+			g.Expect(userData.RunCmd).To(Equal(tc.expectRunCmd))
+			g.Expect(userData.WriteFiles).To(HaveLen(1))
+			g.Expect(userData.WriteFiles[0].Path).To(Equal(kubevip.ManifestPath))
+
+			pod := &corev1.Pod{}
+			g.Expect(yaml.Unmarshal([]byte(userData.WriteFiles[0].Content), pod)).To(Succeed())
+			g.Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "address", Value: "192.168.8.15"}))
+			g.Expect(pod.Spec.Volumes[0].HostPath.Path).To(Equal(tc.expectKubeconfig))
+		})
+	}
+}
+
+func TestMachineReconcileNoVmCreateIPv6Host(t *testing.T) {
+	g := NewWithT(t)
+
+	hostEndpoint := "[fd00::1]:9090"
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.Machine.Spec.FailureDomain = pointer.String(hostEndpoint)
+	apiObjects.Machine.Labels[clusterv1.MachineControlPlaneLabel] = ""
+	apiObjects.Cluster.Status.FailureDomains = clusterv1.FailureDomains{
+		hostEndpoint: clusterv1.FailureDomainSpec{ControlPlane: true},
+	}
+	apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].Name = ""
+	apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].Endpoint = hostEndpoint
+	apiObjects.MvmCluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "fd00::10", Port: 6443}
+	apiObjects.MvmCluster.Spec.LoadBalancer = &v1alpha1.LoadBalancerSpec{Type: v1alpha1.LoadBalancerTypeKubeVIPARP}
+	apiObjects.BootstrapSecret.Data["value"] = []byte("#cloud-config\nruncmd:\n- kubeadm init\n")
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	var dialedAddress string
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	machineController := &controllers.MicrovmMachineReconciler{
+		Client: client,
+		MvmClientFunc: func(address string, opts ...flclient.Options) (flclient.Client, error) {
+			dialedAddress = address
+
+			return &fakeAPIClient, nil
+		},
+		IdentityNamespace: testIdentityNamespace,
+	}
+
+	_, err := machineController.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testMachineName, Namespace: testClusterNamespace},
+	})
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+	g.Expect(dialedAddress).To(Equal(hostEndpoint))
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm).ToNot(BeNil())
+
+	userData, err := base64.StdEncoding.DecodeString(createReq.Microvm.Metadata["user-data"])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(userData)).To(ContainSubstring("fd00::10"))
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred(), "Getting microvm machine should not fail")
+
+	expectedProviderID := fmt.Sprintf("microvm://[fd00::1]:9090/%s", testMachineUID)
+	g.Expect(reconciled.Spec.ProviderID).To(Equal(pointer.String(expectedProviderID)))
+}
+
+func TestMachineReconcileNoVmCreateIPv6HostDHCP(t *testing.T) {
+	g := NewWithT(t)
+
+	hostEndpoint := "[fd00::1]:9090"
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.Machine.Spec.FailureDomain = pointer.String(hostEndpoint)
+	apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].Name = ""
+	apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].Endpoint = hostEndpoint
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm.Kernel.AddNetworkConfig).To(BeFalse())
+
+	networkConfig, err := base64.StdEncoding.DecodeString(createReq.Microvm.Metadata["network-config"])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(networkConfig)).To(ContainSubstring("dhcp6: true"), "expect the dhcp interface to get an ipv6 address")
+	g.Expect(string(networkConfig)).To(ContainSubstring("dhcp4: true"))
+}
+
+func TestMachineReconcileLegacyProviderIDMigration(t *testing.T) {
+	g := NewWithT(t)
+
+	legacyProviderID := fmt.Sprintf("microvm://127.0.0.1:9090/%s", testMachineUID)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = pointer.String(legacyProviderID)
+	apiObjects.Machine.Spec.FailureDomain = pointer.String("127.0.0.1:9090")
+
+	fakeAPIClient := fakes.FakeClient{}
+	withExistingMicrovm(&fakeAPIClient, flintlocktypes.MicroVMStatus_CREATED)
+
+	var dialedAddress string
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	machineController := &controllers.MicrovmMachineReconciler{
+		Client: client,
+		MvmClientFunc: func(address string, opts ...flclient.Options) (flclient.Client, error) {
+			dialedAddress = address
+
+			return &fakeAPIClient, nil
+		},
+		IdentityNamespace: testIdentityNamespace,
+	}
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testMachineName, Namespace: testClusterNamespace},
+	}
+
+	_, err := machineController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(dialedAddress).To(Equal("127.0.0.1:9090"))
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Spec.ProviderID).To(Equal(pointer.String(legacyProviderID)), "Expect the providerID not to change")
+	g.Expect(reconciled.Annotations).To(HaveKeyWithValue(v1alpha1.HostIDAnnotation, "host1"))
+
+	mvmCluster, err := getMicrovmCluster(context.TODO(), client, testClusterName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].Endpoint = "10.0.0.5:9090"
+	g.Expect(client.Update(context.TODO(), mvmCluster)).To(Succeed())
+
+	_, err = machineController.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(dialedAddress).To(Equal("10.0.0.5:9090"), "Expect the new endpoint of the host to be used")
+
+	reconciled, err = getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reconciled.Spec.ProviderID).To(Equal(pointer.String(legacyProviderID)))
+}
+
+func TestMachineReconcileNoVmCreateHostNetworkOverrides(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].NetworkOverrides = []v1alpha1.HostNetworkOverride{
+		{GuestDeviceName: "eth0", Type: microvm.IfaceTypeTap, BridgeName: "br0"},
+	}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm.Interfaces).To(HaveLen(1))
+	g.Expect(createReq.Microvm.Interfaces[0].Type).To(Equal(flintlocktypes.NetworkInterface_TAP))
+	g.Expect(createReq.Microvm.Interfaces[0].Overrides).NotTo(BeNil())
+	g.Expect(createReq.Microvm.Interfaces[0].Overrides.BridgeName).To(Equal(pointer.String("br0")))
+}
+
+func TestMachineReconcileNoVmCreateNamedNetworkVLAN(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.NetworkInterfaceConfigs = []v1alpha1.NetworkInterfaceConfig{
+		{GuestDeviceName: "eth0", Network: "storage", VLANID: pointer.Int32(20)},
+	}
+	apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].Networks = []v1alpha1.HostNetwork{
+		{Name: "storage", BridgeName: "br-storage"},
+	}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm.Interfaces[0].Type).To(Equal(flintlocktypes.NetworkInterface_TAP))
+	g.Expect(createReq.Microvm.Interfaces[0].Overrides.BridgeName).To(Equal(pointer.String("br-storage")))
+	g.Expect(createReq.Microvm.Kernel.AddNetworkConfig).To(BeFalse())
+
+	networkConfig, err := base64.StdEncoding.DecodeString(createReq.Microvm.Metadata["network-config"])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(networkConfig)).To(ContainSubstring("eth0.20:"))
+}
+
+func TestMachineReconcileNoVmCreateMissingHostNetwork(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.NetworkInterfaceConfigs = []v1alpha1.NetworkInterfaceConfig{
+		{GuestDeviceName: "eth0", Network: "storage"},
+	}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	result, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when no host has the network should not return error")
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", 0), "Expect requeue to be requested")
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(0))
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, v1alpha1.MicrovmReadyCondition, v1alpha1.NoCompatibleHostReason)
+	g.Expect(conditions.GetMessage(reconciled, v1alpha1.MicrovmReadyCondition)).To(ContainSubstring("network storage"))
+}
+
+func TestMachineReconcileNoVmCreateDuplicateMACOnHost(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.NetworkInterfaces[0].GuestMAC = "02:00:5e:00:00:01"
+
+	otherMachine := createMicrovmMachine()
+	otherMachine.Name = "other-machine"
+	otherMachine.Namespace = "other-ns"
+	otherMachine.Spec.ProviderID = pointer.String("microvm://127.0.0.1:9090/other")
+	otherMachine.Spec.NetworkInterfaces[0].GuestMAC = "02:00:5E:00:00:01"
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, append(apiObjects.AsRuntimeObjects(), otherMachine))
+	result, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", 0), "Expect requeue when mac address is in use")
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(0), "Expect microvm not to be created with a duplicate mac address")
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, v1alpha1.MicrovmReadyCondition, v1alpha1.DuplicateMACAddressReason)
+}
+
+func TestMachineReconcileNoVmCreateDuplicateMACOnSharedNamedHost(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.NetworkInterfaces[0].GuestMAC = "02:00:5e:00:00:01"
+
+	// The other cluster gives the shared host a different name.
+	otherMachine := createMicrovmMachine()
+	otherMachine.Name = "other-machine"
+	otherMachine.Namespace = "other-ns"
+	otherMachine.Labels = map[string]string{clusterv1.ClusterNameLabel: "other-cluster"}
+	otherMachine.Annotations = map[string]string{
+		v1alpha1.HostIDAnnotation:       "shared",
+		v1alpha1.HostEndpointAnnotation: "127.0.0.1:9090",
+	}
+	otherMachine.Spec.ProviderID = pointer.String("microvm://shared/other")
+	otherMachine.Spec.NetworkInterfaces[0].GuestMAC = "02:00:5E:00:00:01"
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, append(apiObjects.AsRuntimeObjects(), otherMachine))
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(0), "Expect microvm not to be created with a duplicate mac address")
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, v1alpha1.MicrovmReadyCondition, v1alpha1.DuplicateMACAddressReason)
+}
+
+func TestMachineReconcileNoVmCreateWaitsForIPAddress(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.NetworkInterfaceConfigs = []v1alpha1.NetworkInterfaceConfig{
+		{GuestDeviceName: "eth0", AddressFromPool: &corev1.TypedLocalObjectReference{Kind: "InClusterIPPool", Name: "pool1"}},
+	}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	result, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when waiting for an ip address should not return error")
+	g.Expect(result.IsZero()).To(BeTrue(), "Expect no requeue as the claim is watched")
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(0), "Expect microvm not to be created until address allocated")
+
+	claim, err := getIPAddressClaim(client, testMachineName+"-eth0", testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred(), "Expect ip address claim to be created")
+	g.Expect(claim.Spec.PoolRef.Name).To(Equal("pool1"))
+
+	claim.Status.AddressRef.Name = "address1"
+	g.Expect(client.Status().Update(context.TODO(), claim)).To(Succeed())
+	g.Expect(client.Create(context.TODO(), &ipamv1.IPAddress{
+		ObjectMeta: metav1.ObjectMeta{Name: "address1", Namespace: testClusterNamespace},
+		Spec:       ipamv1.IPAddressSpec{Address: "10.0.0.5", Prefix: 24, Gateway: "10.0.0.1"},
+	})).To(Succeed())
+
+	_, err = reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(1))
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm.Interfaces).To(HaveLen(1))
+	g.Expect(createReq.Microvm.Interfaces[0].Address).NotTo(BeNil())
+	g.Expect(createReq.Microvm.Interfaces[0].Address.Address).To(Equal("10.0.0.5/24"))
+	g.Expect(createReq.Microvm.Interfaces[0].Address.Gateway).To(Equal(pointer.String("10.0.0.1")))
+}
+
 func TestMachineReconcileNoMachineFailureDomainCreateSucceeds(t *testing.T) {
 	g := NewWithT(t)
 
@@ -341,11 +972,12 @@ func TestMachineReconcileNoVmCreateClusterSSHSucceeds(t *testing.T) {
 	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
 	g.Expect(createReq.Microvm).ToNot(BeNil())
 	g.Expect(createReq.Microvm.Labels).To(HaveLen(1))
-	g.Expect(createReq.Microvm.Metadata).To(HaveLen(3))
+	g.Expect(createReq.Microvm.Metadata).To(HaveLen(4))
 
 	expectedBootstrapData := base64.StdEncoding.EncodeToString([]byte(testbootStrapData))
 	g.Expect(createReq.Microvm.Metadata).To(HaveKeyWithValue("user-data", expectedBootstrapData))
 
+	g.Expect(createReq.Microvm.Metadata).To(HaveKey("network-config"), "expect cloud-init network-config to be created")
 	g.Expect(createReq.Microvm.Metadata).To(HaveKey("vendor-data"), "expect cloud-init vendor-data to be created")
 	assertVendorData(g, createReq.Microvm.Metadata["vendor-data"], expectedKeys)
 }
@@ -379,15 +1011,91 @@ func TestMachineReconcileNoVmCreateClusterMachineSSHSucceeds(t *testing.T) {
 	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
 	g.Expect(createReq.Microvm).ToNot(BeNil())
 	g.Expect(createReq.Microvm.Labels).To(HaveLen(1))
-	g.Expect(createReq.Microvm.Metadata).To(HaveLen(3))
+	g.Expect(createReq.Microvm.Metadata).To(HaveLen(4))
 
 	expectedBootstrapData := base64.StdEncoding.EncodeToString([]byte(testbootStrapData))
 	g.Expect(createReq.Microvm.Metadata).To(HaveKeyWithValue("user-data", expectedBootstrapData))
 
+	g.Expect(createReq.Microvm.Metadata).To(HaveKey("network-config"), "expect cloud-init network-config to be created")
 	g.Expect(createReq.Microvm.Metadata).To(HaveKey("vendor-data"), "expect cloud-init vendor-data to be created")
 	assertVendorData(g, createReq.Microvm.Metadata["vendor-data"], expectedKeys)
 }
 
+func TestMachineReconcileNoVmCreateMergedSSHKeysFromConfigMap(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	clusterKey := createSSHPublicKey(1, "cluster")
+	sharedKey := createSSHPublicKey(2, "shared")
+	machineKey := createSSHPublicKey(3, "machine")
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmCluster.Spec.SSHPublicKeysPolicy = v1alpha1.SSHPublicKeysPolicyMerge
+	apiObjects.MvmCluster.Spec.SSHPublicKeys = []microvm.SSHPublicKey{{User: "ubuntu", AuthorizedKeys: []string{clusterKey}}}
+	apiObjects.MvmCluster.Spec.SSHPublicKeysFrom = []v1alpha1.SSHPublicKeySource{{
+		User: "ubuntu",
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "access-keys"},
+			Key:                  "ubuntu",
+		},
+	}}
+	apiObjects.MvmMachine.Spec.SSHPublicKeys = []microvm.SSHPublicKey{
+		{User: "ubuntu", AuthorizedKeys: []string{machineKey, sharedKey}},
+		{User: "root", AuthorizedKeys: []string{machineKey}},
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "access-keys", Namespace: testClusterNamespace},
+		Data: map[string]string{
+			"ubuntu": "# managed by the access system\n" + sharedKey + "\n\n" + clusterKey + "\n",
+		},
+	}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, append(apiObjects.AsRuntimeObjects(), configMap))
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when creating microvm should not return error")
+
+	_, createReq, _ := fakeAPIClient.CreateMicroVMArgsForCall(0)
+	g.Expect(createReq.Microvm).ToNot(BeNil())
+	assertVendorData(g, createReq.Microvm.Metadata["vendor-data"], []microvm.SSHPublicKey{
+		{User: "ubuntu", AuthorizedKeys: []string{clusterKey, sharedKey, machineKey}},
+		{User: "root", AuthorizedKeys: []string{machineKey}},
+	})
+}
+
+func TestMachineReconcileNoVmCreateMissingSSHKeys(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.SSHPublicKeysFrom = []v1alpha1.SSHPublicKeySource{{
+		User: "ubuntu",
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "missing-keys"},
+			Key:                  "ubuntu",
+		},
+	}}
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).To(HaveOccurred(), "Reconciling when the ssh keys are missing should return an error")
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(0), "expect the microvm not to be created")
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	assertConditionFalse(g, reconciled, v1alpha1.MicrovmReadyCondition, v1alpha1.SSHPublicKeysUnavailableReason)
+}
+
 func TestMachineReconcileNoVmCreateAdditionReconcile(t *testing.T) {
 	g := NewWithT(t)
 
@@ -509,3 +1217,153 @@ func TestMachineReconcileDeleteDeleteErrors(t *testing.T) {
 	// assertConditionFalse(g, reconciled, infrav1.MicrovmReadyCondition, infrav1.MicrovmDeleteFailedReason)
 	// assertMachineNotReady(g, reconciled)
 }
+
+func TestSecretToMicrovmMachines(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmCluster.Spec.TLSSecretRef = "cluster-tls"
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	reconciler := &controllers.MicrovmMachineReconciler{Client: client}
+	mapFunc := reconciler.SecretToMicrovmMachines(context.TODO(), logr.Discard())
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-tls", Namespace: testClusterNamespace}}
+	g.Expect(mapFunc(context.TODO(), secret)).To(ConsistOf(ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: testMachineName, Namespace: testClusterNamespace},
+	}))
+
+	otherSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testClusterNamespace}}
+	g.Expect(mapFunc(context.TODO(), otherSecret)).To(BeEmpty())
+}
+
+func TestMachineReconcileNoCompatibleHost(t *testing.T) {
+	g := NewWithT(t)
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmCluster.Spec.Placement.StaticPool.Hosts[0].Capabilities = &v1alpha1.HostCapabilities{
+		Providers: []string{"firecracker"},
+	}
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+	apiObjects.MvmMachine.Spec.Provider = "cloudhypervisor"
+
+	fakeAPIClient := fakes.FakeClient{}
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	result, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred(), "Reconciling when no host is compatible should not return error")
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", 0), "Expect requeue to be requested")
+
+	g.Expect(fakeAPIClient.CreateMicroVMCallCount()).To(Equal(0), "expect the microvm not to be created")
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred(), "Getting microvm machine should not fail")
+	assertConditionFalse(g, reconciled, v1alpha1.MicrovmReadyCondition, v1alpha1.NoCompatibleHostReason)
+	g.Expect(conditions.GetMessage(reconciled, v1alpha1.MicrovmReadyCondition)).To(ContainSubstring("failure domain host1"),
+		"expect the failure domain of the machine to be named")
+}
+
+func TestMachineReconcileRecordsMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	metrics.DeleteClusterMetrics(testClusterNamespace, testClusterName)
+	defer metrics.DeleteClusterMetrics(testClusterNamespace, testClusterName)
+
+	apiObjects := defaultClusterObjects()
+
+	fakeAPIClient := fakes.FakeClient{}
+	fakeAPIClient.GetMicroVMReturns(&flintlockv1.GetMicroVMResponse{
+		Microvm: &flintlocktypes.MicroVM{
+			Spec: &flintlocktypes.MicroVMSpec{
+				Uid:       pointer.String(testMachineUID),
+				CreatedAt: timestamppb.New(time.Now().Add(-time.Minute)),
+			},
+			Status: &flintlocktypes.MicroVMStatus{
+				State: flintlocktypes.MicroVMStatus_CREATED,
+			},
+		},
+	}, nil)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	host := reconciled.Annotations[v1alpha1.HostIDAnnotation]
+
+	g.Expect(testutil.ToFloat64(metrics.Microvms.WithLabelValues(testClusterNamespace, testClusterName, host, "created"))).
+		To(BeNumerically("==", 1))
+	g.Expect(testutil.CollectAndCount(metrics.MicrovmCreateDuration)).To(Equal(1), "expect the time to create to be recorded")
+}
+
+func TestMachineReconcileRecordsErrorsOnce(t *testing.T) {
+	g := NewWithT(t)
+
+	metrics.DeleteClusterMetrics(testClusterNamespace, testClusterName)
+	defer metrics.DeleteClusterMetrics(testClusterNamespace, testClusterName)
+
+	apiObjects := defaultClusterObjects()
+
+	fakeAPIClient := fakes.FakeClient{}
+	withExistingMicrovm(&fakeAPIClient, flintlocktypes.MicroVMStatus_FAILED)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+
+	for i := 0; i < 2; i++ {
+		_, err := reconcileMachine(client, &fakeAPIClient)
+		g.Expect(err).To(HaveOccurred())
+	}
+
+	reconciled, err := getMicrovmMachine(client, testMachineName, testClusterNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	host := reconciled.Annotations[v1alpha1.HostIDAnnotation]
+
+	g.Expect(testutil.ToFloat64(metrics.MicrovmErrors.WithLabelValues(
+		testClusterNamespace, testClusterName, host, v1alpha1.MicrovmProvisionFailedReason,
+	))).To(BeNumerically("==", 1), "expect a machine that stays failed to be counted once")
+	g.Expect(testutil.ToFloat64(metrics.Microvms.WithLabelValues(testClusterNamespace, testClusterName, host, "failed"))).
+		To(BeNumerically("==", 1))
+}
+
+func TestMachineReconcileTracing(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	apiObjects := defaultClusterObjects()
+	apiObjects.MvmMachine.Spec.ProviderID = nil
+
+	fakeAPIClient := fakes.FakeClient{}
+	withMissingMicrovm(&fakeAPIClient)
+	withCreateMicrovmSuccess(&fakeAPIClient)
+
+	client := createFakeClient(g, apiObjects.AsRuntimeObjects())
+	_, err := reconcileMachine(client, &fakeAPIClient)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	g.Expect(spans).To(HaveKey("MicrovmMachine.Reconcile"))
+	reconcileSpan := spans["MicrovmMachine.Reconcile"]
+	g.Expect(reconcileSpan.Attributes()).To(ContainElements(
+		attribute.String("capmvm.name", testMachineName),
+		attribute.String("capmvm.cluster", testClusterName),
+		attribute.String("capmvm.host", "host1"),
+		attribute.String("capmvm.microvm_ready_reason", v1alpha1.MicrovmPendingReason),
+	))
+
+	for _, name := range []string{
+		"MicrovmMachine.Placement",
+		"microvm.services.api.v1alpha1.MicroVM/CreateMicroVM",
+	} {
+		g.Expect(spans).To(HaveKey(name))
+		g.Expect(spans[name].Parent().SpanID()).To(Equal(reconcileSpan.SpanContext().SpanID()),
+			"expected %s to be a child of the reconcile", name)
+	}
+}
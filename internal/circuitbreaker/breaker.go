@@ -0,0 +1,226 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package circuitbreaker stops the controllers calling flintlock hosts that are down. Calls to a
+// host that is down block until they time out, which ties up the workers that would otherwise
+// reconcile the machines on healthy hosts.
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive failed calls to a host before its
+	// circuit breaker opens if a threshold isn't specified.
+	DefaultFailureThreshold = 3
+	// DefaultCooldown is how long a circuit breaker stays open before a call to the host is
+	// tried again if a cooldown isn't specified.
+	DefaultCooldown = 30 * time.Second
+)
+
+// State is the state of the circuit breaker for a host.
+type State string
+
+const (
+	// StateClosed means the host is called as normal.
+	StateClosed State = "closed"
+	// StateOpen means the host has failed and isn't called until the cooldown has passed.
+	StateOpen State = "open"
+	// StateHalfOpen means the cooldown has passed and a single call is being tried to find out
+	// if the host has recovered.
+	StateHalfOpen State = "half-open"
+)
+
+// Config is the configuration of the circuit breakers.
+type Config struct {
+	// FailureThreshold is the number of consecutive failed calls to a host before its circuit
+	// breaker opens.
+	FailureThreshold int
+	// Cooldown is how long a circuit breaker stays open before a call to the host is tried again.
+	Cooldown time.Duration
+}
+
+// Breakers are the circuit breakers for the flintlock hosts, keyed by the host endpoint. They're
+// safe for concurrent use so they can be shared by the controllers.
+type Breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+type breaker struct {
+	state    State
+	failures int
+	// since is when the breaker opened or, when half-open, when the trial call was allowed.
+	since time.Time
+}
+
+// New creates the circuit breakers.
+func New(cfg Config) *Breakers {
+	threshold := cfg.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+
+	cooldown := cfg.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+
+	return &Breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     map[string]*breaker{},
+	}
+}
+
+// Allow returns an OpenError if the host shouldn't be called. Once the cooldown has passed
+// a single call is allowed to find out if the host has recovered. If the result of that call
+// isn't recorded then another call is allowed after the cooldown.
+func (b *Breakers) Allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.hosts[host]
+	if !ok || br.state == StateClosed {
+		return nil
+	}
+
+	if remaining := b.cooldown - time.Since(br.since); remaining > 0 {
+		return &OpenError{Host: host, RetryAfter: remaining}
+	}
+
+	br.state = StateHalfOpen
+	br.since = time.Now()
+
+	return nil
+}
+
+// Record records the result of a call to the host. The circuit breaker opens after consecutive
+// calls fail because the host is unavailable or the call timed out, or if the trial call made
+// while it's half-open fails. Any other result means the host is up and closes it.
+func (b *Breakers) Record(host string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.hosts[host]
+	if !ok {
+		br = &breaker{state: StateClosed}
+		b.hosts[host] = br
+	}
+
+	if !IsHostFailure(err) {
+		br.state = StateClosed
+		br.failures = 0
+
+		return
+	}
+
+	br.failures++
+
+	if br.state == StateHalfOpen || br.failures >= b.threshold {
+		br.state = StateOpen
+		br.since = time.Now()
+	}
+}
+
+// State returns the state of the circuit breaker for the host.
+func (b *Breakers) State(host string) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if br, ok := b.hosts[host]; ok {
+		return br.state
+	}
+
+	return StateClosed
+}
+
+// Wrap wraps the client for the host so that the result of every call is recorded.
+func (b *Breakers) Wrap(host string, client flclient.Client) flclient.Client {
+	return &recordingClient{
+		Client:   client,
+		breakers: b,
+		host:     host,
+	}
+}
+
+// IsHostFailure returns true if the error means the host couldn't be reached or didn't respond
+// in time.
+func IsHostFailure(err error) bool {
+	return flintlock.IsUnavailable(err) || flintlock.IsTimeout(err)
+}
+
+type recordingClient struct {
+	flclient.Client
+
+	breakers *Breakers
+	host     string
+}
+
+func (c *recordingClient) CreateMicroVM(
+	ctx context.Context,
+	in *flintlockv1.CreateMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.CreateMicroVMResponse, error) {
+	resp, err := c.Client.CreateMicroVM(ctx, in, opts...)
+	c.breakers.Record(c.host, err)
+
+	return resp, err
+}
+
+func (c *recordingClient) DeleteMicroVM(
+	ctx context.Context,
+	in *flintlockv1.DeleteMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	resp, err := c.Client.DeleteMicroVM(ctx, in, opts...)
+	c.breakers.Record(c.host, err)
+
+	return resp, err
+}
+
+func (c *recordingClient) GetMicroVM(
+	ctx context.Context,
+	in *flintlockv1.GetMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.GetMicroVMResponse, error) {
+	resp, err := c.Client.GetMicroVM(ctx, in, opts...)
+	c.breakers.Record(c.host, err)
+
+	return resp, err
+}
+
+func (c *recordingClient) ListMicroVMs(
+	ctx context.Context,
+	in *flintlockv1.ListMicroVMsRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.ListMicroVMsResponse, error) {
+	resp, err := c.Client.ListMicroVMs(ctx, in, opts...)
+	c.breakers.Record(c.host, err)
+
+	return resp, err
+}
+
+func (c *recordingClient) ListMicroVMsStream(
+	ctx context.Context,
+	in *flintlockv1.ListMicroVMsRequest,
+	opts ...grpc.CallOption,
+) (grpc.ServerStreamingClient[flintlockv1.ListMessage], error) {
+	stream, err := c.Client.ListMicroVMsStream(ctx, in, opts...)
+	c.breakers.Record(c.host, err)
+
+	return stream, err
+}
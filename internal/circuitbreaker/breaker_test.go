@@ -0,0 +1,152 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/circuitbreaker"
+)
+
+const testHost = "10.0.0.1:9090"
+
+var errUnavailable = status.Error(codes.Unavailable, "connection refused")
+
+func TestIsHostFailure(t *testing.T) {
+	tt := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "no error",
+			err:      nil,
+			expected: false,
+		},
+		{
+			name:     "unavailable",
+			err:      errUnavailable,
+			expected: true,
+		},
+		{
+			name:     "deadline exceeded status",
+			err:      status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+			expected: true,
+		},
+		{
+			name:     "wrapped context deadline",
+			err:      fmt.Errorf("getting microvm: %w", context.DeadlineExceeded),
+			expected: true,
+		},
+		{
+			name:     "not found",
+			err:      status.Error(codes.NotFound, "microvm not found"),
+			expected: false,
+		},
+		{
+			name:     "other error",
+			err:      errors.New("invalid spec"),
+			expected: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(circuitbreaker.IsHostFailure(tc.err)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	g := NewWithT(t)
+
+	breakers := circuitbreaker.New(circuitbreaker.Config{FailureThreshold: 2, Cooldown: time.Minute})
+
+	breakers.Record(testHost, errUnavailable)
+	g.Expect(breakers.State(testHost)).To(Equal(circuitbreaker.StateClosed))
+	g.Expect(breakers.Allow(testHost)).To(Succeed())
+
+	breakers.Record(testHost, errUnavailable)
+	g.Expect(breakers.State(testHost)).To(Equal(circuitbreaker.StateOpen))
+
+	err := breakers.Allow(testHost)
+	g.Expect(err).To(HaveOccurred())
+
+	openErr := &circuitbreaker.OpenError{}
+	g.Expect(errors.As(err, &openErr)).To(BeTrue())
+	g.Expect(openErr.Host).To(Equal(testHost))
+	g.Expect(openErr.RetryAfter).To(BeNumerically(">", 0))
+	g.Expect(openErr.RetryAfter).To(BeNumerically("<=", time.Minute))
+
+	g.Expect(breakers.Allow("10.0.0.2:9090")).To(Succeed(), "expect other hosts to be called")
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	g := NewWithT(t)
+
+	breakers := circuitbreaker.New(circuitbreaker.Config{FailureThreshold: 2, Cooldown: time.Minute})
+
+	breakers.Record(testHost, errUnavailable)
+	breakers.Record(testHost, status.Error(codes.NotFound, "microvm not found"))
+	breakers.Record(testHost, errUnavailable)
+
+	g.Expect(breakers.State(testHost)).To(Equal(circuitbreaker.StateClosed), "expect the failures not to be consecutive")
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	g := NewWithT(t)
+
+	breakers := circuitbreaker.New(circuitbreaker.Config{FailureThreshold: 1, Cooldown: 10 * time.Millisecond})
+
+	breakers.Record(testHost, errUnavailable)
+	g.Expect(breakers.Allow(testHost)).NotTo(Succeed())
+
+	time.Sleep(20 * time.Millisecond)
+
+	g.Expect(breakers.Allow(testHost)).To(Succeed(), "expect a trial call after the cooldown")
+	g.Expect(breakers.State(testHost)).To(Equal(circuitbreaker.StateHalfOpen))
+	g.Expect(breakers.Allow(testHost)).NotTo(Succeed(), "expect a single trial call")
+
+	breakers.Record(testHost, errUnavailable)
+	g.Expect(breakers.State(testHost)).To(Equal(circuitbreaker.StateOpen), "expect a failed trial call to reopen")
+
+	time.Sleep(20 * time.Millisecond)
+
+	g.Expect(breakers.Allow(testHost)).To(Succeed())
+	breakers.Record(testHost, nil)
+	g.Expect(breakers.State(testHost)).To(Equal(circuitbreaker.StateClosed), "expect a successful trial call to close")
+	g.Expect(breakers.Allow(testHost)).To(Succeed())
+}
+
+func TestBreakerWrapRecordsCalls(t *testing.T) {
+	g := NewWithT(t)
+
+	breakers := circuitbreaker.New(circuitbreaker.Config{FailureThreshold: 2, Cooldown: time.Minute})
+
+	fakeClient := &fakes.FakeClient{}
+	fakeClient.GetMicroVMReturns(nil, errUnavailable)
+
+	client := breakers.Wrap(testHost, fakeClient)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetMicroVM(context.TODO(), &flintlockv1.GetMicroVMRequest{})
+		g.Expect(err).To(MatchError(errUnavailable))
+	}
+
+	g.Expect(breakers.State(testHost)).To(Equal(circuitbreaker.StateOpen))
+	g.Expect(fakeClient.GetMicroVMCallCount()).To(Equal(2))
+}
@@ -0,0 +1,21 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package circuitbreaker
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpenError is returned when a host can't be called as its circuit breaker is open.
+type OpenError struct {
+	// Host is the endpoint of the host.
+	Host string
+	// RetryAfter is how long until the host can be tried again.
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("flintlock host %s is unavailable, retrying in %s", e.Host, e.RetryAfter)
+}
@@ -0,0 +1,245 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package clientpool caches the gRPC connections to the flintlock hosts so that they can be
+// reused across reconciles instead of making a new connection, and TLS handshake, every time.
+package clientpool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
+)
+
+const (
+	// DefaultIdleTimeout is how long a connection can be unused before it's closed if an idle
+	// timeout isn't specified.
+	DefaultIdleTimeout = 5 * time.Minute
+
+	minCleanupInterval = time.Second
+)
+
+// Credentials are the credentials used to connect to a flintlock host.
+type Credentials struct {
+	// BasicAuthToken is the basic auth token for the host.
+	BasicAuthToken string
+	// TLS is the client certificate and CA used to connect to the host.
+	TLS *flclient.TLSConfig
+	// Proxy is the proxy used to connect to the host.
+	Proxy *flclient.Proxy
+}
+
+// Fingerprint returns a hash of the credentials. Connections are only reused for the same
+// credentials, so a connection made with old credentials isn't used after they change.
+func (c Credentials) Fingerprint() string {
+	hash := sha256.New()
+
+	write := func(data []byte) {
+		// The length is written first so that different credentials can't hash the same.
+		_ = binary.Write(hash, binary.BigEndian, uint32(len(data)))
+		hash.Write(data)
+	}
+
+	write([]byte(c.BasicAuthToken))
+
+	if c.TLS != nil {
+		write(c.TLS.Cert)
+		write(c.TLS.Key)
+		write(c.TLS.CACert)
+	} else {
+		write(nil)
+		write(nil)
+		write(nil)
+	}
+
+	if c.Proxy != nil {
+		write([]byte(c.Proxy.Endpoint))
+	} else {
+		write(nil)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Options returns the client options for the credentials.
+func (c Credentials) Options() []flclient.Options {
+	return []flclient.Options{
+		flclient.WithProxy(c.Proxy),
+		flclient.WithBasicAuth(c.BasicAuthToken),
+		flclient.WithTLS(c.TLS),
+	}
+}
+
+// Config is the configuration of the connection pool.
+type Config struct {
+	// IdleTimeout is how long a connection can be unused before it's closed.
+	IdleTimeout time.Duration
+}
+
+// Pool is a cache of connections to the flintlock hosts keyed by the host endpoint and the
+// fingerprint of the credentials. It's safe for concurrent use so it can be shared by the
+// controllers.
+type Pool struct {
+	factory     flclient.FactoryFunc
+	idleTimeout time.Duration
+
+	mu    sync.Mutex
+	conns map[connKey]*conn
+}
+
+type connKey struct {
+	address     string
+	fingerprint string
+}
+
+type conn struct {
+	client   flclient.Client
+	refs     int
+	lastUsed time.Time
+}
+
+// New creates a connection pool that uses the factory to connect to the hosts. The factory
+// shouldn't block as it's called with the pool locked, which is the case for gRPC clients as
+// they connect in the background.
+func New(factory flclient.FactoryFunc, cfg Config) *Pool {
+	idleTimeout := cfg.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+
+	return &Pool{
+		factory:     factory,
+		idleTimeout: idleTimeout,
+		conns:       map[connKey]*conn{},
+	}
+}
+
+// Get returns a client for the host at the address that uses the credentials. A pooled
+// connection is used if there is one, otherwise a new connection is made. Close must be called
+// on the client when it's no longer needed, which returns the connection to the pool.
+func (p *Pool) Get(address string, creds Credentials) (flclient.Client, error) {
+	key := connKey{address: address, fingerprint: creds.Fingerprint()}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.conns[key]
+	if !ok {
+		client, err := p.factory(address, creds.Options()...)
+		if err != nil {
+			return nil, err
+		}
+
+		c = &conn{client: client}
+		p.conns[key] = c
+
+		metrics.IncFlintlockConnectionsCreated(address)
+		p.updateMetrics(address)
+	}
+
+	c.refs++
+	c.lastUsed = time.Now()
+
+	return &pooledClient{Client: c.client, pool: p, key: key}, nil
+}
+
+// CloseIdle closes the connections that haven't been used for longer than the idle timeout.
+func (p *Pool) CloseIdle() {
+	p.closeWhere(func(_ connKey, c *conn) bool {
+		return time.Since(c.lastUsed) > p.idleTimeout
+	})
+}
+
+// Len returns the number of pooled connections.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.conns)
+}
+
+// Start closes idle connections until the context is done, at which point all the connections
+// are closed. It implements the controller-runtime Runnable interface so that the pool can be
+// added to the manager.
+func (p *Pool) Start(ctx context.Context) error {
+	interval := p.idleTimeout / 2
+	if interval < minCleanupInterval {
+		interval = minCleanupInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.closeWhere(func(connKey, *conn) bool { return true })
+
+			return nil
+		case <-ticker.C:
+			p.CloseIdle()
+		}
+	}
+}
+
+// closeWhere closes the connections that aren't in use and match the filter.
+func (p *Pool) closeWhere(filter func(key connKey, c *conn) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, c := range p.conns {
+		if c.refs > 0 || !filter(key, c) {
+			continue
+		}
+
+		c.client.Close()
+		delete(p.conns, key)
+
+		p.updateMetrics(key.address)
+	}
+}
+
+func (p *Pool) release(key connKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if c, ok := p.conns[key]; ok {
+		c.refs--
+		c.lastUsed = time.Now()
+	}
+}
+
+// updateMetrics records the number of connections to the host. The lock must be held.
+func (p *Pool) updateMetrics(address string) {
+	count := 0
+
+	for key := range p.conns {
+		if key.address == address {
+			count++
+		}
+	}
+
+	metrics.SetFlintlockConnections(address, count)
+}
+
+// pooledClient is a client that returns its connection to the pool when it's closed.
+type pooledClient struct {
+	flclient.Client
+
+	pool *Pool
+	key  connKey
+	once sync.Once
+}
+
+func (c *pooledClient) Close() {
+	c.once.Do(func() {
+		c.pool.release(c.key)
+	})
+}
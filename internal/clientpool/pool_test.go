@@ -0,0 +1,152 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package clientpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
+)
+
+type fakeFactory struct {
+	clients []*fakes.FakeClient
+	err     error
+}
+
+func (f *fakeFactory) newClient(_ string, _ ...flclient.Options) (flclient.Client, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	client := &fakes.FakeClient{}
+	f.clients = append(f.clients, client)
+
+	return client, nil
+}
+
+func TestPoolReusesConnections(t *testing.T) {
+	g := NewWithT(t)
+
+	host := "reuse:9090"
+	factory := &fakeFactory{}
+	pool := clientpool.New(factory.newClient, clientpool.Config{})
+
+	creds := clientpool.Credentials{BasicAuthToken: "token"}
+
+	first, err := pool.Get(host, creds)
+	g.Expect(err).NotTo(HaveOccurred())
+	first.Close()
+
+	second, err := pool.Get(host, creds)
+	g.Expect(err).NotTo(HaveOccurred())
+	second.Close()
+	second.Close()
+
+	g.Expect(factory.clients).To(HaveLen(1), "expect the connection to be reused")
+	g.Expect(factory.clients[0].CloseCallCount()).To(Equal(0), "expect the pooled connection to stay open")
+	g.Expect(pool.Len()).To(Equal(1))
+	g.Expect(testutil.ToFloat64(metrics.FlintlockConnections.WithLabelValues(host))).To(BeNumerically("==", 1))
+	g.Expect(testutil.ToFloat64(metrics.FlintlockConnectionsCreated.WithLabelValues(host))).To(BeNumerically("==", 1))
+}
+
+func TestPoolCredentialsChange(t *testing.T) {
+	g := NewWithT(t)
+
+	host := "rotated:9090"
+	factory := &fakeFactory{}
+	pool := clientpool.New(factory.newClient, clientpool.Config{})
+
+	oldCreds := clientpool.Credentials{TLS: &flclient.TLSConfig{Cert: []byte("old"), Key: []byte("key")}}
+	newCreds := clientpool.Credentials{TLS: &flclient.TLSConfig{Cert: []byte("new"), Key: []byte("key")}}
+
+	g.Expect(oldCreds.Fingerprint()).NotTo(Equal(newCreds.Fingerprint()))
+	g.Expect(clientpool.Credentials{BasicAuthToken: "ab"}.Fingerprint()).
+		NotTo(Equal(clientpool.Credentials{Proxy: &flclient.Proxy{Endpoint: "ab"}}.Fingerprint()))
+
+	client, err := pool.Get(host, oldCreds)
+	g.Expect(err).NotTo(HaveOccurred())
+	client.Close()
+
+	client, err = pool.Get(host, newCreds)
+	g.Expect(err).NotTo(HaveOccurred())
+	client.Close()
+
+	g.Expect(factory.clients).To(HaveLen(2), "expect a new connection for the new credentials")
+	g.Expect(pool.Len()).To(Equal(2))
+}
+
+func TestPoolCloseIdle(t *testing.T) {
+	g := NewWithT(t)
+
+	factory := &fakeFactory{}
+	pool := clientpool.New(factory.newClient, clientpool.Config{IdleTimeout: time.Millisecond})
+
+	idle, err := pool.Get("idle:9090", clientpool.Credentials{})
+	g.Expect(err).NotTo(HaveOccurred())
+	idle.Close()
+
+	inUse, err := pool.Get("inuse:9090", clientpool.Credentials{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	time.Sleep(5 * time.Millisecond)
+	pool.CloseIdle()
+
+	g.Expect(pool.Len()).To(Equal(1), "expect connections in use not to be closed")
+	g.Expect(factory.clients[0].CloseCallCount()).To(Equal(1))
+	g.Expect(factory.clients[1].CloseCallCount()).To(Equal(0))
+	g.Expect(testutil.CollectAndCount(metrics.FlintlockConnections, "capmvm_flintlock_connections")).
+		To(BeNumerically(">=", 1))
+
+	inUse.Close()
+	time.Sleep(5 * time.Millisecond)
+	pool.CloseIdle()
+
+	g.Expect(pool.Len()).To(Equal(0))
+	g.Expect(factory.clients[1].CloseCallCount()).To(Equal(1))
+}
+
+func TestPoolStartClosesConnections(t *testing.T) {
+	g := NewWithT(t)
+
+	factory := &fakeFactory{}
+	pool := clientpool.New(factory.newClient, clientpool.Config{})
+
+	client, err := pool.Get("shutdown:9090", clientpool.Credentials{})
+	g.Expect(err).NotTo(HaveOccurred())
+	client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- pool.Start(ctx)
+	}()
+
+	cancel()
+
+	g.Eventually(done).Should(Receive(BeNil()))
+	g.Expect(pool.Len()).To(Equal(0))
+	g.Expect(factory.clients[0].CloseCallCount()).To(Equal(1))
+}
+
+func TestPoolFactoryError(t *testing.T) {
+	g := NewWithT(t)
+
+	factory := &fakeFactory{err: errors.New("dial failed")}
+	pool := clientpool.New(factory.newClient, clientpool.Config{})
+
+	_, err := pool.Get("broken:9090", clientpool.Credentials{})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(pool.Len()).To(Equal(0))
+}
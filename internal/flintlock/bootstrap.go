@@ -0,0 +1,56 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"github.com/liquidmetal-dev/flintlock/client/cloudinit"
+)
+
+const (
+	// TalosPlatformKernelArg is the kernel argument used to tell Talos which platform it's running on.
+	TalosPlatformKernelArg = "talos.platform"
+	// TalosPlatformNoCloud is the Talos platform that reads its machine config from the NoCloud user-data.
+	TalosPlatformNoCloud = "nocloud"
+)
+
+// WithKernelArgs returns a SpecMutator that adds the supplied arguments to the kernel
+// cmdline of the microvm. Arguments that have been set explicitly in the spec are
+// left untouched.
+func WithKernelArgs(args map[string]string) SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		if len(args) == 0 {
+			return nil
+		}
+
+		if spec.Kernel == nil {
+			spec.Kernel = &flintlocktypes.Kernel{}
+		}
+
+		if spec.Kernel.Cmdline == nil {
+			spec.Kernel.Cmdline = map[string]string{}
+		}
+
+		for k, v := range args {
+			if _, ok := spec.Kernel.Cmdline[k]; ok {
+				continue
+			}
+
+			spec.Kernel.Cmdline[k] = v
+		}
+
+		return nil
+	}
+}
+
+// WithoutVendorData returns a SpecMutator that removes the cloud-init vendor data from
+// the microvm metadata. This is used when the guest doesn't run cloud-init and would
+// otherwise misinterpret it.
+func WithoutVendorData() SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		delete(spec.Metadata, cloudinit.VendorDataKey)
+
+		return nil
+	}
+}
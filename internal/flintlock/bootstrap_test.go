@@ -0,0 +1,58 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+func TestWithKernelArgs(t *testing.T) {
+	RegisterTestingT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{
+		Kernel: &flintlocktypes.Kernel{
+			Cmdline: map[string]string{"console": "ttyS0", "talos.platform": "metal"},
+		},
+	}
+
+	Expect(flintlock.WithKernelArgs(map[string]string{
+		"talos.platform": "nocloud",
+		"panic":          "1",
+	})(spec)).To(Succeed())
+
+	Expect(spec.Kernel.Cmdline).To(Equal(map[string]string{
+		"console":        "ttyS0",
+		"talos.platform": "metal",
+		"panic":          "1",
+	}))
+}
+
+func TestWithKernelArgsNoCmdline(t *testing.T) {
+	RegisterTestingT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{}
+
+	Expect(flintlock.WithKernelArgs(map[string]string{"talos.platform": "nocloud"})(spec)).To(Succeed())
+	Expect(spec.Kernel.Cmdline).To(HaveKeyWithValue("talos.platform", "nocloud"))
+}
+
+func TestWithoutVendorData(t *testing.T) {
+	RegisterTestingT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{
+		Metadata: map[string]string{
+			"user-data":   "dXNlcg==",
+			"vendor-data": "dmVuZG9y",
+		},
+	}
+
+	Expect(flintlock.WithoutVendorData()(spec)).To(Succeed())
+	Expect(spec.Metadata).To(Equal(map[string]string{"user-data": "dXNlcg=="}))
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	// DefaultCreateTimeout is the default timeout of calls to create a microvm.
+	DefaultCreateTimeout = 30 * time.Second
+	// DefaultDeleteTimeout is the default timeout of calls to delete a microvm.
+	DefaultDeleteTimeout = 30 * time.Second
+	// DefaultGetTimeout is the default timeout of calls to get a microvm.
+	DefaultGetTimeout = 10 * time.Second
+	// DefaultListTimeout is the default timeout of calls to list microvms.
+	DefaultListTimeout = 30 * time.Second
+	// DefaultRetries is the default number of times calls to get or list microvms are retried.
+	DefaultRetries = 2
+	// DefaultRetryBackoff is the default time to wait before the first retry.
+	DefaultRetryBackoff = time.Second
+)
+
+// CallPolicy is the timeouts and retries of the calls to a flintlock host. A timeout of zero
+// means the call doesn't have a timeout other than the deadline of the reconcile, if any.
+type CallPolicy struct {
+	// CreateTimeout is how long a call to create a microvm can take.
+	CreateTimeout time.Duration
+	// DeleteTimeout is how long a call to delete a microvm can take.
+	DeleteTimeout time.Duration
+	// GetTimeout is how long a call to get a microvm can take, including its retries.
+	GetTimeout time.Duration
+	// ListTimeout is how long a call to list microvms can take, including its retries.
+	ListTimeout time.Duration
+	// Retries is how many times a call to get or list microvms is retried if the host is
+	// unavailable or the call times out. The retries must fit in the timeout of the call.
+	Retries int
+	// RetryBackoff is how long to wait before the first retry. It doubles for each retry after that.
+	RetryBackoff time.Duration
+}
+
+// NewPolicyClient wraps the supplied client so that calls use the timeouts of the policy and
+// calls to get or list microvms, which don't change anything, are retried.
+func NewPolicyClient(client flclient.Client, policy CallPolicy) flclient.Client {
+	return &policyClient{
+		Client: client,
+		policy: policy,
+	}
+}
+
+// IsTimeout returns true if the error is because a call to a host timed out.
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
+
+// IsUnavailable returns true if the error is because a host couldn't be reached.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return status.Code(err) == codes.Unavailable
+}
+
+type policyClient struct {
+	flclient.Client
+
+	policy CallPolicy
+}
+
+func (c *policyClient) CreateMicroVM(
+	ctx context.Context,
+	in *flintlockv1.CreateMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.CreateMicroVMResponse, error) {
+	ctx, cancel := withTimeout(ctx, c.policy.CreateTimeout)
+	defer cancel()
+
+	return c.Client.CreateMicroVM(ctx, in, opts...)
+}
+
+func (c *policyClient) DeleteMicroVM(
+	ctx context.Context,
+	in *flintlockv1.DeleteMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	ctx, cancel := withTimeout(ctx, c.policy.DeleteTimeout)
+	defer cancel()
+
+	return c.Client.DeleteMicroVM(ctx, in, opts...)
+}
+
+func (c *policyClient) GetMicroVM(
+	ctx context.Context,
+	in *flintlockv1.GetMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.GetMicroVMResponse, error) {
+	var resp *flintlockv1.GetMicroVMResponse
+
+	err := c.retry(ctx, c.policy.GetTimeout, func(ctx context.Context) error {
+		var err error
+
+		resp, err = c.Client.GetMicroVM(ctx, in, opts...)
+
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *policyClient) ListMicroVMs(
+	ctx context.Context,
+	in *flintlockv1.ListMicroVMsRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.ListMicroVMsResponse, error) {
+	var resp *flintlockv1.ListMicroVMsResponse
+
+	err := c.retry(ctx, c.policy.ListTimeout, func(ctx context.Context) error {
+		var err error
+
+		resp, err = c.Client.ListMicroVMs(ctx, in, opts...)
+
+		return err
+	})
+
+	return resp, err
+}
+
+// retry makes the call until it succeeds, fails with an error that isn't worth retrying or the
+// retries run out. The timeout is for the call as a whole, including the retries and the waits
+// between them, so a host that is down doesn't hold up a reconcile for longer than the timeout.
+func (c *policyClient) retry(ctx context.Context, timeout time.Duration, call func(ctx context.Context) error) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := c.policy.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := call(ctx)
+
+		if err == nil || attempt >= c.policy.Retries || !(IsTimeout(err) || IsUnavailable(err)) {
+			return err
+		}
+
+		// The reconcile has been cancelled or the call has run out of time.
+		if ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
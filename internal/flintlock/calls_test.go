@@ -0,0 +1,216 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+var errUnavailable = status.Error(codes.Unavailable, "connection refused")
+
+func TestIsTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(flintlock.IsTimeout(nil)).To(BeFalse())
+	Expect(flintlock.IsTimeout(status.Error(codes.DeadlineExceeded, "deadline exceeded"))).To(BeTrue())
+	Expect(flintlock.IsTimeout(fmt.Errorf("getting microvm: %w", context.DeadlineExceeded))).To(BeTrue())
+	Expect(flintlock.IsTimeout(errUnavailable)).To(BeFalse())
+
+	Expect(flintlock.IsUnavailable(errUnavailable)).To(BeTrue())
+	Expect(flintlock.IsUnavailable(fmt.Errorf("creating microvm: %w", errUnavailable))).To(BeTrue())
+	Expect(flintlock.IsUnavailable(errors.New("invalid spec"))).To(BeFalse())
+}
+
+func TestPolicyClientTimeouts(t *testing.T) {
+	RegisterTestingT(t)
+
+	fakeClient := &fakes.FakeClient{}
+	client := flintlock.NewPolicyClient(fakeClient, flintlock.CallPolicy{
+		CreateTimeout: time.Minute,
+		GetTimeout:    time.Second,
+	})
+
+	_, err := client.CreateMicroVM(context.TODO(), &flintlockv1.CreateMicroVMRequest{})
+	Expect(err).NotTo(HaveOccurred())
+
+	ctx, _, _ := fakeClient.CreateMicroVMArgsForCall(0)
+	deadline, ok := ctx.Deadline()
+	Expect(ok).To(BeTrue(), "expect create to have a deadline")
+	Expect(time.Until(deadline)).To(BeNumerically("~", time.Minute, time.Second))
+
+	_, err = client.GetMicroVM(context.TODO(), &flintlockv1.GetMicroVMRequest{})
+	Expect(err).NotTo(HaveOccurred())
+
+	ctx, _, _ = fakeClient.GetMicroVMArgsForCall(0)
+	deadline, ok = ctx.Deadline()
+	Expect(ok).To(BeTrue(), "expect get to have a deadline")
+	Expect(time.Until(deadline)).To(BeNumerically("<=", time.Second))
+
+	_, err = client.DeleteMicroVM(context.TODO(), &flintlockv1.DeleteMicroVMRequest{})
+	Expect(err).NotTo(HaveOccurred())
+
+	ctx, _, _ = fakeClient.DeleteMicroVMArgsForCall(0)
+	_, ok = ctx.Deadline()
+	Expect(ok).To(BeFalse(), "expect delete not to have a deadline when the timeout isn't set")
+}
+
+func TestPolicyClientRetries(t *testing.T) {
+	tt := []struct {
+		name          string
+		retries       int
+		errs          []error
+		expectedCalls int
+		expectedErr   error
+	}{
+		{
+			name:          "success",
+			retries:       2,
+			errs:          []error{nil},
+			expectedCalls: 1,
+		},
+		{
+			name:          "unavailable then success",
+			retries:       2,
+			errs:          []error{errUnavailable, nil},
+			expectedCalls: 2,
+		},
+		{
+			name:          "timeouts until retries run out",
+			retries:       2,
+			errs:          []error{status.Error(codes.DeadlineExceeded, "deadline exceeded")},
+			expectedCalls: 3,
+			expectedErr:   status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+		},
+		{
+			name:          "not retried when not found",
+			retries:       2,
+			errs:          []error{status.Error(codes.NotFound, "microvm not found")},
+			expectedCalls: 1,
+			expectedErr:   status.Error(codes.NotFound, "microvm not found"),
+		},
+		{
+			name:          "no retries",
+			retries:       0,
+			errs:          []error{errUnavailable},
+			expectedCalls: 1,
+			expectedErr:   errUnavailable,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			fakeClient := &fakes.FakeClient{}
+			fakeClient.GetMicroVMStub = func(
+				_ context.Context,
+				_ *flintlockv1.GetMicroVMRequest,
+				_ ...grpc.CallOption,
+			) (*flintlockv1.GetMicroVMResponse, error) {
+				call := fakeClient.GetMicroVMCallCount() - 1
+				if call >= len(tc.errs) {
+					call = len(tc.errs) - 1
+				}
+
+				if tc.errs[call] != nil {
+					return nil, tc.errs[call]
+				}
+
+				return &flintlockv1.GetMicroVMResponse{}, nil
+			}
+
+			client := flintlock.NewPolicyClient(fakeClient, flintlock.CallPolicy{
+				Retries:      tc.retries,
+				RetryBackoff: time.Millisecond,
+			})
+
+			_, err := client.GetMicroVM(context.TODO(), &flintlockv1.GetMicroVMRequest{})
+			if tc.expectedErr != nil {
+				g.Expect(err).To(MatchError(tc.expectedErr))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+
+			g.Expect(fakeClient.GetMicroVMCallCount()).To(Equal(tc.expectedCalls))
+		})
+	}
+}
+
+func TestPolicyClientRetriesWithinTimeout(t *testing.T) {
+	RegisterTestingT(t)
+
+	fakeClient := &fakes.FakeClient{}
+	fakeClient.GetMicroVMStub = func(
+		ctx context.Context,
+		_ *flintlockv1.GetMicroVMRequest,
+		_ ...grpc.CallOption,
+	) (*flintlockv1.GetMicroVMResponse, error) {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	client := flintlock.NewPolicyClient(fakeClient, flintlock.CallPolicy{
+		GetTimeout:   50 * time.Millisecond,
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+	})
+
+	start := time.Now()
+
+	_, err := client.GetMicroVM(context.TODO(), &flintlockv1.GetMicroVMRequest{})
+	Expect(err).To(MatchError(context.DeadlineExceeded))
+	Expect(time.Since(start)).To(BeNumerically("<", 100*time.Millisecond), "expect the retries to fit in the timeout")
+	Expect(fakeClient.GetMicroVMCallCount()).To(Equal(1))
+}
+
+func TestPolicyClientCreateNotRetried(t *testing.T) {
+	RegisterTestingT(t)
+
+	fakeClient := &fakes.FakeClient{}
+	fakeClient.CreateMicroVMReturns(nil, errUnavailable)
+
+	client := flintlock.NewPolicyClient(fakeClient, flintlock.CallPolicy{Retries: 2, RetryBackoff: time.Millisecond})
+
+	_, err := client.CreateMicroVM(context.TODO(), &flintlockv1.CreateMicroVMRequest{})
+	Expect(err).To(MatchError(errUnavailable))
+	Expect(fakeClient.CreateMicroVMCallCount()).To(Equal(1))
+}
+
+func TestPolicyClientRetriesStopWhenCancelled(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fakeClient := &fakes.FakeClient{}
+	fakeClient.ListMicroVMsStub = func(
+		_ context.Context,
+		_ *flintlockv1.ListMicroVMsRequest,
+		_ ...grpc.CallOption,
+	) (*flintlockv1.ListMicroVMsResponse, error) {
+		cancel()
+
+		return nil, errUnavailable
+	}
+
+	client := flintlock.NewPolicyClient(fakeClient, flintlock.CallPolicy{Retries: 5, RetryBackoff: time.Minute})
+
+	_, err := client.ListMicroVMs(ctx, &flintlockv1.ListMicroVMsRequest{})
+	Expect(err).To(MatchError(errUnavailable))
+	Expect(fakeClient.ListMicroVMsCallCount()).To(Equal(1))
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package flintlock contains helpers used when calling the microvm service on flintlock hosts.
+package flintlock
+
+import (
+	"context"
+	"fmt"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"google.golang.org/grpc"
+)
+
+// SpecMutator is used to change the microvm spec that has been generated by the
+// microvm service before the create request is sent to the flintlock host.
+type SpecMutator func(spec *flintlocktypes.MicroVMSpec) error
+
+// NewMutatingClient wraps the supplied client so that the mutators are applied, in order,
+// to the microvm spec of every create request.
+func NewMutatingClient(client flclient.Client, mutators ...SpecMutator) flclient.Client {
+	return &mutatingClient{
+		Client:   client,
+		mutators: mutators,
+	}
+}
+
+type mutatingClient struct {
+	flclient.Client
+
+	mutators []SpecMutator
+}
+
+func (c *mutatingClient) CreateMicroVM(
+	ctx context.Context,
+	in *flintlockv1.CreateMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.CreateMicroVMResponse, error) {
+	if in.Microvm != nil {
+		for _, mutate := range c.mutators {
+			if err := mutate(in.Microvm); err != nil {
+				return nil, fmt.Errorf("mutating microvm spec: %w", err)
+			}
+		}
+	}
+
+	return c.Client.CreateMicroVM(ctx, in, opts...)
+}
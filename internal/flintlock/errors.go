@@ -0,0 +1,19 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errInvalidCACert = errors.New("could not add the CA certificate to the pool")
+
+type invalidGatewayError struct {
+	gateway string
+}
+
+func (e *invalidGatewayError) Error() string {
+	return fmt.Sprintf("invalid gateway address %q", e.gateway)
+}
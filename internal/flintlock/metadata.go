@@ -0,0 +1,58 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"github.com/liquidmetal-dev/flintlock/client/cloudinit"
+	"gopkg.in/yaml.v2"
+)
+
+// WithInstanceMetadata returns a SpecMutator that adds the supplied values to the cloud-init
+// instance metadata (i.e. meta-data) of the microvm. Keys that already exist in the instance
+// metadata are left untouched.
+func WithInstanceMetadata(values map[string]interface{}) SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		if len(values) == 0 {
+			return nil
+		}
+
+		if spec.Metadata == nil {
+			spec.Metadata = map[string]string{}
+		}
+
+		instanceData := map[string]interface{}{}
+
+		if existing := spec.Metadata[cloudinit.InstanceDataKey]; existing != "" {
+			data, err := base64.StdEncoding.DecodeString(existing)
+			if err != nil {
+				return fmt.Errorf("decoding instance metadata: %w", err)
+			}
+
+			if err := yaml.Unmarshal(data, &instanceData); err != nil {
+				return fmt.Errorf("unmarshalling instance metadata: %w", err)
+			}
+		}
+
+		for k, v := range values {
+			if _, ok := instanceData[k]; ok {
+				continue
+			}
+
+			instanceData[k] = v
+		}
+
+		data, err := yaml.Marshal(instanceData)
+		if err != nil {
+			return fmt.Errorf("marshalling instance metadata: %w", err)
+		}
+
+		spec.Metadata[cloudinit.InstanceDataKey] = base64.StdEncoding.EncodeToString(data)
+
+		return nil
+	}
+}
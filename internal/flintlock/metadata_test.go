@@ -0,0 +1,87 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"gopkg.in/yaml.v2"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+func TestWithInstanceMetadata(t *testing.T) {
+	RegisterTestingT(t)
+
+	existing, err := yaml.Marshal(map[string]string{
+		"local_hostname": "machine-1",
+		"vm_host":        "127.0.0.1:9090",
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	tt := []struct {
+		name     string
+		metadata map[string]string
+		values   map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "values are added to existing instance metadata",
+			metadata: map[string]string{"meta-data": base64.StdEncoding.EncodeToString(existing)},
+			values: map[string]interface{}{
+				"cluster_name": "cluster-1",
+				"labels":       map[string]string{"app": "test"},
+			},
+			expected: map[string]interface{}{
+				"local_hostname": "machine-1",
+				"vm_host":        "127.0.0.1:9090",
+				"cluster_name":   "cluster-1",
+				"labels":         map[interface{}]interface{}{"app": "test"},
+			},
+		},
+		{
+			name:     "existing keys are not overridden",
+			metadata: map[string]string{"meta-data": base64.StdEncoding.EncodeToString(existing)},
+			values:   map[string]interface{}{"vm_host": "somewhere-else"},
+			expected: map[string]interface{}{
+				"local_hostname": "machine-1",
+				"vm_host":        "127.0.0.1:9090",
+			},
+		},
+		{
+			name:     "instance metadata is created if missing",
+			metadata: nil,
+			values:   map[string]interface{}{"cluster_name": "cluster-1"},
+			expected: map[string]interface{}{"cluster_name": "cluster-1"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			spec := &flintlocktypes.MicroVMSpec{Metadata: tc.metadata}
+			Expect(flintlock.WithInstanceMetadata(tc.values)(spec)).To(Succeed())
+
+			Expect(spec.Metadata).To(HaveKey("meta-data"))
+			data, err := base64.StdEncoding.DecodeString(spec.Metadata["meta-data"])
+			Expect(err).NotTo(HaveOccurred())
+
+			instanceData := map[string]interface{}{}
+			Expect(yaml.Unmarshal(data, &instanceData)).To(Succeed())
+			Expect(instanceData).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestWithInstanceMetadataInvalidExisting(t *testing.T) {
+	RegisterTestingT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{Metadata: map[string]string{"meta-data": "not base64!"}}
+	Expect(flintlock.WithInstanceMetadata(map[string]interface{}{"a": "b"})(spec)).NotTo(Succeed())
+}
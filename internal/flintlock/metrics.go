@@ -0,0 +1,116 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"context"
+	"time"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
+)
+
+// NewMetricsClient wraps the supplied client for the host of the cluster so that the duration and
+// status code of every call is recorded. The host is the ID of the host, not its endpoint.
+func NewMetricsClient(client flclient.Client, clusterNamespace, clusterName, host string) flclient.Client {
+	return &metricsClient{
+		Client:           client,
+		clusterNamespace: clusterNamespace,
+		clusterName:      clusterName,
+		host:             host,
+	}
+}
+
+// StatusCode returns the gRPC status code of the error from a call, which is OK if there isn't
+// an error. Calls that ran out of time have the DeadlineExceeded code whether the deadline was
+// reached by the client or the server.
+func StatusCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+
+	if IsTimeout(err) {
+		return codes.DeadlineExceeded
+	}
+
+	return status.Code(err)
+}
+
+type metricsClient struct {
+	flclient.Client
+
+	clusterNamespace string
+	clusterName      string
+	host             string
+}
+
+func (c *metricsClient) CreateMicroVM(
+	ctx context.Context,
+	in *flintlockv1.CreateMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.CreateMicroVMResponse, error) {
+	start := time.Now()
+	resp, err := c.Client.CreateMicroVM(ctx, in, opts...)
+	c.observe("CreateMicroVM", start, err)
+
+	return resp, err
+}
+
+func (c *metricsClient) DeleteMicroVM(
+	ctx context.Context,
+	in *flintlockv1.DeleteMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	start := time.Now()
+	resp, err := c.Client.DeleteMicroVM(ctx, in, opts...)
+	c.observe("DeleteMicroVM", start, err)
+
+	return resp, err
+}
+
+func (c *metricsClient) GetMicroVM(
+	ctx context.Context,
+	in *flintlockv1.GetMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.GetMicroVMResponse, error) {
+	start := time.Now()
+	resp, err := c.Client.GetMicroVM(ctx, in, opts...)
+	c.observe("GetMicroVM", start, err)
+
+	return resp, err
+}
+
+func (c *metricsClient) ListMicroVMs(
+	ctx context.Context,
+	in *flintlockv1.ListMicroVMsRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.ListMicroVMsResponse, error) {
+	start := time.Now()
+	resp, err := c.Client.ListMicroVMs(ctx, in, opts...)
+	c.observe("ListMicroVMs", start, err)
+
+	return resp, err
+}
+
+func (c *metricsClient) ListMicroVMsStream(
+	ctx context.Context,
+	in *flintlockv1.ListMicroVMsRequest,
+	opts ...grpc.CallOption,
+) (grpc.ServerStreamingClient[flintlockv1.ListMessage], error) {
+	start := time.Now()
+	stream, err := c.Client.ListMicroVMsStream(ctx, in, opts...)
+	c.observe("ListMicroVMsStream", start, err)
+
+	return stream, err
+}
+
+func (c *metricsClient) observe(method string, start time.Time, err error) {
+	metrics.ObserveFlintlockRequest(c.clusterNamespace, c.clusterName, c.host, method, StatusCode(err).String(), time.Since(start))
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
+)
+
+func TestStatusCode(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(flintlock.StatusCode(nil)).To(Equal(codes.OK))
+	Expect(flintlock.StatusCode(errUnavailable)).To(Equal(codes.Unavailable))
+	Expect(flintlock.StatusCode(fmt.Errorf("getting microvm: %w", context.DeadlineExceeded))).To(Equal(codes.DeadlineExceeded))
+	Expect(flintlock.StatusCode(errors.New("invalid spec"))).To(Equal(codes.Unknown))
+}
+
+func TestMetricsClient(t *testing.T) {
+	RegisterTestingT(t)
+
+	host := "metrics-test"
+
+	fakeClient := &fakes.FakeClient{}
+	fakeClient.GetMicroVMReturns(nil, status.Error(codes.NotFound, "microvm not found"))
+
+	client := flintlock.NewMetricsClient(fakeClient, "ns1", "cluster1", host)
+
+	_, err := client.CreateMicroVM(context.TODO(), &flintlockv1.CreateMicroVMRequest{})
+	Expect(err).NotTo(HaveOccurred())
+
+	_, err = client.GetMicroVM(context.TODO(), &flintlockv1.GetMicroVMRequest{})
+	Expect(err).To(HaveOccurred())
+
+	_, err = client.GetMicroVM(context.TODO(), &flintlockv1.GetMicroVMRequest{})
+	Expect(err).To(HaveOccurred())
+
+	Expect(fakeClient.CreateMicroVMCallCount()).To(Equal(1))
+	Expect(fakeClient.GetMicroVMCallCount()).To(Equal(2))
+
+	Expect(testutil.CollectAndCount(metrics.FlintlockRequestDuration)).To(Equal(2))
+
+	Expect(requestCount(host, "CreateMicroVM", "OK")).To(BeNumerically("==", 1))
+	Expect(requestCount(host, "GetMicroVM", "NotFound")).To(BeNumerically("==", 2))
+}
+
+// requestCount returns the number of calls recorded for the host, method and code.
+func requestCount(host, method, code string) uint64 {
+	registry := prometheus.NewRegistry()
+	Expect(registry.Register(metrics.FlintlockRequestDuration)).To(Succeed())
+
+	families, err := registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			if labels["cluster"] == "cluster1" && labels["host"] == host && labels["method"] == method && labels["code"] == code {
+				return metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	return 0
+}
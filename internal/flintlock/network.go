@@ -0,0 +1,73 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+)
+
+// WithStaticAddresses returns a SpecMutator that sets the static address of the network
+// interfaces. The addresses are keyed by the device id (i.e. guest device name) of the
+// interface.
+func WithStaticAddresses(addresses map[string]*flintlocktypes.StaticAddress) SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		for _, iface := range spec.Interfaces {
+			if address, ok := addresses[iface.DeviceId]; ok {
+				iface.Address = address
+			}
+		}
+
+		return nil
+	}
+}
+
+// InterfaceOverride is a change to a network interface of the microvm.
+type InterfaceOverride struct {
+	// DeviceID is the id of the interface to change. If empty the override applies to all
+	// the interfaces.
+	DeviceID string
+	// Type is the type of interface to use, if set.
+	Type *flintlocktypes.NetworkInterface_IfaceType
+	// BridgeName is the bridge to attach the interface to, if set.
+	BridgeName string
+}
+
+// WithInterfaceOverrides returns a SpecMutator that applies the supplied overrides to the
+// network interfaces of the microvm. Overrides that apply to all the interfaces are applied
+// first so that overrides for a specific interface take precedence.
+func WithInterfaceOverrides(overrides ...InterfaceOverride) SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		for _, iface := range spec.Interfaces {
+			for _, override := range overrides {
+				if override.DeviceID == "" {
+					applyInterfaceOverride(iface, override)
+				}
+			}
+
+			for _, override := range overrides {
+				if override.DeviceID == iface.DeviceId {
+					applyInterfaceOverride(iface, override)
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+func applyInterfaceOverride(iface *flintlocktypes.NetworkInterface, override InterfaceOverride) {
+	if override.Type != nil {
+		iface.Type = *override.Type
+	}
+
+	if override.BridgeName != "" {
+		bridgeName := override.BridgeName
+
+		if iface.Overrides == nil {
+			iface.Overrides = &flintlocktypes.NetworkOverrides{}
+		}
+
+		iface.Overrides.BridgeName = &bridgeName
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"k8s.io/utils/pointer"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+func TestWithStaticAddresses(t *testing.T) {
+	RegisterTestingT(t)
+
+	existing := &flintlocktypes.StaticAddress{Address: "192.168.0.2/24"}
+	spec := &flintlocktypes.MicroVMSpec{
+		Interfaces: []*flintlocktypes.NetworkInterface{
+			{DeviceId: "eth0", Address: existing},
+			{DeviceId: "eth1"},
+		},
+	}
+
+	claimed := &flintlocktypes.StaticAddress{
+		Address:     "10.0.0.5/24",
+		Gateway:     pointer.String("10.0.0.1"),
+		Nameservers: []string{"1.1.1.1"},
+	}
+
+	Expect(flintlock.WithStaticAddresses(map[string]*flintlocktypes.StaticAddress{
+		"eth1": claimed,
+	})(spec)).To(Succeed())
+
+	Expect(spec.Interfaces[0].Address).To(Equal(existing))
+	Expect(spec.Interfaces[1].Address).To(Equal(claimed))
+}
+
+func TestWithInterfaceOverrides(t *testing.T) {
+	RegisterTestingT(t)
+
+	tap := flintlocktypes.NetworkInterface_TAP
+	spec := &flintlocktypes.MicroVMSpec{
+		Interfaces: []*flintlocktypes.NetworkInterface{
+			{DeviceId: "eth0", Type: flintlocktypes.NetworkInterface_MACVTAP},
+			{DeviceId: "eth1", Type: flintlocktypes.NetworkInterface_MACVTAP},
+		},
+	}
+
+	Expect(flintlock.WithInterfaceOverrides(
+		flintlock.InterfaceOverride{DeviceID: "eth1", BridgeName: "br-eth1"},
+		flintlock.InterfaceOverride{Type: &tap, BridgeName: "br0"},
+	)(spec)).To(Succeed())
+
+	Expect(spec.Interfaces[0].Type).To(Equal(flintlocktypes.NetworkInterface_TAP))
+	Expect(spec.Interfaces[0].Overrides.BridgeName).To(Equal(pointer.String("br0")))
+	Expect(spec.Interfaces[1].Type).To(Equal(flintlocktypes.NetworkInterface_TAP))
+	Expect(spec.Interfaces[1].Overrides.BridgeName).To(Equal(pointer.String("br-eth1")), "expect device override to take precedence")
+}
@@ -0,0 +1,213 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"github.com/liquidmetal-dev/flintlock/client/cloudinit"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	networkConfigVersion = 2
+	dhcpIdentifierMAC    = "mac"
+)
+
+// InterfaceConfig is additional guest network configuration for one of the network
+// interfaces of the microvm.
+type InterfaceConfig struct {
+	// DeviceID is the id of the interface the configuration applies to.
+	DeviceID string
+	// VLANID is the VLAN the interface's traffic is tagged with. If set a VLAN interface
+	// is created in the guest and the address is assigned to it.
+	VLANID int32
+	// MTU is the MTU of the interface.
+	MTU int32
+	// Gateway is the default gateway used if the address of the interface doesn't have one.
+	Gateway string
+	// Nameservers are the DNS servers used if the address of the interface doesn't have any.
+	Nameservers []string
+	// Routes are additional routes for the interface.
+	Routes []Route
+}
+
+// Route is a route in the guest.
+type Route struct {
+	To     string `yaml:"to"`
+	Via    string `yaml:"via"`
+	Metric *int32 `yaml:"metric,omitempty"`
+}
+
+// networkConfig is a cloud-init network configuration (version 2) document.
+type networkConfig struct {
+	Version   int                 `yaml:"version"`
+	Ethernets map[string]ethernet `yaml:"ethernets"`
+	VLANs     map[string]vlan     `yaml:"vlans,omitempty"`
+}
+
+type ethernet struct {
+	Match      match  `yaml:"match"`
+	SetName    string `yaml:"set-name,omitempty"`
+	MTU        int32  `yaml:"mtu,omitempty"`
+	addressing `yaml:",inline"`
+}
+
+type vlan struct {
+	ID         int32  `yaml:"id"`
+	Link       string `yaml:"link"`
+	MTU        int32  `yaml:"mtu,omitempty"`
+	addressing `yaml:",inline"`
+}
+
+type match struct {
+	MACAddress string `yaml:"macaddress,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+}
+
+type addressing struct {
+	Addresses      []string     `yaml:"addresses,omitempty"`
+	Gateway4       string       `yaml:"gateway4,omitempty"`
+	Gateway6       string       `yaml:"gateway6,omitempty"`
+	DHCP4          *bool        `yaml:"dhcp4,omitempty"`
+	DHCP6          *bool        `yaml:"dhcp6,omitempty"`
+	DHCPIdentifier string       `yaml:"dhcp-identifier,omitempty"`
+	Nameservers    *nameservers `yaml:"nameservers,omitempty"`
+	Routes         []Route      `yaml:"routes,omitempty"`
+}
+
+type nameservers struct {
+	Addresses []string `yaml:"addresses,omitempty"`
+}
+
+// WithNetworkConfig returns a SpecMutator that generates the cloud-init network-config of
+// the microvm from its network interfaces and the supplied additional configuration. Flintlock
+// is told not to add its own network-config.
+func WithNetworkConfig(configs ...InterfaceConfig) SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		if len(spec.Interfaces) == 0 {
+			return nil
+		}
+
+		byDevice := map[string]InterfaceConfig{}
+		for _, cfg := range configs {
+			byDevice[cfg.DeviceID] = cfg
+		}
+
+		doc := networkConfig{
+			Version:   networkConfigVersion,
+			Ethernets: map[string]ethernet{},
+		}
+
+		for _, iface := range spec.Interfaces {
+			cfg := byDevice[iface.DeviceId]
+
+			eth := ethernet{
+				Match: match{Name: iface.DeviceId},
+				MTU:   cfg.MTU,
+			}
+
+			if iface.GuestMac != nil && *iface.GuestMac != "" {
+				eth.Match = match{MACAddress: *iface.GuestMac}
+				eth.SetName = iface.DeviceId
+			}
+
+			ifaceAddressing, err := newAddressing(iface.Address, cfg)
+			if err != nil {
+				return fmt.Errorf("generating network config for interface %s: %w", iface.DeviceId, err)
+			}
+
+			ifaceAddressing.Routes = cfg.Routes
+
+			if cfg.VLANID == 0 {
+				eth.addressing = ifaceAddressing
+				doc.Ethernets[iface.DeviceId] = eth
+
+				continue
+			}
+
+			dhcp := false
+			eth.DHCP4 = &dhcp
+			eth.DHCP6 = &dhcp
+			doc.Ethernets[iface.DeviceId] = eth
+
+			if doc.VLANs == nil {
+				doc.VLANs = map[string]vlan{}
+			}
+
+			doc.VLANs[fmt.Sprintf("%s.%d", iface.DeviceId, cfg.VLANID)] = vlan{
+				ID:         cfg.VLANID,
+				Link:       iface.DeviceId,
+				MTU:        cfg.MTU,
+				addressing: ifaceAddressing,
+			}
+		}
+
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("marshalling network config: %w", err)
+		}
+
+		if spec.Metadata == nil {
+			spec.Metadata = map[string]string{}
+		}
+
+		spec.Metadata[cloudinit.NetworkConfigDataKey] = base64.StdEncoding.EncodeToString(data)
+
+		if spec.Kernel != nil {
+			spec.Kernel.AddNetworkConfig = false
+		}
+
+		return nil
+	}
+}
+
+func newAddressing(address *flintlocktypes.StaticAddress, cfg InterfaceConfig) (addressing, error) {
+	result := addressing{}
+
+	gateway := cfg.Gateway
+	nameserverAddresses := cfg.Nameservers
+
+	if address == nil {
+		// The address family of the network isn't known so both are enabled, like in the
+		// network-config generated by flintlock, so that IPv6 and dual stack guests get an
+		// address.
+		dhcp := true
+		result.DHCP4 = &dhcp
+		result.DHCP6 = &dhcp
+		result.DHCPIdentifier = dhcpIdentifierMAC
+	} else {
+		result.Addresses = []string{address.Address}
+
+		if address.Gateway != nil && *address.Gateway != "" {
+			gateway = *address.Gateway
+		}
+
+		if len(address.Nameservers) > 0 {
+			nameserverAddresses = address.Nameservers
+		}
+	}
+
+	if gateway != "" && address != nil {
+		gatewayIP := net.ParseIP(gateway)
+		if gatewayIP == nil {
+			return addressing{}, &invalidGatewayError{gateway: gateway}
+		}
+
+		if gatewayIP.To4() != nil {
+			result.Gateway4 = gateway
+		} else {
+			result.Gateway6 = gateway
+		}
+	}
+
+	if len(nameserverAddresses) > 0 {
+		result.Nameservers = &nameservers{Addresses: nameserverAddresses}
+	}
+
+	return result, nil
+}
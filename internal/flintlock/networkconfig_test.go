@@ -0,0 +1,314 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"k8s.io/utils/pointer"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+func TestWithNetworkConfig(t *testing.T) {
+	RegisterTestingT(t)
+
+	tt := []struct {
+		name         string
+		interfaces   []*flintlocktypes.NetworkInterface
+		configs      []flintlock.InterfaceConfig
+		expectedYAML string
+		expectedErr  bool
+	}{
+		{
+			name:       "dhcp interface matched by name",
+			interfaces: []*flintlocktypes.NetworkInterface{{DeviceId: "eth0"}},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    dhcp4: true
+    dhcp6: true
+    dhcp-identifier: mac
+`,
+		},
+		{
+			name: "interface matched by mac",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{DeviceId: "eth0", GuestMac: pointer.String("02:00:00:00:00:01")},
+			},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      macaddress: "02:00:00:00:00:01"
+    set-name: eth0
+    dhcp4: true
+    dhcp6: true
+    dhcp-identifier: mac
+`,
+		},
+		{
+			name: "static ipv4 address with gateway and nameservers",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{
+					DeviceId: "eth0",
+					Address: &flintlocktypes.StaticAddress{
+						Address:     "10.0.0.5/24",
+						Gateway:     pointer.String("10.0.0.1"),
+						Nameservers: []string{"1.1.1.1", "8.8.8.8"},
+					},
+				},
+			},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    addresses:
+    - 10.0.0.5/24
+    gateway4: 10.0.0.1
+    nameservers:
+      addresses:
+      - 1.1.1.1
+      - 8.8.8.8
+`,
+		},
+		{
+			name: "static ipv6 address",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{
+					DeviceId: "eth0",
+					Address: &flintlocktypes.StaticAddress{
+						Address: "2001:db8::5/64",
+						Gateway: pointer.String("2001:db8::1"),
+					},
+				},
+			},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    addresses:
+    - 2001:db8::5/64
+    gateway6: 2001:db8::1
+`,
+		},
+		{
+			name: "gateway and nameservers from config when address has none",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{DeviceId: "eth0", Address: &flintlocktypes.StaticAddress{Address: "10.0.0.5/24"}},
+			},
+			configs: []flintlock.InterfaceConfig{
+				{DeviceID: "eth0", Gateway: "10.0.0.1", Nameservers: []string{"10.0.0.2"}},
+			},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    addresses:
+    - 10.0.0.5/24
+    gateway4: 10.0.0.1
+    nameservers:
+      addresses:
+      - 10.0.0.2
+`,
+		},
+		{
+			name: "address gateway takes precedence over config",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{
+					DeviceId: "eth0",
+					Address:  &flintlocktypes.StaticAddress{Address: "10.0.0.5/24", Gateway: pointer.String("10.0.0.254")},
+				},
+			},
+			configs: []flintlock.InterfaceConfig{{DeviceID: "eth0", Gateway: "10.0.0.1"}},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    addresses:
+    - 10.0.0.5/24
+    gateway4: 10.0.0.254
+`,
+		},
+		{
+			name:       "gateway ignored for dhcp",
+			interfaces: []*flintlocktypes.NetworkInterface{{DeviceId: "eth0"}},
+			configs:    []flintlock.InterfaceConfig{{DeviceID: "eth0", Gateway: "10.0.0.1", Nameservers: []string{"10.0.0.2"}}},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    dhcp4: true
+    dhcp6: true
+    dhcp-identifier: mac
+    nameservers:
+      addresses:
+      - 10.0.0.2
+`,
+		},
+		{
+			name: "routes and mtu",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{DeviceId: "eth0", Address: &flintlocktypes.StaticAddress{Address: "10.0.0.5/24"}},
+			},
+			configs: []flintlock.InterfaceConfig{
+				{
+					DeviceID: "eth0",
+					MTU:      9000,
+					Routes: []flintlock.Route{
+						{To: "10.1.0.0/16", Via: "10.0.0.254"},
+						{To: "10.2.0.0/16", Via: "10.0.0.253", Metric: pointer.Int32(100)},
+					},
+				},
+			},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    mtu: 9000
+    addresses:
+    - 10.0.0.5/24
+    routes:
+    - to: 10.1.0.0/16
+      via: 10.0.0.254
+    - to: 10.2.0.0/16
+      via: 10.0.0.253
+      metric: 100
+`,
+		},
+		{
+			name: "vlan interface gets the address",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{DeviceId: "eth0"},
+				{
+					DeviceId: "eth1",
+					GuestMac: pointer.String("02:00:00:00:00:01"),
+					Address:  &flintlocktypes.StaticAddress{Address: "10.0.10.5/24", Gateway: pointer.String("10.0.10.1")},
+				},
+			},
+			configs: []flintlock.InterfaceConfig{
+				{
+					DeviceID: "eth1",
+					VLANID:   10,
+					MTU:      1500,
+					Routes:   []flintlock.Route{{To: "10.20.0.0/16", Via: "10.0.10.254"}},
+				},
+			},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    dhcp4: true
+    dhcp6: true
+    dhcp-identifier: mac
+  eth1:
+    match:
+      macaddress: "02:00:00:00:00:01"
+    set-name: eth1
+    mtu: 1500
+    dhcp4: false
+    dhcp6: false
+vlans:
+  eth1.10:
+    id: 10
+    link: eth1
+    mtu: 1500
+    addresses:
+    - 10.0.10.5/24
+    gateway4: 10.0.10.1
+    routes:
+    - to: 10.20.0.0/16
+      via: 10.0.10.254
+`,
+		},
+		{
+			name: "dhcp on vlan interface",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{DeviceId: "eth0"},
+			},
+			configs: []flintlock.InterfaceConfig{{DeviceID: "eth0", VLANID: 100}},
+			expectedYAML: `
+version: 2
+ethernets:
+  eth0:
+    match:
+      name: eth0
+    dhcp4: false
+    dhcp6: false
+vlans:
+  eth0.100:
+    id: 100
+    link: eth0
+    dhcp4: true
+    dhcp6: true
+    dhcp-identifier: mac
+`,
+		},
+		{
+			name: "invalid gateway",
+			interfaces: []*flintlocktypes.NetworkInterface{
+				{DeviceId: "eth0", Address: &flintlocktypes.StaticAddress{Address: "10.0.0.5/24"}},
+			},
+			configs:     []flintlock.InterfaceConfig{{DeviceID: "eth0", Gateway: "nope"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			spec := &flintlocktypes.MicroVMSpec{
+				Kernel:     &flintlocktypes.Kernel{AddNetworkConfig: true},
+				Interfaces: tc.interfaces,
+			}
+
+			err := flintlock.WithNetworkConfig(tc.configs...)(spec)
+			if tc.expectedErr {
+				Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Kernel.AddNetworkConfig).To(BeFalse(), "expect flintlock not to add its network config")
+
+			data, err := base64.StdEncoding.DecodeString(spec.Metadata["network-config"])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(MatchYAML(tc.expectedYAML))
+		})
+	}
+}
+
+func TestWithNetworkConfigNoInterfaces(t *testing.T) {
+	RegisterTestingT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{Kernel: &flintlocktypes.Kernel{AddNetworkConfig: true}}
+
+	Expect(flintlock.WithNetworkConfig()(spec)).To(Succeed())
+	Expect(spec.Metadata).NotTo(HaveKey("network-config"))
+	Expect(spec.Kernel.AddNetworkConfig).To(BeTrue())
+}
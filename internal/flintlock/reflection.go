@@ -0,0 +1,180 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flgrpc "github.com/liquidmetal-dev/flintlock/client/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
+)
+
+const (
+	microvmSpecPackage = "flintlock.types"
+	microvmSpecMessage = "MicroVMSpec"
+
+	// MicrovmSpecProviderField is the field of the microvm spec that chooses the provider of the
+	// microvm. Hosts whose API doesn't have it create microvms with their default provider.
+	MicrovmSpecProviderField = "provider"
+)
+
+// ErrReflectionNotServed is returned by DescribeHost if the host doesn't serve gRPC server
+// reflection, so what its API supports can't be found out.
+var ErrReflectionNotServed = errors.New("host doesn't serve grpc server reflection")
+
+// HostDescription is what a flintlock host reports about its API.
+type HostDescription struct {
+	// MicrovmSpecFields are the names of the fields of the microvm spec in the API of the host.
+	MicrovmSpecFields []string
+}
+
+// DescribeHostFunc describes the API of the flintlock host at the address.
+type DescribeHostFunc func(ctx context.Context, address string, creds clientpool.Credentials) (*HostDescription, error)
+
+// DescribeHost uses the gRPC server reflection of the flintlock host at the address to find out
+// which fields of the microvm spec its API has. Flintlock doesn't report its version or the
+// providers it has, but hosts running an older version don't have the fields added since and
+// ignore them. It returns ErrReflectionNotServed if the host doesn't serve reflection.
+func DescribeHost(ctx context.Context, address string, creds clientpool.Credentials) (*HostDescription, error) {
+	conn, err := dial(address, creds)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stream, err := grpc_reflection_v1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, reflectionError(err)
+	}
+
+	symbol := microvmSpecPackage + "." + microvmSpecMessage
+
+	err = stream.Send(&grpc_reflection_v1.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: symbol,
+		},
+	})
+	if err != nil {
+		return nil, reflectionError(err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, reflectionError(err)
+	}
+
+	_ = stream.CloseSend()
+
+	if errResp := resp.GetErrorResponse(); errResp != nil {
+		return nil, fmt.Errorf("describing %s: %s", symbol, errResp.GetErrorMessage())
+	}
+
+	for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		file := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(raw, file); err != nil {
+			return nil, fmt.Errorf("parsing file descriptor: %w", err)
+		}
+
+		if file.GetPackage() != microvmSpecPackage {
+			continue
+		}
+
+		for _, message := range file.GetMessageType() {
+			if message.GetName() != microvmSpecMessage {
+				continue
+			}
+
+			description := &HostDescription{}
+			for _, field := range message.GetField() {
+				description.MicrovmSpecFields = append(description.MicrovmSpecFields, field.GetName())
+			}
+
+			return description, nil
+		}
+	}
+
+	return nil, fmt.Errorf("host didn't describe %s", symbol)
+}
+
+func reflectionError(err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		return ErrReflectionNotServed
+	}
+
+	return fmt.Errorf("calling server reflection: %w", err)
+}
+
+// dial connects to the host the same way as the flintlock client, as the connections of the
+// client can't be used for other services.
+func dial(address string, creds clientpool.Credentials) (*grpc.ClientConn, error) {
+	transportCreds := insecure.NewCredentials()
+
+	if creds.TLS != nil {
+		var err error
+
+		transportCreds, err = loadTLS(creds.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCreds),
+	}
+
+	if creds.BasicAuthToken != "" {
+		dialOpts = append(dialOpts,
+			grpc.WithPerRPCCredentials(flclient.Basic(creds.BasicAuthToken, creds.TLS != nil)),
+		)
+	}
+
+	if creds.Proxy != nil {
+		proxyURL, err := url.Parse(creds.Proxy.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy server url %s: %w", creds.Proxy.Endpoint, err)
+		}
+
+		dialOpts = append(dialOpts, flgrpc.WithProxy(proxyURL))
+	}
+
+	conn, err := grpc.NewClient(address, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating grpc connection: %w", err)
+	}
+
+	return conn, nil
+}
+
+func loadTLS(cfg *flclient.TLSConfig) (credentials.TransportCredentials, error) {
+	certificate, err := tls.X509KeyPair(cfg.Cert, cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("loading client certificate: %w", err)
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(cfg.CACert) {
+		return nil, errInvalidCACert
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      caPool,
+	}), nil
+}
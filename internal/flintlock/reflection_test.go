@@ -0,0 +1,70 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+func TestDescribeHost(t *testing.T) {
+	RegisterTestingT(t)
+
+	address := startHost(t, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	description, err := flintlock.DescribeHost(ctx, address, clientpool.Credentials{})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(description.MicrovmSpecFields).To(ContainElements("id", "vcpu", flintlock.MicrovmSpecProviderField))
+}
+
+func TestDescribeHostWithoutReflection(t *testing.T) {
+	RegisterTestingT(t)
+
+	address := startHost(t, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := flintlock.DescribeHost(ctx, address, clientpool.Credentials{})
+	Expect(err).To(MatchError(flintlock.ErrReflectionNotServed))
+}
+
+// startHost serves the flintlock API on a local port and returns its address.
+func startHost(t *testing.T, withReflection bool) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+
+	server := grpc.NewServer()
+	flintlockv1.RegisterMicroVMServer(server, &flintlockv1.UnimplementedMicroVMServer{})
+
+	if withReflection {
+		reflection.Register(server)
+	}
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"context"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/tracing"
+)
+
+const microvmService = "microvm.services.api.v1alpha1.MicroVM"
+
+// NewTracingClient wraps the supplied client for the host so that every call has a span and
+// the trace context is sent to the host in the gRPC metadata.
+func NewTracingClient(client flclient.Client, host string) flclient.Client {
+	return &tracingClient{
+		Client: client,
+		host:   host,
+	}
+}
+
+type tracingClient struct {
+	flclient.Client
+
+	host string
+}
+
+func (c *tracingClient) CreateMicroVM(
+	ctx context.Context,
+	in *flintlockv1.CreateMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.CreateMicroVMResponse, error) {
+	ctx, span := c.start(ctx, "CreateMicroVM")
+	resp, err := c.Client.CreateMicroVM(ctx, in, opts...)
+	c.end(span, err)
+
+	return resp, err
+}
+
+func (c *tracingClient) DeleteMicroVM(
+	ctx context.Context,
+	in *flintlockv1.DeleteMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	ctx, span := c.start(ctx, "DeleteMicroVM")
+	resp, err := c.Client.DeleteMicroVM(ctx, in, opts...)
+	c.end(span, err)
+
+	return resp, err
+}
+
+func (c *tracingClient) GetMicroVM(
+	ctx context.Context,
+	in *flintlockv1.GetMicroVMRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.GetMicroVMResponse, error) {
+	ctx, span := c.start(ctx, "GetMicroVM")
+	resp, err := c.Client.GetMicroVM(ctx, in, opts...)
+	c.end(span, err)
+
+	return resp, err
+}
+
+func (c *tracingClient) ListMicroVMs(
+	ctx context.Context,
+	in *flintlockv1.ListMicroVMsRequest,
+	opts ...grpc.CallOption,
+) (*flintlockv1.ListMicroVMsResponse, error) {
+	ctx, span := c.start(ctx, "ListMicroVMs")
+	resp, err := c.Client.ListMicroVMs(ctx, in, opts...)
+	c.end(span, err)
+
+	return resp, err
+}
+
+// ListMicroVMsStream only has a span for opening the stream, not for reading from it.
+func (c *tracingClient) ListMicroVMsStream(
+	ctx context.Context,
+	in *flintlockv1.ListMicroVMsRequest,
+	opts ...grpc.CallOption,
+) (grpc.ServerStreamingClient[flintlockv1.ListMessage], error) {
+	ctx, span := c.start(ctx, "ListMicroVMsStream")
+	stream, err := c.Client.ListMicroVMsStream(ctx, in, opts...)
+	c.end(span, err)
+
+	return stream, err
+}
+
+// start starts the span of a call and adds its trace context to the outgoing metadata.
+func (c *tracingClient) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx, span := tracing.Tracer().Start(ctx, microvmService+"/"+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(microvmService),
+			semconv.RPCMethod(method),
+			attribute.String("capmvm.host", c.host),
+		),
+	)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+func (c *tracingClient) end(span trace.Span, err error) {
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(StatusCode(err))))
+	tracing.End(span, err)
+}
+
+// metadataCarrier lets the propagator read and write the trace context in gRPC metadata.
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	values := metadata.MD(m).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlockv1 "github.com/liquidmetal-dev/flintlock/api/services/microvm/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/controllers/fakes"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+func TestTracingClient(t *testing.T) {
+	RegisterTestingT(t)
+
+	recorder := withSpanRecorder(t)
+
+	fakeClient := &fakes.FakeClient{}
+	fakeClient.GetMicroVMReturns(nil, status.Error(codes.NotFound, "microvm not found"))
+
+	client := flintlock.NewTracingClient(fakeClient, "tracing-test:9090")
+
+	ctx, parent := otel.Tracer("test").Start(context.TODO(), "reconcile")
+	ctx = metadata.AppendToOutgoingContext(ctx, "existing", "value")
+
+	_, err := client.CreateMicroVM(ctx, &flintlockv1.CreateMicroVMRequest{})
+	Expect(err).NotTo(HaveOccurred())
+
+	_, err = client.GetMicroVM(ctx, &flintlockv1.GetMicroVMRequest{})
+	Expect(err).To(HaveOccurred())
+
+	parent.End()
+
+	spans := recorder.Ended()
+	Expect(spans).To(HaveLen(3))
+
+	create, get := spans[0], spans[1]
+	Expect(create.Name()).To(Equal("microvm.services.api.v1alpha1.MicroVM/CreateMicroVM"))
+	Expect(create.Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+	Expect(create.Status().Code).To(Equal(otelcodes.Unset))
+	Expect(create.Attributes()).To(ContainElements(
+		attribute.String("capmvm.host", "tracing-test:9090"),
+		attribute.String("rpc.method", "CreateMicroVM"),
+		attribute.Int("rpc.grpc.status_code", int(codes.OK)),
+	))
+
+	Expect(get.Name()).To(Equal("microvm.services.api.v1alpha1.MicroVM/GetMicroVM"))
+	Expect(get.Status().Code).To(Equal(otelcodes.Error))
+	Expect(get.Attributes()).To(ContainElement(attribute.Int("rpc.grpc.status_code", int(codes.NotFound))))
+
+	callCtx, _, _ := fakeClient.CreateMicroVMArgsForCall(0)
+	md, ok := metadata.FromOutgoingContext(callCtx)
+	Expect(ok).To(BeTrue())
+	Expect(md.Get("existing")).To(ConsistOf("value"))
+	Expect(md.Get("traceparent")).To(HaveLen(1))
+	Expect(md.Get("traceparent")[0]).To(ContainSubstring(create.SpanContext().SpanID().String()))
+
+	original, _ := metadata.FromOutgoingContext(ctx)
+	Expect(original.Get("traceparent")).To(BeEmpty(), "the metadata of the caller shouldn't be changed")
+}
+
+func TestTracingClientDisabled(t *testing.T) {
+	RegisterTestingT(t)
+
+	fakeClient := &fakes.FakeClient{}
+	client := flintlock.NewTracingClient(fakeClient, "tracing-test:9090")
+
+	_, err := client.CreateMicroVM(context.TODO(), &flintlockv1.CreateMicroVMRequest{})
+	Expect(err).NotTo(HaveOccurred())
+
+	callCtx, _, _ := fakeClient.CreateMicroVMArgsForCall(0)
+	md, _ := metadata.FromOutgoingContext(callCtx)
+	Expect(md.Get("traceparent")).To(BeEmpty())
+}
+
+// withSpanRecorder records the spans of the test. The global tracer provider and propagator are
+// set back to no-ops, as they are when tracing is disabled, when it's done.
+func withSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return recorder
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"github.com/liquidmetal-dev/flintlock/client/cloudinit"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	writeFilesKey = "write_files"
+	runCmdKey     = "runcmd"
+)
+
+// File is a file that will be written in the guest by cloud-init.
+type File struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Owner       string `yaml:"owner,omitempty"`
+	Permissions string `yaml:"permissions,omitempty"`
+}
+
+// WithUserDataFiles returns a SpecMutator that adds the supplied files to the write_files of
+// the cloud-config user-data (i.e. the bootstrap data) of the microvm. Files with a path that
+// is already in the user-data are left untouched.
+func WithUserDataFiles(files ...File) SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		if len(files) == 0 {
+			return nil
+		}
+
+		return updateUserData(spec, func(userData yaml.MapSlice) yaml.MapSlice {
+			idx, writeFiles := listItem(userData, writeFilesKey)
+
+			existing := map[string]bool{}
+
+			for _, f := range writeFiles {
+				file, ok := f.(yaml.MapSlice)
+				if !ok {
+					continue
+				}
+
+				for _, item := range file {
+					if item.Key == "path" {
+						existing[fmt.Sprint(item.Value)] = true
+					}
+				}
+			}
+
+			for _, file := range files {
+				if existing[file.Path] {
+					continue
+				}
+
+				writeFiles = append(writeFiles, file)
+			}
+
+			return setListItem(userData, idx, writeFilesKey, writeFiles)
+		})
+	}
+}
+
+// WithUserDataCommands returns a SpecMutator that adds the supplied commands to the end of the
+// runcmd of the cloud-config user-data of the microvm, so they run after the commands of the
+// bootstrap provider (e.g. kubeadm init).
+func WithUserDataCommands(commands ...string) SpecMutator {
+	return func(spec *flintlocktypes.MicroVMSpec) error {
+		if len(commands) == 0 {
+			return nil
+		}
+
+		return updateUserData(spec, func(userData yaml.MapSlice) yaml.MapSlice {
+			idx, runCmd := listItem(userData, runCmdKey)
+
+			for _, command := range commands {
+				runCmd = append(runCmd, command)
+			}
+
+			return setListItem(userData, idx, runCmdKey, runCmd)
+		})
+	}
+}
+
+// updateUserData decodes the cloud-config user-data of the microvm, applies the update to it and
+// encodes it again.
+func updateUserData(spec *flintlocktypes.MicroVMSpec, update func(userData yaml.MapSlice) yaml.MapSlice) error {
+	if spec.Metadata == nil {
+		spec.Metadata = map[string]string{}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(spec.Metadata[cloudinit.UserdataKey])
+	if err != nil {
+		return fmt.Errorf("decoding user data: %w", err)
+	}
+
+	// Cloud-init uses the comments at the start of the user-data (e.g. #cloud-config
+	// and ## template: jinja) so they need to be kept.
+	var header bytes.Buffer
+
+	for bytes.HasPrefix(data, []byte("#")) {
+		line, rest, _ := bytes.Cut(data, []byte("\n"))
+		header.Write(line)
+		header.WriteString("\n")
+		data = rest
+	}
+
+	userData := yaml.MapSlice{}
+	if err := yaml.Unmarshal(data, &userData); err != nil {
+		return fmt.Errorf("unmarshalling user data: %w", err)
+	}
+
+	updated, err := yaml.Marshal(update(userData))
+	if err != nil {
+		return fmt.Errorf("marshalling user data: %w", err)
+	}
+
+	spec.Metadata[cloudinit.UserdataKey] = base64.StdEncoding.EncodeToString(append(header.Bytes(), updated...))
+
+	return nil
+}
+
+// listItem returns the index and value of the list with the key in the user-data, or -1 if the
+// key isn't in the user-data.
+func listItem(userData yaml.MapSlice, key string) (int, []interface{}) {
+	for i, item := range userData {
+		if item.Key == key {
+			list, _ := item.Value.([]interface{})
+
+			return i, list
+		}
+	}
+
+	return -1, nil
+}
+
+// setListItem sets the value of the list at the index returned by listItem, adding it to the end
+// of the user-data if it wasn't there.
+func setListItem(userData yaml.MapSlice, idx int, key string, list []interface{}) yaml.MapSlice {
+	if idx == -1 {
+		return append(userData, yaml.MapItem{Key: key, Value: list})
+	}
+
+	userData[idx].Value = list
+
+	return userData
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package flintlock_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"gopkg.in/yaml.v2"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+const testUserData = `## template: jinja
+#cloud-config
+
+write_files:
+- path: /etc/existing
+  content: existing
+runcmd:
+- kubeadm init
+`
+
+func TestWithUserDataFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{
+		Metadata: map[string]string{
+			"user-data": base64.StdEncoding.EncodeToString([]byte(testUserData)),
+		},
+	}
+
+	err := flintlock.WithUserDataFiles(
+		flintlock.File{Path: "/etc/existing", Content: "replaced"},
+		flintlock.File{Path: "/etc/kubernetes/manifests/kube-vip.yaml", Content: "manifest", Permissions: "0644"},
+	)(spec)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	data, err := base64.StdEncoding.DecodeString(spec.Metadata["user-data"])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(strings.HasPrefix(string(data), "## template: jinja\n#cloud-config\n")).To(BeTrue(), "expect header to be kept")
+
+	userData := struct {
+		WriteFiles []flintlock.File `yaml:"write_files"`
+		RunCmd     []string         `yaml:"runcmd"`
+	}{}
+	g.Expect(yaml.Unmarshal(data, &userData)).To(Succeed())
+
+	g.Expect(userData.RunCmd).To(Equal([]string{"kubeadm init"}))
+	g.Expect(userData.WriteFiles).To(Equal([]flintlock.File{
+		{Path: "/etc/existing", Content: "existing"},
+		{Path: "/etc/kubernetes/manifests/kube-vip.yaml", Content: "manifest", Permissions: "0644"},
+	}))
+}
+
+func TestWithUserDataFilesNoWriteFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{
+		Metadata: map[string]string{
+			"user-data": base64.StdEncoding.EncodeToString([]byte("#cloud-config\nruncmd:\n- echo hello\n")),
+		},
+	}
+
+	err := flintlock.WithUserDataFiles(flintlock.File{Path: "/etc/new", Content: "new"})(spec)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	data, err := base64.StdEncoding.DecodeString(spec.Metadata["user-data"])
+	g.Expect(err).NotTo(HaveOccurred())
+
+	userData := struct {
+		WriteFiles []flintlock.File `yaml:"write_files"`
+	}{}
+	g.Expect(yaml.Unmarshal(data, &userData)).To(Succeed())
+	g.Expect(userData.WriteFiles).To(Equal([]flintlock.File{{Path: "/etc/new", Content: "new"}}))
+}
+
+func TestWithUserDataCommands(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := &flintlocktypes.MicroVMSpec{
+		Metadata: map[string]string{
+			"user-data": base64.StdEncoding.EncodeToString([]byte(testUserData)),
+		},
+	}
+
+	err := flintlock.WithUserDataCommands("echo done")(spec)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	data, err := base64.StdEncoding.DecodeString(spec.Metadata["user-data"])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(strings.HasPrefix(string(data), "## template: jinja\n#cloud-config\n")).To(BeTrue(), "expect header to be kept")
+
+	userData := struct {
+		WriteFiles []flintlock.File `yaml:"write_files"`
+		RunCmd     []string         `yaml:"runcmd"`
+	}{}
+	g.Expect(yaml.Unmarshal(data, &userData)).To(Succeed())
+
+	g.Expect(userData.RunCmd).To(Equal([]string{"kubeadm init", "echo done"}), "expect the command to run after the bootstrap commands")
+	g.Expect(userData.WriteFiles).To(Equal([]flintlock.File{{Path: "/etc/existing", Content: "existing"}}))
+}
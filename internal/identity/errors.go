@@ -0,0 +1,16 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package identity
+
+import "errors"
+
+var (
+	// ErrNoIdentity is returned when the MicrovmCluster doesn't reference an identity.
+	ErrNoIdentity = errors.New("microvm cluster doesn't reference an identity")
+	// ErrNotFound is returned when the identity referenced by a MicrovmCluster doesn't exist.
+	ErrNotFound = errors.New("identity not found")
+	// ErrNamespaceNotAllowed is returned when the namespace of a MicrovmCluster isn't allowed
+	// to use the identity it references.
+	ErrNamespaceNotAllowed = errors.New("namespace is not allowed to use the identity")
+)
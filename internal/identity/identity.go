@@ -0,0 +1,84 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package identity contains the logic for using a MicrovmClusterIdentity from a MicrovmCluster.
+package identity
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+)
+
+// Get returns the identity referenced by the MicrovmCluster after checking that the namespace
+// of the MicrovmCluster is allowed to use it.
+func Get(ctx context.Context, c client.Reader, mvmCluster *infrav1.MicrovmCluster) (*infrav1.MicrovmClusterIdentity, error) {
+	if mvmCluster.Spec.IdentityRef == nil {
+		return nil, ErrNoIdentity
+	}
+
+	identity := &infrav1.MicrovmClusterIdentity{}
+	if err := c.Get(ctx, client.ObjectKey{Name: mvmCluster.Spec.IdentityRef.Name}, identity); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("identity %s: %w", mvmCluster.Spec.IdentityRef.Name, ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("getting identity %s: %w", mvmCluster.Spec.IdentityRef.Name, err)
+	}
+
+	allowed, err := IsNamespaceAllowed(ctx, c, identity, mvmCluster.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, fmt.Errorf("identity %s used from namespace %s: %w", identity.Name, mvmCluster.Namespace, ErrNamespaceNotAllowed)
+	}
+
+	return identity, nil
+}
+
+// IsNamespaceAllowed returns true if the identity can be used by MicrovmClusters in the namespace.
+func IsNamespaceAllowed(
+	ctx context.Context,
+	c client.Reader,
+	identity *infrav1.MicrovmClusterIdentity,
+	namespace string,
+) (bool, error) {
+	allowed := identity.Spec.AllowedNamespaces
+	if allowed == nil {
+		return false, nil
+	}
+
+	if len(allowed.NamespaceList) == 0 && allowed.Selector == nil {
+		return true, nil
+	}
+
+	if slices.Contains(allowed.NamespaceList, namespace) {
+		return true, nil
+	}
+
+	if allowed.Selector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(allowed.Selector)
+	if err != nil {
+		return false, fmt.Errorf("parsing allowed namespaces selector of identity %s: %w", identity.Name, err)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return false, fmt.Errorf("getting namespace %s: %w", namespace, err)
+	}
+
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package identity_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/identity"
+)
+
+func TestIsNamespaceAllowed(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := newFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"tenant": "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}},
+	)
+
+	tt := []struct {
+		name      string
+		allowed   *infrav1.AllowedNamespaces
+		namespace string
+		expected  bool
+	}{
+		{
+			name:      "no allowed namespaces allows none",
+			allowed:   nil,
+			namespace: "tenant-a",
+			expected:  false,
+		},
+		{
+			name:      "empty allowed namespaces allows all",
+			allowed:   &infrav1.AllowedNamespaces{},
+			namespace: "tenant-b",
+			expected:  true,
+		},
+		{
+			name:      "namespace in list is allowed",
+			allowed:   &infrav1.AllowedNamespaces{NamespaceList: []string{"tenant-b"}},
+			namespace: "tenant-b",
+			expected:  true,
+		},
+		{
+			name:      "namespace not in list isn't allowed",
+			allowed:   &infrav1.AllowedNamespaces{NamespaceList: []string{"tenant-b"}},
+			namespace: "tenant-a",
+			expected:  false,
+		},
+		{
+			name: "namespace matching selector is allowed",
+			allowed: &infrav1.AllowedNamespaces{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+			namespace: "tenant-a",
+			expected:  true,
+		},
+		{
+			name: "namespace not matching selector isn't allowed",
+			allowed: &infrav1.AllowedNamespaces{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+			namespace: "tenant-b",
+			expected:  false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			clusterIdentity := newIdentity("shared", tc.allowed)
+
+			allowed, err := identity.IsNamespaceAllowed(context.TODO(), c, clusterIdentity, tc.namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	RegisterTestingT(t)
+
+	c := newFakeClient(newIdentity("shared", &infrav1.AllowedNamespaces{NamespaceList: []string{"tenant-a"}}))
+
+	clusterIdentity, err := identity.Get(context.TODO(), c, newMicrovmCluster("tenant-a", "shared"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(clusterIdentity.Name).To(Equal("shared"))
+
+	_, err = identity.Get(context.TODO(), c, newMicrovmCluster("tenant-b", "shared"))
+	Expect(err).To(MatchError(identity.ErrNamespaceNotAllowed))
+
+	_, err = identity.Get(context.TODO(), c, newMicrovmCluster("tenant-a", "missing"))
+	Expect(err).To(MatchError(identity.ErrNotFound))
+
+	_, err = identity.Get(context.TODO(), c, newMicrovmCluster("tenant-a", ""))
+	Expect(err).To(MatchError(identity.ErrNoIdentity))
+}
+
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(infrav1.AddToScheme(scheme)).To(Succeed())
+	Expect(corev1.AddToScheme(scheme)).To(Succeed())
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func newIdentity(name string, allowed *infrav1.AllowedNamespaces) *infrav1.MicrovmClusterIdentity {
+	return &infrav1.MicrovmClusterIdentity{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: infrav1.MicrovmClusterIdentitySpec{
+			AllowedNamespaces: allowed,
+		},
+	}
+}
+
+func newMicrovmCluster(namespace, identityName string) *infrav1.MicrovmCluster {
+	mvmCluster := &infrav1.MicrovmCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "testcluster", Namespace: namespace},
+	}
+
+	if identityName != "" {
+		mvmCluster.Spec.IdentityRef = &infrav1.MicrovmClusterIdentityReference{Name: identityName}
+	}
+
+	return mvmCluster
+}
@@ -0,0 +1,11 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package kubevip
+
+import "errors"
+
+var (
+	errEndpointRequired  = errors.New("control plane endpoint host is required for kube-vip")
+	errBGPConfigRequired = errors.New("bgp configuration is required for kube-vip-bgp")
+)
@@ -0,0 +1,184 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package kubevip generates the static pod manifest used to run kube-vip as the
+// control plane load balancer.
+package kubevip
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+)
+
+const (
+	// DefaultImage is the kube-vip image used if one isn't specified.
+	DefaultImage = "ghcr.io/kube-vip/kube-vip:v0.8.9"
+	// ManifestPath is the path in the guest that the static pod manifest is written to.
+	ManifestPath = "/etc/kubernetes/manifests/kube-vip.yaml"
+
+	kubeconfigPath = "/etc/kubernetes/admin.conf"
+	// superAdminKubeconfigPath is the kubeconfig with cluster-admin rights that kubeadm creates on
+	// the init node from Kubernetes 1.29. The admin.conf kubeconfig only has any RBAC once kubeadm
+	// init has finished, so kube-vip can't bring up the VIP the API server is reached on with it.
+	superAdminKubeconfigPath = "/etc/kubernetes/super-admin.conf"
+
+	// RestoreKubeconfigCommand is the command run once kubeadm init has finished on the init node
+	// to switch kube-vip back to admin.conf, as super-admin.conf shouldn't be used after init.
+	RestoreKubeconfigCommand = "sed -i 's#path: " + superAdminKubeconfigPath + "#path: " + kubeconfigPath + "#' " + ManifestPath
+)
+
+var superAdminKubeconfigVersion = version.MajorMinor(1, 29)
+
+// Manifest returns the static pod manifest that runs kube-vip for the supplied load balancer
+// configuration and control plane endpoint. On the node that runs kubeadm init the manifest uses
+// super-admin.conf, which RestoreKubeconfigCommand switches back to admin.conf after init.
+func Manifest(lb *infrav1.LoadBalancerSpec, endpoint clusterv1.APIEndpoint, initNode bool) ([]byte, error) {
+	if !lb.IsManaged() {
+		return nil, fmt.Errorf("load balancer type %q isn't managed by kube-vip", lb.Type)
+	}
+
+	if endpoint.Host == "" {
+		return nil, errEndpointRequired
+	}
+
+	image := lb.Image
+	if image == "" {
+		image = DefaultImage
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "address", Value: endpoint.Host},
+		{Name: "port", Value: strconv.Itoa(int(endpoint.Port))},
+		{Name: "cp_enable", Value: "true"},
+		{Name: "cp_namespace", Value: metav1.NamespaceSystem},
+		{Name: "svc_enable", Value: "false"},
+	}
+
+	if lb.Interface != "" {
+		env = append(env, corev1.EnvVar{Name: "vip_interface", Value: lb.Interface})
+	}
+
+	switch lb.Type {
+	case infrav1.LoadBalancerTypeKubeVIPARP:
+		env = append(env,
+			corev1.EnvVar{Name: "vip_arp", Value: "true"},
+			corev1.EnvVar{Name: "vip_leaderelection", Value: "true"},
+		)
+	case infrav1.LoadBalancerTypeKubeVIPBGP:
+		if lb.BGP == nil {
+			return nil, errBGPConfigRequired
+		}
+
+		peers := make([]string, 0, len(lb.BGP.Peers))
+		for _, peer := range lb.BGP.Peers {
+			peers = append(peers, fmt.Sprintf("%s:%d::false", bgpPeerAddress(peer.Address), peer.AS))
+		}
+
+		env = append(env,
+			corev1.EnvVar{Name: "bgp_enable", Value: "true"},
+			corev1.EnvVar{Name: "bgp_as", Value: strconv.FormatUint(uint64(lb.BGP.LocalAS), 10)},
+			corev1.EnvVar{Name: "bgp_peers", Value: strings.Join(peers, ",")},
+		)
+
+		if lb.Interface != "" {
+			env = append(env, corev1.EnvVar{Name: "bgp_routerinterface", Value: lb.Interface})
+		}
+	}
+
+	hostKubeconfigPath := kubeconfigPath
+	if initNode {
+		hostKubeconfigPath = superAdminKubeconfigPath
+	}
+
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kube-vip",
+			Namespace: metav1.NamespaceSystem,
+		},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			HostAliases: []corev1.HostAlias{
+				{IP: loopbackAddress(endpoint.Host), Hostnames: []string{"kubernetes"}},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:            "kube-vip",
+					Image:           image,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Args:            []string{"manager"},
+					Env:             env,
+					SecurityContext: &corev1.SecurityContext{
+						Capabilities: &corev1.Capabilities{
+							Add: []corev1.Capability{"NET_ADMIN", "NET_RAW"},
+						},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "kubeconfig", MountPath: kubeconfigPath},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "kubeconfig",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{Path: hostKubeconfigPath},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := yaml.Marshal(pod)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling kube-vip manifest: %w", err)
+	}
+
+	return data, nil
+}
+
+// UsesSuperAdminKubeconfig returns whether kubeadm init creates super-admin.conf for the version
+// of Kubernetes, which it does from 1.29. Versions that can't be parsed are assumed to be recent.
+func UsesSuperAdminKubeconfig(kubernetesVersion string) bool {
+	v, err := version.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return true
+	}
+
+	return v.AtLeast(superAdminKubeconfigVersion)
+}
+
+// bgpPeerAddress returns the address of a BGP peer in the format kube-vip expects in the peer
+// list. The fields of a peer are separated by colons, so IPv6 addresses must be in brackets.
+func bgpPeerAddress(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil || ip.To4() != nil {
+		return address
+	}
+
+	return "[" + ip.String() + "]"
+}
+
+// loopbackAddress returns the loopback address for the IP family of the control plane endpoint
+// so that kube-vip can reach the local API server on IPv6 only hosts.
+func loopbackAddress(host string) string {
+	ip := net.ParseIP(host)
+	if ip != nil && ip.To4() == nil {
+		return net.IPv6loopback.String()
+	}
+
+	return "127.0.0.1"
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package kubevip_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/kubevip"
+)
+
+func TestManifest(t *testing.T) {
+	RegisterTestingT(t)
+
+	endpoint := clusterv1.APIEndpoint{Host: "192.168.8.15", Port: 6443}
+
+	tt := []struct {
+		name        string
+		lb          *infrav1.LoadBalancerSpec
+		expectError bool
+		expectImage string
+		expectEnv   map[string]string
+	}{
+		{
+			name:        "arp with defaults",
+			lb:          &infrav1.LoadBalancerSpec{Type: infrav1.LoadBalancerTypeKubeVIPARP},
+			expectImage: kubevip.DefaultImage,
+			expectEnv: map[string]string{
+				"address":            "192.168.8.15",
+				"port":               "6443",
+				"vip_arp":            "true",
+				"vip_leaderelection": "true",
+			},
+		},
+		{
+			name: "arp with image and interface",
+			lb: &infrav1.LoadBalancerSpec{
+				Type:      infrav1.LoadBalancerTypeKubeVIPARP,
+				Image:     "myregistry/kube-vip:v1",
+				Interface: "eth1",
+			},
+			expectImage: "myregistry/kube-vip:v1",
+			expectEnv: map[string]string{
+				"vip_arp":       "true",
+				"vip_interface": "eth1",
+			},
+		},
+		{
+			name: "bgp",
+			lb: &infrav1.LoadBalancerSpec{
+				Type: infrav1.LoadBalancerTypeKubeVIPBGP,
+				BGP: &infrav1.BGPConfig{
+					LocalAS: 65000,
+					Peers: []infrav1.BGPPeer{
+						{Address: "10.0.0.1", AS: 65001},
+						{Address: "10.0.0.2", AS: 65002},
+					},
+				},
+			},
+			expectImage: kubevip.DefaultImage,
+			expectEnv: map[string]string{
+				"bgp_enable": "true",
+				"bgp_as":     "65000",
+				"bgp_peers":  "10.0.0.1:65001::false,10.0.0.2:65002::false",
+			},
+		},
+		{
+			name: "bgp with ipv6 peers",
+			lb: &infrav1.LoadBalancerSpec{
+				Type: infrav1.LoadBalancerTypeKubeVIPBGP,
+				BGP: &infrav1.BGPConfig{
+					LocalAS: 65000,
+					Peers: []infrav1.BGPPeer{
+						{Address: "fd00::1", AS: 65001},
+						{Address: "10.0.0.2", AS: 65002},
+					},
+				},
+			},
+			expectImage: kubevip.DefaultImage,
+			expectEnv: map[string]string{
+				"bgp_peers": "[fd00::1]:65001::false,10.0.0.2:65002::false",
+			},
+		},
+		{
+			name:        "bgp without config",
+			lb:          &infrav1.LoadBalancerSpec{Type: infrav1.LoadBalancerTypeKubeVIPBGP},
+			expectError: true,
+		},
+		{
+			name:        "unmanaged",
+			lb:          &infrav1.LoadBalancerSpec{Type: infrav1.LoadBalancerTypeNone},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			data, err := kubevip.Manifest(tc.lb, endpoint, false)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pod := &corev1.Pod{}
+			g.Expect(yaml.Unmarshal(data, pod)).To(Succeed())
+			g.Expect(pod.Namespace).To(Equal("kube-system"))
+			g.Expect(pod.Spec.HostNetwork).To(BeTrue())
+			g.Expect(pod.Spec.Containers).To(HaveLen(1))
+			g.Expect(pod.Spec.Containers[0].Image).To(Equal(tc.expectImage))
+
+			env := map[string]string{}
+			for _, e := range pod.Spec.Containers[0].Env {
+				env[e.Name] = e.Value
+			}
+
+			for k, v := range tc.expectEnv {
+				g.Expect(env).To(HaveKeyWithValue(k, v))
+			}
+		})
+	}
+}
+
+func TestManifestNoEndpoint(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := kubevip.Manifest(&infrav1.LoadBalancerSpec{Type: infrav1.LoadBalancerTypeKubeVIPARP}, clusterv1.APIEndpoint{}, false)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestManifestIPv6Endpoint(t *testing.T) {
+	g := NewWithT(t)
+
+	endpoint := clusterv1.APIEndpoint{Host: "fd00::10", Port: 6443}
+
+	data, err := kubevip.Manifest(&infrav1.LoadBalancerSpec{Type: infrav1.LoadBalancerTypeKubeVIPARP}, endpoint, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pod := &corev1.Pod{}
+	g.Expect(yaml.Unmarshal(data, pod)).To(Succeed())
+	g.Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "address", Value: "fd00::10"}))
+	g.Expect(pod.Spec.HostAliases).To(ConsistOf(corev1.HostAlias{IP: "::1", Hostnames: []string{"kubernetes"}}))
+}
+
+func TestManifestKubeconfig(t *testing.T) {
+	endpoint := clusterv1.APIEndpoint{Host: "192.168.8.15", Port: 6443}
+
+	tt := []struct {
+		name             string
+		initNode         bool
+		expectKubeconfig string
+	}{
+		{
+			name:             "init node",
+			initNode:         true,
+			expectKubeconfig: "/etc/kubernetes/super-admin.conf",
+		},
+		{
+			name:             "joining node",
+			initNode:         false,
+			expectKubeconfig: "/etc/kubernetes/admin.conf",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			data, err := kubevip.Manifest(&infrav1.LoadBalancerSpec{Type: infrav1.LoadBalancerTypeKubeVIPARP}, endpoint, tc.initNode)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			pod := &corev1.Pod{}
+			g.Expect(yaml.Unmarshal(data, pod)).To(Succeed())
+			g.Expect(pod.Spec.Containers[0].VolumeMounts).To(ConsistOf(
+				corev1.VolumeMount{Name: "kubeconfig", MountPath: "/etc/kubernetes/admin.conf"},
+			))
+			g.Expect(pod.Spec.Volumes).To(HaveLen(1))
+			g.Expect(pod.Spec.Volumes[0].HostPath.Path).To(Equal(tc.expectKubeconfig))
+		})
+	}
+}
+
+func TestRestoreKubeconfigCommand(t *testing.T) {
+	g := NewWithT(t)
+
+	initManifest, err := kubevip.Manifest(&infrav1.LoadBalancerSpec{Type: infrav1.LoadBalancerTypeKubeVIPARP},
+		clusterv1.APIEndpoint{Host: "192.168.8.15", Port: 6443}, true)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The command replaces the host path of the kubeconfig volume with sed, so it must only
+	// match that line of the manifest.
+	g.Expect(strings.Count(string(initManifest), "path: /etc/kubernetes/super-admin.conf")).To(Equal(1))
+	g.Expect(kubevip.RestoreKubeconfigCommand).To(Equal(
+		"sed -i 's#path: /etc/kubernetes/super-admin.conf#path: /etc/kubernetes/admin.conf#' /etc/kubernetes/manifests/kube-vip.yaml"))
+}
+
+func TestUsesSuperAdminKubeconfig(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(kubevip.UsesSuperAdminKubeconfig("v1.28.9")).To(BeFalse())
+	g.Expect(kubevip.UsesSuperAdminKubeconfig("v1.29.0")).To(BeTrue())
+	g.Expect(kubevip.UsesSuperAdminKubeconfig("v1.31.2")).To(BeTrue())
+}
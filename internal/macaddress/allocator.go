@@ -0,0 +1,264 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package macaddress allocates the MAC addresses of microvm network interfaces so that
+// they are unique within a configurable scope.
+package macaddress
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+)
+
+// Scope is the scope that MAC addresses must be unique within.
+type Scope string
+
+const (
+	// ScopeCluster means MAC addresses are unique within the machines of a CAPI cluster.
+	ScopeCluster = Scope("cluster")
+	// ScopeHost means MAC addresses are unique within the microvms on a flintlock host. As the
+	// host isn't known when the address is allocated, addresses are allocated so that they are
+	// unique within the management cluster and duplicates are detected before the microvm is
+	// created.
+	ScopeHost = Scope("host")
+	// ScopeManagementCluster means MAC addresses are unique across all the machines in the
+	// management cluster.
+	ScopeManagementCluster = Scope("management-cluster")
+
+	maxPrefixLength = 5
+	macLength       = 6
+	maxProbes       = 1 << 16
+
+	multicastBit = 0x01
+	localBit     = 0x02
+)
+
+// Config is the configuration of the MAC address allocator.
+type Config struct {
+	// Scope is the scope that MAC addresses are unique within.
+	Scope Scope
+	// Prefix is an optional OUI or prefix (e.g. 02:00:5e) that all allocated MAC addresses
+	// will start with. If not set random locally administered unicast addresses are used.
+	Prefix string
+}
+
+// Allocator allocates unique MAC addresses for the network interfaces of microvm machines.
+type Allocator struct {
+	client client.Reader
+	scope  Scope
+	prefix net.HardwareAddr
+}
+
+// NewAllocator creates a new MAC address allocator that uses the supplied client to find
+// the MAC addresses that are already in use.
+func NewAllocator(c client.Reader, cfg Config) (*Allocator, error) {
+	if c == nil {
+		return nil, errClientRequired
+	}
+
+	scope := cfg.Scope
+	if scope == "" {
+		scope = ScopeCluster
+	}
+
+	switch scope {
+	case ScopeCluster, ScopeHost, ScopeManagementCluster:
+	default:
+		return nil, &invalidScopeError{scope: scope}
+	}
+
+	prefix, err := parsePrefix(cfg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Allocator{
+		client: c,
+		scope:  scope,
+		prefix: prefix,
+	}, nil
+}
+
+// Allocate sets the MAC address of any network interfaces of the machine that don't have one
+// to an address that isn't in use within the scope of the allocator.
+func (a *Allocator) Allocate(ctx context.Context, machine *infrav1.MicrovmMachine) error {
+	inUse, err := a.inUse(ctx, machine)
+	if err != nil {
+		return err
+	}
+
+	for i := range machine.Spec.NetworkInterfaces {
+		iface := &machine.Spec.NetworkInterfaces[i]
+		if iface.GuestMAC != "" {
+			inUse[Normalize(iface.GuestMAC)] = true
+		}
+	}
+
+	for i := range machine.Spec.NetworkInterfaces {
+		iface := &machine.Spec.NetworkInterfaces[i]
+		if iface.GuestMAC != "" {
+			continue
+		}
+
+		mac, err := a.generate(inUse)
+		if err != nil {
+			return err
+		}
+
+		inUse[mac] = true
+		iface.GuestMAC = mac
+	}
+
+	return nil
+}
+
+// Validate checks that the MAC addresses of the machine aren't already in use within the
+// scope of the allocator. When the scope is the host the duplicates can only be detected
+// once the machine has been placed, so no errors are returned.
+func (a *Allocator) Validate(ctx context.Context, machine *infrav1.MicrovmMachine) (field.ErrorList, error) {
+	if a.scope == ScopeHost {
+		return nil, nil
+	}
+
+	inUse, err := a.inUse(ctx, machine)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs field.ErrorList
+
+	for i, iface := range machine.Spec.NetworkInterfaces {
+		if iface.GuestMAC == "" || !inUse[Normalize(iface.GuestMAC)] {
+			continue
+		}
+
+		fieldPath := field.NewPath("spec", "networkInterfaces").Index(i).Child("guestMac")
+		errs = append(errs, field.Duplicate(fieldPath, iface.GuestMAC))
+	}
+
+	return errs, nil
+}
+
+// inUse returns the MAC addresses used by the other machines within the scope of the allocator.
+func (a *Allocator) inUse(ctx context.Context, machine *infrav1.MicrovmMachine) (map[string]bool, error) {
+	opts := []client.ListOption{}
+
+	if a.scope == ScopeCluster {
+		opts = append(opts, client.InNamespace(machine.Namespace))
+
+		if clusterName, ok := machine.Labels[clusterv1.ClusterNameLabel]; ok {
+			opts = append(opts, client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName})
+		}
+	}
+
+	machines := &infrav1.MicrovmMachineList{}
+	if err := a.client.List(ctx, machines, opts...); err != nil {
+		return nil, fmt.Errorf("listing microvm machines: %w", err)
+	}
+
+	inUse := map[string]bool{}
+
+	for _, other := range machines.Items {
+		if other.Namespace == machine.Namespace && other.Name == machine.Name {
+			continue
+		}
+
+		for _, iface := range other.Spec.NetworkInterfaces {
+			if iface.GuestMAC != "" {
+				inUse[Normalize(iface.GuestMAC)] = true
+			}
+		}
+	}
+
+	return inUse, nil
+}
+
+func (a *Allocator) generate(inUse map[string]bool) (string, error) {
+	mac := make(net.HardwareAddr, macLength)
+	if _, err := rand.Read(mac); err != nil {
+		return "", fmt.Errorf("generating mac address: %w", err)
+	}
+
+	if len(a.prefix) > 0 {
+		copy(mac, a.prefix)
+	} else {
+		mac[0] = (mac[0] | localBit) &^ multicastBit
+	}
+
+	// Probe from the random address so that a free address is found even if the
+	// range allowed by the prefix is nearly full.
+	suffix := mac[max(len(a.prefix), 1):]
+
+	// A short suffix has fewer addresses than maxProbes, so stop once they've all been tried.
+	probes := maxProbes
+	if bits := 8 * len(suffix); bits < 16 {
+		probes = 1 << bits
+	}
+
+	for attempt := 0; attempt < probes; attempt++ {
+		if !inUse[mac.String()] {
+			return mac.String(), nil
+		}
+
+		increment(suffix)
+	}
+
+	return "", errNoFreeAddress
+}
+
+// increment adds one to the big endian number in b, wrapping on overflow.
+func increment(b []byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+func parsePrefix(prefix string) (net.HardwareAddr, error) {
+	if prefix == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(prefix, ":")
+	if len(parts) > maxPrefixLength {
+		return nil, &invalidPrefixError{prefix: prefix}
+	}
+
+	// Pad the prefix to a full address so that it can be parsed.
+	for len(parts) < macLength {
+		parts = append(parts, "00")
+	}
+
+	mac, err := net.ParseMAC(strings.Join(parts, ":"))
+	if err != nil {
+		return nil, &invalidPrefixError{prefix: prefix}
+	}
+
+	if mac[0]&multicastBit != 0 {
+		return nil, &invalidPrefixError{prefix: prefix}
+	}
+
+	return mac[:len(strings.Split(prefix, ":"))], nil
+}
+
+// Normalize returns the MAC address in its canonical lower case, colon separated form so that
+// addresses written differently can be compared. Invalid addresses are only lower cased.
+func Normalize(mac string) string {
+	parsed, err := net.ParseMAC(mac)
+	if err != nil {
+		return strings.ToLower(mac)
+	}
+
+	return parsed.String()
+}
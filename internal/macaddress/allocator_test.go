@@ -0,0 +1,185 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package macaddress_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/macaddress"
+)
+
+func TestNewAllocator(t *testing.T) {
+	RegisterTestingT(t)
+
+	tt := []struct {
+		name        string
+		cfg         macaddress.Config
+		expectError bool
+	}{
+		{name: "defaults", cfg: macaddress.Config{}},
+		{name: "host scope with prefix", cfg: macaddress.Config{Scope: macaddress.ScopeHost, Prefix: "02:00:5e"}},
+		{name: "invalid scope", cfg: macaddress.Config{Scope: "rack"}, expectError: true},
+		{name: "invalid prefix", cfg: macaddress.Config{Prefix: "zz:00"}, expectError: true},
+		{name: "multicast prefix", cfg: macaddress.Config{Prefix: "01:00:5e"}, expectError: true},
+		{name: "prefix too long", cfg: macaddress.Config{Prefix: "02:00:00:00:00:00"}, expectError: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := macaddress.NewAllocator(createFakeClient(g), tc.cfg)
+			if tc.expectError {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	g := NewWithT(t)
+
+	allocator, err := macaddress.NewAllocator(createFakeClient(g), macaddress.Config{Prefix: "02:00:5e"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	machine := newMachine("machine1", "cluster1", "", "", "02:00:5e:00:00:01")
+	g.Expect(allocator.Allocate(context.TODO(), machine)).To(Succeed())
+
+	ifaces := machine.Spec.NetworkInterfaces
+	g.Expect(ifaces[2].GuestMAC).To(Equal("02:00:5e:00:00:01"), "expect existing mac to be kept")
+	g.Expect(ifaces[0].GuestMAC).To(HavePrefix("02:00:5e:"))
+	g.Expect(ifaces[1].GuestMAC).To(HavePrefix("02:00:5e:"))
+	g.Expect(ifaces[0].GuestMAC).NotTo(Equal(ifaces[1].GuestMAC))
+}
+
+func TestAllocateWithoutPrefix(t *testing.T) {
+	g := NewWithT(t)
+
+	allocator, err := macaddress.NewAllocator(createFakeClient(g), macaddress.Config{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	machine := newMachine("machine1", "cluster1", "")
+	g.Expect(allocator.Allocate(context.TODO(), machine)).To(Succeed())
+
+	mac := machine.Spec.NetworkInterfaces[0].GuestMAC
+	g.Expect(strings.Split(mac, ":")).To(HaveLen(6))
+
+	firstOctet := mac[:2]
+	g.Expect(strings.ContainsAny(firstOctet[1:], "26ae")).To(BeTrue(), "expect a locally administered unicast address, got %s", mac)
+}
+
+func TestAllocateAvoidsMACsInUse(t *testing.T) {
+	g := NewWithT(t)
+
+	// With a 5 octet prefix there are only 256 addresses, so fill all but one of them.
+	existing := []runtime.Object{}
+
+	for i := 0; i < 255; i++ {
+		existing = append(existing, newMachine(fmt.Sprintf("existing-%d", i), "cluster1", fmt.Sprintf("02:00:5e:00:00:%02x", i)))
+	}
+
+	allocator, err := macaddress.NewAllocator(createFakeClient(g, existing...), macaddress.Config{Prefix: "02:00:5e:00:00"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	machine := newMachine("machine1", "cluster1", "")
+	g.Expect(allocator.Allocate(context.TODO(), machine)).To(Succeed())
+	g.Expect(machine.Spec.NetworkInterfaces[0].GuestMAC).To(Equal("02:00:5e:00:00:ff"))
+}
+
+func TestAllocateFullPool(t *testing.T) {
+	g := NewWithT(t)
+
+	// With a 5 octet prefix there are only 256 addresses, so fill all of them.
+	existing := []runtime.Object{}
+
+	for i := 0; i < 256; i++ {
+		existing = append(existing, newMachine(fmt.Sprintf("existing-%d", i), "cluster1", fmt.Sprintf("02:00:5e:00:00:%02x", i)))
+	}
+
+	allocator, err := macaddress.NewAllocator(createFakeClient(g, existing...), macaddress.Config{Prefix: "02:00:5e:00:00"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	machine := newMachine("machine1", "cluster1", "")
+	g.Expect(allocator.Allocate(context.TODO(), machine)).NotTo(Succeed())
+	g.Expect(machine.Spec.NetworkInterfaces[0].GuestMAC).To(BeEmpty())
+}
+
+func TestValidate(t *testing.T) {
+	RegisterTestingT(t)
+
+	existing := []runtime.Object{
+		newMachine("machine1", "cluster1", "02:00:5e:00:00:01"),
+		newMachine("machine2", "cluster2", "02:00:5e:00:00:02"),
+	}
+
+	tt := []struct {
+		name         string
+		scope        macaddress.Scope
+		mac          string
+		expectErrors int
+	}{
+		{name: "cluster scope duplicate in cluster", scope: macaddress.ScopeCluster, mac: "02:00:5E:00:00:01", expectErrors: 1},
+		{name: "cluster scope duplicate in other cluster", scope: macaddress.ScopeCluster, mac: "02:00:5e:00:00:02"},
+		{name: "management scope duplicate in other cluster", scope: macaddress.ScopeManagementCluster, mac: "02:00:5e:00:00:02", expectErrors: 1},
+		{name: "host scope isn't checked", scope: macaddress.ScopeHost, mac: "02:00:5e:00:00:01"},
+		{name: "unique", scope: macaddress.ScopeManagementCluster, mac: "02:00:5e:00:00:03"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			allocator, err := macaddress.NewAllocator(createFakeClient(g, existing...), macaddress.Config{Scope: tc.scope})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			errs, err := allocator.Validate(context.TODO(), newMachine("machine3", "cluster1", tc.mac))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(errs).To(HaveLen(tc.expectErrors))
+		})
+	}
+}
+
+func newMachine(name, clusterName string, macs ...string) *infrav1.MicrovmMachine {
+	machine := &infrav1.MicrovmMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "ns1",
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabel: clusterName,
+			},
+		},
+	}
+
+	for i, mac := range macs {
+		machine.Spec.NetworkInterfaces = append(machine.Spec.NetworkInterfaces, microvm.NetworkInterface{
+			GuestDeviceName: fmt.Sprintf("eth%d", i),
+			GuestMAC:        mac,
+			Type:            microvm.IfaceTypeMacvtap,
+		})
+	}
+
+	return machine
+}
+
+func createFakeClient(g *WithT, objects ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	g.Expect(infrav1.AddToScheme(scheme)).To(Succeed())
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()
+}
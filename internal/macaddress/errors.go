@@ -0,0 +1,30 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package macaddress
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errClientRequired = errors.New("client required to create mac address allocator")
+	errNoFreeAddress  = errors.New("unable to find a free mac address")
+)
+
+type invalidScopeError struct {
+	scope Scope
+}
+
+func (e *invalidScopeError) Error() string {
+	return fmt.Sprintf("invalid mac address scope %q, must be one of cluster, host or management-cluster", e.scope)
+}
+
+type invalidPrefixError struct {
+	prefix string
+}
+
+func (e *invalidPrefixError) Error() string {
+	return fmt.Sprintf("invalid mac address prefix %q, must be 1 to 5 octets of a unicast address (e.g. 02:00:5e)", e.prefix)
+}
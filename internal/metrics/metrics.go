@@ -0,0 +1,192 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package metrics contains the Prometheus metrics exposed by the provider. The metrics are
+// registered with the controller-runtime registry and served by the manager's metrics endpoint.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "capmvm"
+
+	labelNamespace = "namespace"
+	labelCluster   = "cluster"
+	labelHost      = "host"
+	labelEndpoint  = "endpoint"
+	labelSecret    = "secret"
+	labelMethod    = "method"
+	labelCode      = "code"
+	labelState     = "state"
+	labelReason    = "reason"
+)
+
+//nolint:gochecknoglobals // metrics are registered once with the global registry.
+var (
+	// TLSCertificateExpiry is the time the client certificate used to connect to a host expires.
+	TLSCertificateExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tls_certificate_expiry_timestamp_seconds",
+			Help:      "The time the client certificate used to connect to a microvm host expires, in seconds since the epoch.",
+		},
+		[]string{labelNamespace, labelCluster, labelHost, labelSecret},
+	)
+
+	// FlintlockConnections is the number of pooled connections to a host endpoint. The
+	// connections are shared by the clusters that use the endpoint, so they aren't labelled with
+	// a host ID.
+	FlintlockConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "flintlock_connections",
+			Help:      "The number of pooled gRPC connections to a microvm host endpoint.",
+		},
+		[]string{labelEndpoint},
+	)
+
+	// FlintlockConnectionsCreated is the number of connections that have been made to a host
+	// endpoint.
+	FlintlockConnectionsCreated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "flintlock_connections_created_total",
+			Help:      "The number of gRPC connections that have been made to a microvm host endpoint.",
+		},
+		[]string{labelEndpoint},
+	)
+
+	// FlintlockRequestDuration is how long the calls to a host of a cluster take, by method and
+	// gRPC status code.
+	FlintlockRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "flintlock_request_duration_seconds",
+			Help:      "The duration of gRPC calls to a microvm host, by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{labelNamespace, labelCluster, labelHost, labelMethod, labelCode},
+	)
+
+	// HostInfo has the endpoint of each host of a cluster, so the metrics for a host ID can be
+	// joined with the metrics for its endpoint.
+	HostInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "host_info",
+			Help:      "The endpoint of a microvm host of a cluster. The value is always 1.",
+		},
+		[]string{labelNamespace, labelCluster, labelHost, labelEndpoint},
+	)
+
+	// HostReachable is whether a host of a cluster responded the last time it was checked.
+	HostReachable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "host_reachable",
+			Help:      "Whether a microvm host of a cluster responded the last time it was checked (1) or not (0).",
+		},
+		[]string{labelNamespace, labelCluster, labelHost},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		TLSCertificateExpiry,
+		FlintlockConnections,
+		FlintlockConnectionsCreated,
+		FlintlockRequestDuration,
+		HostReachable,
+		HostInfo,
+		MicrovmCreateDuration,
+		Microvms,
+		MicrovmErrors,
+	)
+}
+
+// SetTLSCertificateExpiry records when the client certificate used to connect to a host expires.
+func SetTLSCertificateExpiry(clusterNamespace, clusterName, host, secret string, notAfter time.Time) {
+	TLSCertificateExpiry.DeletePartialMatch(prometheus.Labels{
+		labelNamespace: clusterNamespace,
+		labelCluster:   clusterName,
+		labelHost:      host,
+	})
+	TLSCertificateExpiry.WithLabelValues(clusterNamespace, clusterName, host, secret).Set(float64(notAfter.Unix()))
+}
+
+// SetFlintlockConnections records the number of pooled connections to a host endpoint. The
+// endpoint is removed from the metric when there are no connections.
+func SetFlintlockConnections(endpoint string, count int) {
+	if count == 0 {
+		FlintlockConnections.DeleteLabelValues(endpoint)
+
+		return
+	}
+
+	FlintlockConnections.WithLabelValues(endpoint).Set(float64(count))
+}
+
+// IncFlintlockConnectionsCreated records that a new connection has been made to a host endpoint.
+func IncFlintlockConnectionsCreated(endpoint string) {
+	FlintlockConnectionsCreated.WithLabelValues(endpoint).Inc()
+}
+
+// ObserveFlintlockRequest records how long a call to a host of a cluster took. The code is the
+// gRPC status code of the call, which is OK if it succeeded.
+func ObserveFlintlockRequest(clusterNamespace, clusterName, host, method, code string, duration time.Duration) {
+	FlintlockRequestDuration.WithLabelValues(clusterNamespace, clusterName, host, method, code).Observe(duration.Seconds())
+}
+
+// SetHostReachable records whether a host of a cluster responded the last time it was checked.
+func SetHostReachable(clusterNamespace, clusterName, host string, reachable bool) {
+	value := 0.0
+	if reachable {
+		value = 1
+	}
+
+	HostReachable.WithLabelValues(clusterNamespace, clusterName, host).Set(value)
+}
+
+// SetHostInfo records the endpoint of a host of a cluster, replacing its previous endpoint.
+func SetHostInfo(clusterNamespace, clusterName, host, endpoint string) {
+	HostInfo.DeletePartialMatch(prometheus.Labels{
+		labelNamespace: clusterNamespace,
+		labelCluster:   clusterName,
+		labelHost:      host,
+	})
+	HostInfo.WithLabelValues(clusterNamespace, clusterName, host, endpoint).Set(1)
+}
+
+// DeleteHost removes the metrics of a host that is no longer in the pool of a cluster.
+func DeleteHost(clusterNamespace, clusterName, host string) {
+	labels := prometheus.Labels{
+		labelNamespace: clusterNamespace,
+		labelCluster:   clusterName,
+		labelHost:      host,
+	}
+
+	HostReachable.DeletePartialMatch(labels)
+	HostInfo.DeletePartialMatch(labels)
+	FlintlockRequestDuration.DeletePartialMatch(labels)
+}
+
+// DeleteClusterMetrics removes all the metrics for a cluster.
+func DeleteClusterMetrics(clusterNamespace, clusterName string) {
+	labels := prometheus.Labels{
+		labelNamespace: clusterNamespace,
+		labelCluster:   clusterName,
+	}
+
+	TLSCertificateExpiry.DeletePartialMatch(labels)
+	HostReachable.DeletePartialMatch(labels)
+	HostInfo.DeletePartialMatch(labels)
+	FlintlockRequestDuration.DeletePartialMatch(labels)
+	MicrovmCreateDuration.DeletePartialMatch(labels)
+	MicrovmErrors.DeletePartialMatch(labels)
+	microvmStates.deleteCluster(clusterNamespace, clusterName)
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/metrics"
+)
+
+func TestSetTLSCertificateExpiry(t *testing.T) {
+	RegisterTestingT(t)
+
+	notAfter := time.Now().Add(time.Hour)
+	rotated := notAfter.Add(24 * time.Hour)
+
+	metrics.SetTLSCertificateExpiry("ns1", "cluster1", "host1:9090", "old-tls", notAfter)
+	metrics.SetTLSCertificateExpiry("ns1", "cluster1", "host1:9090", "new-tls", rotated)
+	metrics.SetTLSCertificateExpiry("ns1", "cluster2", "host1:9090", "new-tls", rotated)
+
+	Expect(testutil.CollectAndCount(metrics.TLSCertificateExpiry)).To(Equal(2), "expect the old secret to be replaced")
+	Expect(testutil.ToFloat64(metrics.TLSCertificateExpiry.WithLabelValues("ns1", "cluster1", "host1:9090", "new-tls"))).
+		To(BeNumerically("==", rotated.Unix()))
+
+	metrics.DeleteClusterMetrics("ns1", "cluster1")
+
+	Expect(testutil.CollectAndCount(metrics.TLSCertificateExpiry)).To(Equal(1))
+}
+
+func TestSetMicrovmState(t *testing.T) {
+	RegisterTestingT(t)
+
+	metrics.SetMicrovmState("ns1", "cluster1", "machine1", "host1", "pending")
+	metrics.SetMicrovmState("ns1", "cluster1", "machine2", "host1", "pending")
+	metrics.SetMicrovmState("ns1", "cluster1", "machine3", "host2", "created")
+
+	Expect(testutil.ToFloat64(metrics.Microvms.WithLabelValues("ns1", "cluster1", "host1", "pending"))).To(BeNumerically("==", 2))
+	Expect(testutil.ToFloat64(metrics.Microvms.WithLabelValues("ns1", "cluster1", "host2", "created"))).To(BeNumerically("==", 1))
+
+	metrics.SetMicrovmState("ns1", "cluster1", "machine1", "host1", "created")
+	metrics.SetMicrovmState("ns1", "cluster1", "machine1", "host1", "created")
+
+	Expect(testutil.ToFloat64(metrics.Microvms.WithLabelValues("ns1", "cluster1", "host1", "pending"))).To(BeNumerically("==", 1))
+	Expect(testutil.ToFloat64(metrics.Microvms.WithLabelValues("ns1", "cluster1", "host1", "created"))).To(BeNumerically("==", 1))
+
+	metrics.DeleteMicrovm("ns1", "machine2")
+
+	Expect(testutil.CollectAndCount(metrics.Microvms)).To(Equal(2), "expect states without microvms to be removed")
+
+	metrics.DeleteClusterMetrics("ns1", "cluster1")
+
+	Expect(testutil.CollectAndCount(metrics.Microvms)).To(Equal(0))
+
+	metrics.SetMicrovmState("ns1", "cluster1", "machine1", "host1", "created")
+	Expect(testutil.ToFloat64(metrics.Microvms.WithLabelValues("ns1", "cluster1", "host1", "created"))).
+		To(BeNumerically("==", 1), "expect the machines of a deleted cluster to be forgotten")
+
+	metrics.DeleteClusterMetrics("ns1", "cluster1")
+}
+
+func TestMicrovmLifecycleMetrics(t *testing.T) {
+	RegisterTestingT(t)
+
+	metrics.ObserveMicrovmCreated("ns1", "cluster1", "host1", 20*time.Second)
+	metrics.ObserveMicrovmCreated("ns1", "cluster1", "host1", -time.Second)
+	metrics.IncMicrovmErrors("ns1", "cluster1", "host1", "MicrovmProvisionFailed")
+	metrics.SetHostReachable("ns1", "cluster1", "host1", true)
+	metrics.SetHostReachable("ns1", "cluster1", "host2", false)
+	metrics.SetHostInfo("ns1", "cluster1", "host1", "10.0.0.1:9090")
+	metrics.SetHostInfo("ns1", "cluster1", "host2", "10.0.0.2:9090")
+	metrics.SetHostInfo("ns1", "cluster1", "host2", "10.0.0.3:9090")
+	metrics.ObserveFlintlockRequest("ns1", "cluster1", "host2", "GetMicroVM", "OK", time.Second)
+
+	Expect(testutil.CollectAndCount(metrics.MicrovmCreateDuration)).To(Equal(1))
+	Expect(testutil.ToFloat64(metrics.MicrovmErrors.WithLabelValues("ns1", "cluster1", "host1", "MicrovmProvisionFailed"))).
+		To(BeNumerically("==", 1))
+	Expect(testutil.ToFloat64(metrics.HostReachable.WithLabelValues("ns1", "cluster1", "host1"))).To(BeNumerically("==", 1))
+	Expect(testutil.ToFloat64(metrics.HostReachable.WithLabelValues("ns1", "cluster1", "host2"))).To(BeNumerically("==", 0))
+	Expect(testutil.CollectAndCount(metrics.HostInfo)).To(Equal(2), "expect the old endpoint of host2 to be replaced")
+	Expect(testutil.ToFloat64(metrics.HostInfo.WithLabelValues("ns1", "cluster1", "host2", "10.0.0.3:9090"))).To(BeNumerically("==", 1))
+
+	metrics.DeleteHost("ns1", "cluster1", "host2")
+	Expect(testutil.CollectAndCount(metrics.HostReachable)).To(Equal(1))
+	Expect(testutil.CollectAndCount(metrics.HostInfo)).To(Equal(1))
+	Expect(testutil.CollectAndCount(metrics.FlintlockRequestDuration)).To(Equal(0))
+
+	metrics.DeleteClusterMetrics("ns1", "cluster1")
+
+	Expect(testutil.CollectAndCount(metrics.MicrovmCreateDuration)).To(Equal(0))
+	Expect(testutil.CollectAndCount(metrics.MicrovmErrors)).To(Equal(0))
+	Expect(testutil.CollectAndCount(metrics.HostReachable)).To(Equal(0))
+	Expect(testutil.CollectAndCount(metrics.HostInfo)).To(Equal(0))
+}
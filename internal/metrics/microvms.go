@@ -0,0 +1,162 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//nolint:gochecknoglobals // metrics are registered once with the global registry.
+var (
+	// MicrovmCreateDuration is the time from a microvm being created on its host to the machine
+	// controller seeing it in the CREATED state.
+	MicrovmCreateDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "microvm_create_duration_seconds",
+			Help:      "The time from a microvm being created on its host to it being seen in the CREATED state.",
+			Buckets:   prometheus.ExponentialBuckets(5, 2, 10), //nolint:gomnd // 5s to ~43m.
+		},
+		[]string{labelNamespace, labelCluster, labelHost},
+	)
+
+	// Microvms is the number of microvms in each state on a host.
+	Microvms = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "microvms",
+			Help:      "The number of microvms in each state on a microvm host.",
+		},
+		[]string{labelNamespace, labelCluster, labelHost, labelState},
+	)
+
+	// MicrovmErrors is the number of times a microvm stopped being ready because of an error,
+	// by the reason of its MicrovmReady condition.
+	MicrovmErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "microvm_errors_total",
+			Help:      "The number of times a microvm stopped being ready because of an error, by the reason of its MicrovmReady condition.",
+		},
+		[]string{labelNamespace, labelCluster, labelHost, labelReason},
+	)
+
+	microvmStates = &stateTracker{machines: map[machineKey]stateLabels{}}
+)
+
+// ObserveMicrovmCreated records how long a microvm took to reach the CREATED state.
+func ObserveMicrovmCreated(clusterNamespace, clusterName, host string, duration time.Duration) {
+	if duration < 0 {
+		duration = 0
+	}
+
+	MicrovmCreateDuration.WithLabelValues(clusterNamespace, clusterName, host).Observe(duration.Seconds())
+}
+
+// SetMicrovmState records the state, and host, of the microvm of a machine.
+func SetMicrovmState(clusterNamespace, clusterName, machineName, host, state string) {
+	microvmStates.set(
+		machineKey{namespace: clusterNamespace, name: machineName},
+		stateLabels{cluster: clusterName, host: host, state: state},
+	)
+}
+
+// DeleteMicrovm removes the microvm of a machine from the count of microvms.
+func DeleteMicrovm(clusterNamespace, machineName string) {
+	microvmStates.delete(machineKey{namespace: clusterNamespace, name: machineName})
+}
+
+// IncMicrovmErrors records that a microvm stopped being ready because of an error.
+func IncMicrovmErrors(clusterNamespace, clusterName, host, reason string) {
+	MicrovmErrors.WithLabelValues(clusterNamespace, clusterName, host, reason).Inc()
+}
+
+type machineKey struct {
+	namespace string
+	name      string
+}
+
+type stateLabels struct {
+	cluster string
+	host    string
+	state   string
+}
+
+// stateTracker keeps the state of the microvm of each machine so the number of microvms in
+// each state can be updated when the state of one changes.
+type stateTracker struct {
+	mu       sync.Mutex
+	machines map[machineKey]stateLabels
+}
+
+func (t *stateTracker) set(key machineKey, labels stateLabels) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.machines[key]
+	if ok && previous == labels {
+		return
+	}
+
+	t.machines[key] = labels
+
+	if ok {
+		t.update(key.namespace, previous)
+	}
+
+	t.update(key.namespace, labels)
+}
+
+func (t *stateTracker) delete(key machineKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.machines[key]
+	if !ok {
+		return
+	}
+
+	delete(t.machines, key)
+	t.update(key.namespace, previous)
+}
+
+func (t *stateTracker) deleteCluster(clusterNamespace, clusterName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, labels := range t.machines {
+		if key.namespace == clusterNamespace && labels.cluster == clusterName {
+			delete(t.machines, key)
+		}
+	}
+
+	Microvms.DeletePartialMatch(prometheus.Labels{
+		labelNamespace: clusterNamespace,
+		labelCluster:   clusterName,
+	})
+}
+
+// update sets the number of microvms with the labels. Labels without any microvms are removed
+// so hosts that are taken out of the pool don't leave series behind. It must be called with
+// the lock held.
+func (t *stateTracker) update(clusterNamespace string, labels stateLabels) {
+	count := 0
+
+	for key, other := range t.machines {
+		if key.namespace == clusterNamespace && other == labels {
+			count++
+		}
+	}
+
+	if count == 0 {
+		Microvms.DeleteLabelValues(clusterNamespace, labels.cluster, labels.host, labels.state)
+
+		return
+	}
+
+	Microvms.WithLabelValues(clusterNamespace, labels.cluster, labels.host, labels.state).Set(float64(count))
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+// BootstrapFormat is the format of the bootstrap data created by the bootstrap provider.
+type BootstrapFormat string
+
+const (
+	// BootstrapFormatCloudConfig is cloud-init configuration, as created by the kubeadm bootstrap provider.
+	BootstrapFormatCloudConfig = BootstrapFormat("cloud-config")
+	// BootstrapFormatTalos is a Talos machine configuration, as created by the Talos bootstrap provider.
+	BootstrapFormatTalos = BootstrapFormat("talos")
+
+	bootstrapFormatKey = "format"
+	kubeadmInitCommand = "kubeadm init"
+)
+
+// BootstrapDelivery is a mechanism used to deliver the bootstrap data to the microvm. There is
+// no config volume delivery as flintlock can't create a volume from the bootstrap data.
+type BootstrapDelivery interface {
+	// Name returns the name of the delivery mechanism.
+	Name() string
+	// Mutators returns the changes that need to be made to the microvm spec so that
+	// the guest picks up the bootstrap data.
+	Mutators() []flintlock.SpecMutator
+}
+
+// GetBootstrapFormat returns the format of the bootstrap data. This is read from the
+// format key of the bootstrap secret and defaults to cloud-config if not set.
+func (m *MachineScope) GetBootstrapFormat() (BootstrapFormat, error) {
+	bootstrapSecret, err := m.getBootstrapSecret()
+	if err != nil {
+		return "", err
+	}
+
+	format := BootstrapFormat(bootstrapSecret.Data[bootstrapFormatKey])
+	if format == "" {
+		return BootstrapFormatCloudConfig, nil
+	}
+
+	return format, nil
+}
+
+// GetBootstrapDelivery returns the delivery mechanism that should be used for the
+// format of the machines bootstrap data.
+func (m *MachineScope) GetBootstrapDelivery() (BootstrapDelivery, error) {
+	format, err := m.GetBootstrapFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	networkConfig := m.GetGuestNetworkConfig()
+
+	switch format {
+	case BootstrapFormatCloudConfig:
+		files, commands, err := m.GetLoadBalancerUserData()
+		if err != nil {
+			return nil, err
+		}
+
+		return &cloudInitDelivery{files: files, commands: commands, networkConfig: networkConfig}, nil
+	case BootstrapFormatTalos:
+		return &kernelCmdlineDelivery{
+			args: map[string]string{
+				flintlock.TalosPlatformKernelArg: flintlock.TalosPlatformNoCloud,
+			},
+			networkConfig: networkConfig,
+		}, nil
+	default:
+		return nil, &unsupportedBootstrapFormatError{format: format}
+	}
+}
+
+// cloudInitDelivery delivers the bootstrap data as cloud-init NoCloud user-data. The
+// microvm service already adds the user-data to the metadata so the only things to do
+// are add the network-config and any extra files and commands the provider needs in the guest.
+type cloudInitDelivery struct {
+	files         []flintlock.File
+	commands      []string
+	networkConfig []flintlock.InterfaceConfig
+}
+
+func (d *cloudInitDelivery) Name() string {
+	return "cloud-init"
+}
+
+func (d *cloudInitDelivery) Mutators() []flintlock.SpecMutator {
+	mutators := []flintlock.SpecMutator{
+		flintlock.WithNetworkConfig(d.networkConfig...),
+	}
+
+	if len(d.files) > 0 {
+		mutators = append(mutators, flintlock.WithUserDataFiles(d.files...))
+	}
+
+	if len(d.commands) > 0 {
+		mutators = append(mutators, flintlock.WithUserDataCommands(d.commands...))
+	}
+
+	return mutators
+}
+
+// kernelCmdlineDelivery delivers the bootstrap data to guests that don't run cloud-init. The
+// bootstrap data is still supplied as NoCloud user-data but the guest is told where to find it
+// using kernel arguments. The NoCloud network-config is also supplied as it's read by the guest.
+type kernelCmdlineDelivery struct {
+	args          map[string]string
+	networkConfig []flintlock.InterfaceConfig
+}
+
+func (d *kernelCmdlineDelivery) Name() string {
+	return "kernel-cmdline"
+}
+
+func (d *kernelCmdlineDelivery) Mutators() []flintlock.SpecMutator {
+	return []flintlock.SpecMutator{
+		flintlock.WithKernelArgs(d.args),
+		flintlock.WithoutVendorData(),
+		flintlock.WithNetworkConfig(d.networkConfig...),
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestMachineGetBootstrapDelivery(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	tt := []struct {
+		name             string
+		secretData       map[string][]byte
+		expectedFormat   scope.BootstrapFormat
+		expectedDelivery string
+		expectedCmdline  map[string]string
+		expectedErr      bool
+	}{
+		{
+			name:             "no format defaults to cloud-init",
+			secretData:       map[string][]byte{"value": []byte("#cloud-config")},
+			expectedFormat:   scope.BootstrapFormatCloudConfig,
+			expectedDelivery: "cloud-init",
+			expectedCmdline:  map[string]string{},
+		},
+		{
+			name:             "cloud-config format uses cloud-init",
+			secretData:       map[string][]byte{"value": []byte("#cloud-config"), "format": []byte("cloud-config")},
+			expectedFormat:   scope.BootstrapFormatCloudConfig,
+			expectedDelivery: "cloud-init",
+			expectedCmdline:  map[string]string{},
+		},
+		{
+			name:             "talos format uses the kernel cmdline",
+			secretData:       map[string][]byte{"value": []byte("version: v1alpha1"), "format": []byte("talos")},
+			expectedFormat:   scope.BootstrapFormatTalos,
+			expectedDelivery: "kernel-cmdline",
+			expectedCmdline:  map[string]string{"talos.platform": "nocloud"},
+		},
+		{
+			name:        "unsupported format errors",
+			secretData:  map[string][]byte{"value": []byte("{}"), "format": []byte("ignition")},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			cluster := newCluster(clusterName, []string{"fd1"})
+			mvmCluster := newMicrovmCluster(clusterName)
+			machine := newMachine(clusterName, machineName)
+			mvmMachine := newMicrovmMachine(clusterName, machineName, "")
+			secret := newSecret(machineName, tc.secretData)
+
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{
+				cluster, mvmCluster, machine, mvmMachine, secret,
+			}...).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        cluster,
+				MicroVMCluster: mvmCluster,
+				Machine:        machine,
+				MicroVMMachine: mvmMachine,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			delivery, err := machineScope.GetBootstrapDelivery()
+			if tc.expectedErr {
+				Expect(err).To(HaveOccurred())
+
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(delivery.Name()).To(Equal(tc.expectedDelivery))
+
+			format, err := machineScope.GetBootstrapFormat()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(format).To(Equal(tc.expectedFormat))
+
+			spec := &flintlocktypes.MicroVMSpec{
+				Kernel:   &flintlocktypes.Kernel{Cmdline: map[string]string{}},
+				Metadata: map[string]string{"user-data": "", "vendor-data": ""},
+			}
+			for _, mutate := range delivery.Mutators() {
+				Expect(mutate(spec)).To(Succeed())
+			}
+			Expect(spec.Kernel.Cmdline).To(Equal(tc.expectedCmdline))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+// GetCallPolicy returns the timeouts and retries of the calls to the hosts of the cluster. The
+// defaults are used for anything that isn't set on the MicrovmCluster.
+func (m *MachineScope) GetCallPolicy(defaults flintlock.CallPolicy) flintlock.CallPolicy {
+	return callPolicy(m.MvmCluster, defaults)
+}
+
+// GetCallPolicy returns the timeouts and retries of the calls to the hosts of the cluster. The
+// defaults are used for anything that isn't set on the MicrovmCluster.
+func (cs *ClusterScope) GetCallPolicy(defaults flintlock.CallPolicy) flintlock.CallPolicy {
+	return callPolicy(cs.MvmCluster, defaults)
+}
+
+func callPolicy(mvmCluster *infrav1.MicrovmCluster, defaults flintlock.CallPolicy) flintlock.CallPolicy {
+	calls := mvmCluster.Spec.FlintlockCalls
+	if calls == nil {
+		return defaults
+	}
+
+	policy := defaults
+
+	overrideDuration(&policy.CreateTimeout, calls.CreateTimeout)
+	overrideDuration(&policy.DeleteTimeout, calls.DeleteTimeout)
+	overrideDuration(&policy.GetTimeout, calls.GetTimeout)
+	overrideDuration(&policy.ListTimeout, calls.ListTimeout)
+	overrideDuration(&policy.RetryBackoff, calls.RetryBackoff)
+
+	if calls.Retries != nil {
+		policy.Retries = int(*calls.Retries)
+	}
+
+	return policy
+}
+
+func overrideDuration(target *time.Duration, override *metav1.Duration) {
+	if override != nil {
+		*target = override.Duration
+	}
+}
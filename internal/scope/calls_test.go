@@ -0,0 +1,112 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestMachineGetCallPolicy(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	defaults := flintlock.CallPolicy{
+		CreateTimeout: 30 * time.Second,
+		DeleteTimeout: 30 * time.Second,
+		GetTimeout:    10 * time.Second,
+		ListTimeout:   30 * time.Second,
+		Retries:       2,
+		RetryBackoff:  time.Second,
+	}
+
+	tt := []struct {
+		name     string
+		calls    *infrav1.FlintlockCallsSpec
+		expected flintlock.CallPolicy
+	}{
+		{
+			name:     "no overrides",
+			calls:    nil,
+			expected: defaults,
+		},
+		{
+			name: "some overrides",
+			calls: &infrav1.FlintlockCallsSpec{
+				GetTimeout: &metav1.Duration{Duration: 2 * time.Second},
+				Retries:    pointer.Int32(0),
+			},
+			expected: flintlock.CallPolicy{
+				CreateTimeout: 30 * time.Second,
+				DeleteTimeout: 30 * time.Second,
+				GetTimeout:    2 * time.Second,
+				ListTimeout:   30 * time.Second,
+				Retries:       0,
+				RetryBackoff:  time.Second,
+			},
+		},
+		{
+			name: "all overrides",
+			calls: &infrav1.FlintlockCallsSpec{
+				CreateTimeout: &metav1.Duration{Duration: time.Minute},
+				DeleteTimeout: &metav1.Duration{Duration: 2 * time.Minute},
+				GetTimeout:    &metav1.Duration{Duration: 5 * time.Second},
+				ListTimeout:   &metav1.Duration{Duration: 15 * time.Second},
+				Retries:       pointer.Int32(5),
+				RetryBackoff:  &metav1.Duration{Duration: 200 * time.Millisecond},
+			},
+			expected: flintlock.CallPolicy{
+				CreateTimeout: time.Minute,
+				DeleteTimeout: 2 * time.Minute,
+				GetTimeout:    5 * time.Second,
+				ListTimeout:   15 * time.Second,
+				Retries:       5,
+				RetryBackoff:  200 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := newCluster(clusterName, nil)
+			mvmCluster := newMicrovmClusterWithSpec(clusterName, infrav1.MicrovmClusterSpec{
+				Placement: infrav1.Placement{
+					StaticPool: &infrav1.StaticPoolPlacement{
+						Hosts: []infrav1.MicrovmHost{{Endpoint: "10.0.0.1:9090"}},
+					},
+				},
+				FlintlockCalls: tc.calls,
+			})
+			machine := newMachine(clusterName, machineName)
+			mvmMachine := newMicrovmMachine(clusterName, machineName, "")
+
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, mvmCluster, machine, mvmMachine).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        cluster,
+				MicroVMCluster: mvmCluster,
+				Machine:        machine,
+				MicroVMMachine: mvmMachine,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(machineScope.GetCallPolicy(defaults)).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/version"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+)
+
+// hostIncompatibility returns why the host can't create the microvm of the machine, or an empty
+// string if it can. Hosts without declared capabilities are assumed to have all providers, but
+// must have every network the machine's interfaces are connected to. If the fields of the microvm
+// spec in the API of the host have been discovered, the host must have the fields the machine
+// needs, as older versions of flintlock ignore the fields they don't have.
+func hostIncompatibility(
+	host *infrav1.MicrovmHost,
+	status *infrav1.HostStatus,
+	mvmMachine *infrav1.MicrovmMachine,
+) string {
+	capabilities := host.Capabilities
+	if capabilities == nil {
+		capabilities = &infrav1.HostCapabilities{}
+	}
+
+	provider := mvmMachine.Spec.Provider
+	if provider != "" && len(capabilities.Providers) > 0 && !slices.Contains(capabilities.Providers, provider) {
+		return fmt.Sprintf("provider %s isn't available", provider)
+	}
+
+	if provider != "" && status != nil && len(status.MicrovmSpecFields) > 0 &&
+		!slices.Contains(status.MicrovmSpecFields, flintlock.MicrovmSpecProviderField) {
+		return "flintlock doesn't support choosing the provider"
+	}
+
+	for _, cfg := range mvmMachine.Spec.NetworkInterfaceConfigs {
+		if cfg.Network == "" {
+			continue
+		}
+
+		hasNetwork := slices.ContainsFunc(host.Networks, func(network infrav1.HostNetwork) bool {
+			return network.Name == cfg.Network
+		})
+		if !hasNetwork {
+			return fmt.Sprintf("network %s isn't available", cfg.Network)
+		}
+	}
+
+	minVersion := mvmMachine.Spec.MinFlintlockVersion
+	if minVersion == "" {
+		return ""
+	}
+
+	if capabilities.Version == "" {
+		return "flintlock version isn't declared"
+	}
+
+	required, err := version.ParseGeneric(minVersion)
+	if err != nil {
+		return fmt.Sprintf("minimum flintlock version %s is invalid", minVersion)
+	}
+
+	hostVersion, err := version.ParseGeneric(capabilities.Version)
+	if err != nil {
+		return fmt.Sprintf("flintlock version %s is invalid", capabilities.Version)
+	}
+
+	if !hostVersion.AtLeast(required) {
+		return fmt.Sprintf("flintlock version %s is older than %s", capabilities.Version, minVersion)
+	}
+
+	return ""
+}
+
+// incompatibility returns why the host in the failure domain can't create the microvm of the
+// machine, or an empty string if it can. Hosts that aren't in the placement of the cluster are
+// assumed to be compatible.
+func (m *MachineScope) incompatibility(failureDomain string) string {
+	host := m.GetHost(failureDomain)
+	if host == nil {
+		return ""
+	}
+
+	var status *infrav1.HostStatus
+
+	for i := range m.MvmCluster.Status.Hosts {
+		if m.MvmCluster.Status.Hosts[i].ID == host.ID() {
+			status = &m.MvmCluster.Status.Hosts[i]
+		}
+	}
+
+	return hostIncompatibility(host, status, m.MvmMachine)
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestMachineGetFailureDomainCapabilities(t *testing.T) {
+	scheme, err := setupScheme()
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	placement := infrav1.Placement{
+		StaticPool: &infrav1.StaticPoolPlacement{
+			Hosts: []infrav1.MicrovmHost{
+				{
+					Name:     "host1",
+					Endpoint: "10.0.0.1:9090",
+					Capabilities: &infrav1.HostCapabilities{
+						Version:   "v0.7.0",
+						Providers: []string{"firecracker"},
+					},
+				},
+				{
+					Name:     "host2",
+					Endpoint: "10.0.0.2:9090",
+					Capabilities: &infrav1.HostCapabilities{
+						Version:   "v0.8.1",
+						Providers: []string{"firecracker", "cloudhypervisor"},
+					},
+				},
+				{
+					Name:     "host3",
+					Endpoint: "10.0.0.3:9090",
+					Networks: []infrav1.HostNetwork{{Name: "storage", BridgeName: "br-storage"}},
+				},
+				{
+					Name:     "host4",
+					Endpoint: "10.0.0.4:9090",
+				},
+			},
+		},
+	}
+
+	// The API of host4 was discovered to be from a version of flintlock without the provider field.
+	hostStatuses := []infrav1.HostStatus{
+		{ID: "host3", Endpoint: "10.0.0.3:9090", MicrovmSpecFields: []string{"id", "vcpu", "provider"}},
+		{ID: "host4", Endpoint: "10.0.0.4:9090", MicrovmSpecFields: []string{"id", "vcpu"}},
+	}
+
+	tt := []struct {
+		name          string
+		failureDomain string
+		providerID    string
+		provider      string
+		minVersion    string
+		network       string
+		expected      string
+		expectedErr   error
+	}{
+		{
+			name:          "compatible failure domain of the machine",
+			failureDomain: "host1",
+			provider:      "firecracker",
+			expected:      "host1",
+		},
+		{
+			name:          "incompatible failure domain of the machine",
+			failureDomain: "host1",
+			provider:      "cloudhypervisor",
+			minVersion:    "v0.8.0",
+			expectedErr:   scope.ErrNoCompatibleHost,
+		},
+		{
+			name:          "host without capabilities has all providers",
+			failureDomain: "host3",
+			provider:      "cloudhypervisor",
+			expected:      "host3",
+		},
+		{
+			name:          "host without the provider field in its api",
+			failureDomain: "host4",
+			provider:      "firecracker",
+			expectedErr:   scope.ErrNoCompatibleHost,
+		},
+		{
+			name:          "host without the provider field in its api and no provider",
+			failureDomain: "host4",
+			expected:      "host4",
+		},
+		{
+			name:       "minimum version",
+			minVersion: "0.8.0",
+			expected:   "host2",
+		},
+		{
+			name:     "host with the network",
+			network:  "storage",
+			expected: "host3",
+		},
+		{
+			name:        "no host with the network",
+			network:     "backup",
+			expectedErr: scope.ErrNoCompatibleHost,
+		},
+		{
+			name:          "existing microvm isn't moved",
+			failureDomain: "host1",
+			providerID:    "microvm://host1/abcdef",
+			provider:      "cloudhypervisor",
+			expected:      "host1",
+		},
+		{
+			name:        "no compatible host",
+			provider:    "cloudhypervisor",
+			minVersion:  "v0.9.0",
+			expectedErr: scope.ErrNoCompatibleHost,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cluster := newCluster(clusterName, []string{"host1", "host2", "host3", "host4"})
+			mvmCluster := newMicrovmClusterWithSpec(clusterName, infrav1.MicrovmClusterSpec{Placement: placement})
+			mvmCluster.Status.Hosts = hostStatuses
+			machine := newMachine(clusterName, machineName)
+			mvmMachine := newMicrovmMachine(clusterName, machineName, tc.providerID)
+			mvmMachine.Spec.Provider = tc.provider
+			mvmMachine.Spec.MinFlintlockVersion = tc.minVersion
+
+			if tc.network != "" {
+				mvmMachine.Spec.NetworkInterfaceConfigs = []infrav1.NetworkInterfaceConfig{
+					{GuestDeviceName: "eth0", Network: tc.network},
+				}
+			}
+
+			if tc.failureDomain != "" {
+				machine.Spec.FailureDomain = pointer.String(tc.failureDomain)
+			}
+
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, mvmCluster, machine, mvmMachine).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        cluster,
+				MicroVMCluster: mvmCluster,
+				Machine:        machine,
+				MicroVMMachine: mvmMachine,
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			failureDomain, err := machineScope.GetFailureDomain()
+			if tc.expectedErr != nil {
+				g.Expect(errors.Is(err, tc.expectedErr)).To(BeTrue(), "expected %v, got %v", tc.expectedErr, err)
+
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(failureDomain).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/defaults"
+)
+
+const (
+	certManagerGroup       = "cert-manager.io"
+	defaultIssuerKind      = "Issuer"
+	clientCertificateReady = "Ready"
+)
+
+// CertificateGVK is the GroupVersionKind of a cert-manager Certificate. The cert-manager API
+// isn't imported so Certificates are handled as unstructured objects.
+var CertificateGVK = schema.GroupVersionKind{
+	Group:   certManagerGroup,
+	Version: "v1",
+	Kind:    "Certificate",
+}
+
+// ClientCertificateName returns the name of the cert-manager Certificate, and the secret it
+// creates, for the client certificate of the MicrovmCluster.
+func ClientCertificateName(mvmCluster *infrav1.MicrovmCluster) string {
+	return fmt.Sprintf("%s-flintlock-client", mvmCluster.Name)
+}
+
+// ReconcileClientCertificate makes sure there is a cert-manager Certificate matching the client
+// certificate configuration of the cluster. It returns true once cert-manager reports the
+// certificate as ready, which means its secret can be used to connect to the hosts.
+func (cs *ClusterScope) ReconcileClientCertificate(ctx context.Context) (bool, error) {
+	if cs.MvmCluster.Spec.ClientCertificate == nil {
+		return true, nil
+	}
+
+	desiredSpec := clientCertificateSpec(cs.MvmCluster, cs.ClusterName())
+
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(CertificateGVK)
+
+	key := types.NamespacedName{Namespace: cs.Namespace(), Name: ClientCertificateName(cs.MvmCluster)}
+
+	err := cs.client.Get(ctx, key, cert)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("getting certificate %s: %w", key, err)
+	}
+
+	if apierrors.IsNotFound(err) {
+		cert.SetName(key.Name)
+		cert.SetNamespace(key.Namespace)
+		cert.SetLabels(map[string]string{
+			clusterv1.ClusterNameLabel: cs.ClusterName(),
+		})
+		cert.SetOwnerReferences([]metav1.OwnerReference{
+			*metav1.NewControllerRef(cs.MvmCluster, infrav1.GroupVersion.WithKind("MicrovmCluster")),
+		})
+		cert.Object["spec"] = desiredSpec
+
+		cs.Info("creating client certificate", "certificate", key.Name)
+
+		if err := cs.client.Create(ctx, cert); err != nil {
+			return false, fmt.Errorf("creating certificate %s: %w", key, err)
+		}
+
+		return false, nil
+	}
+
+	// Only the fields set by the provider are compared so that fields defaulted by cert-manager
+	// don't cause the Certificate to be updated on every reconcile.
+	spec, _, _ := unstructured.NestedMap(cert.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+
+	updatedSpec := runtime.DeepCopyJSON(spec)
+	for k, v := range desiredSpec {
+		updatedSpec[k] = v
+	}
+
+	if _, ok := desiredSpec["subject"]; !ok {
+		delete(updatedSpec, "subject")
+	}
+
+	if _, ok := desiredSpec["duration"]; !ok {
+		delete(updatedSpec, "duration")
+	}
+
+	if _, ok := desiredSpec["renewBefore"]; !ok {
+		delete(updatedSpec, "renewBefore")
+	}
+
+	if !equality.Semantic.DeepEqual(spec, updatedSpec) {
+		cert.Object["spec"] = updatedSpec
+
+		cs.Info("updating client certificate", "certificate", key.Name)
+
+		if err := cs.client.Update(ctx, cert); err != nil {
+			return false, fmt.Errorf("updating certificate %s: %w", key, err)
+		}
+
+		return false, nil
+	}
+
+	ready := isCertificateReady(cert)
+	if !ready {
+		cs.V(defaults.LogLevelDebug).Info("client certificate not ready yet", "certificate", key.Name)
+	}
+
+	return ready, nil
+}
+
+// clientCertificateSpec returns the fields of the cert-manager Certificate spec that are set by
+// the provider. Only types that survive a round trip through JSON are used so that they can be
+// compared with the spec of an existing Certificate.
+func clientCertificateSpec(mvmCluster *infrav1.MicrovmCluster, clusterName string) map[string]interface{} {
+	cfg := mvmCluster.Spec.ClientCertificate
+
+	commonName := cfg.CommonName
+	if commonName == "" {
+		commonName = fmt.Sprintf("capmvm:%s:%s", mvmCluster.Namespace, mvmCluster.Name)
+	}
+
+	issuerKind := cfg.IssuerRef.Kind
+	if issuerKind == "" {
+		issuerKind = defaultIssuerKind
+	}
+
+	issuerGroup := cfg.IssuerRef.Group
+	if issuerGroup == "" {
+		issuerGroup = certManagerGroup
+	}
+
+	spec := map[string]interface{}{
+		"secretName": ClientCertificateName(mvmCluster),
+		"commonName": commonName,
+		"issuerRef": map[string]interface{}{
+			"name":  cfg.IssuerRef.Name,
+			"kind":  issuerKind,
+			"group": issuerGroup,
+		},
+		"usages": []interface{}{"client auth", "digital signature", "key encipherment"},
+		"secretTemplate": map[string]interface{}{
+			"labels": map[string]interface{}{
+				clusterv1.ClusterNameLabel: clusterName,
+			},
+		},
+	}
+
+	if len(cfg.Organizations) > 0 {
+		organizations := make([]interface{}, 0, len(cfg.Organizations))
+		for _, org := range cfg.Organizations {
+			organizations = append(organizations, org)
+		}
+
+		spec["subject"] = map[string]interface{}{
+			"organizations": organizations,
+		}
+	}
+
+	if cfg.Duration != nil {
+		spec["duration"] = cfg.Duration.Duration.String()
+	}
+
+	if cfg.RenewBefore != nil {
+		spec["renewBefore"] = cfg.RenewBefore.Duration.String()
+	}
+
+	return spec
+}
+
+// isCertificateReady returns true if the Ready condition of the Certificate is true.
+func isCertificateReady(cert *unstructured.Unstructured) bool {
+	conds, _, _ := unstructured.NestedSlice(cert.Object, "status", "conditions")
+
+	for _, c := range conds {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if cond["type"] == clientCertificateReady {
+			return cond["status"] == string(metav1.ConditionTrue)
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestClusterReconcileClientCertificate(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{
+		ClientCertificate: &infrav1.ClientCertificateSpec{
+			IssuerRef:     infrav1.CertificateIssuerReference{Name: "flintlock-ca", Kind: "ClusterIssuer"},
+			Organizations: []string{"tenant-a"},
+			Duration:      &metav1.Duration{Duration: 24 * time.Hour},
+		},
+	})
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(mvmCluster).Build()
+
+	clusterScope, err := scope.NewClusterScope(newCluster("testcluster", nil), mvmCluster, client)
+	Expect(err).NotTo(HaveOccurred())
+
+	ready, err := clusterScope.ReconcileClientCertificate(context.TODO())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ready).To(BeFalse())
+
+	key := types.NamespacedName{Namespace: "default", Name: "testcluster-flintlock-client"}
+	cert := getCertificate(client, key)
+
+	Expect(cert.GetOwnerReferences()).To(HaveLen(1))
+	Expect(nestedString(cert, "spec", "secretName")).To(Equal(key.Name))
+	Expect(nestedString(cert, "spec", "commonName")).To(Equal("capmvm:default:testcluster"))
+	Expect(nestedString(cert, "spec", "issuerRef", "kind")).To(Equal("ClusterIssuer"))
+	Expect(nestedString(cert, "spec", "issuerRef", "group")).To(Equal("cert-manager.io"))
+	Expect(nestedString(cert, "spec", "duration")).To(Equal("24h0m0s"))
+	organizations, _, err := unstructured.NestedStringSlice(cert.Object, "spec", "subject", "organizations")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(organizations).To(Equal([]string{"tenant-a"}))
+
+	Expect(unstructured.SetNestedSlice(cert.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions")).To(Succeed())
+	Expect(unstructured.SetNestedField(cert.Object, "RSA", "spec", "privateKey", "algorithm")).To(Succeed())
+	Expect(client.Update(context.TODO(), cert)).To(Succeed())
+
+	ready, err = clusterScope.ReconcileClientCertificate(context.TODO())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ready).To(BeTrue(), "expected fields defaulted by cert-manager to be kept")
+
+	mvmCluster.Spec.ClientCertificate.CommonName = "tenant-a"
+	mvmCluster.Spec.ClientCertificate.Duration = nil
+
+	ready, err = clusterScope.ReconcileClientCertificate(context.TODO())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ready).To(BeFalse())
+
+	cert = getCertificate(client, key)
+	Expect(nestedString(cert, "spec", "commonName")).To(Equal("tenant-a"))
+	Expect(nestedString(cert, "spec", "privateKey", "algorithm")).To(Equal("RSA"))
+	_, found, _ := unstructured.NestedString(cert.Object, "spec", "duration")
+	Expect(found).To(BeFalse())
+}
+
+func TestClientCertificateUsedForTLS(t *testing.T) {
+	RegisterTestingT(t)
+
+	mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{
+		ClientCertificate: &infrav1.ClientCertificateSpec{
+			IssuerRef: infrav1.CertificateIssuerReference{Name: "flintlock-ca"},
+		},
+	})
+
+	Expect(scope.CredentialsSecretNames(mvmCluster)).To(Equal([]string{"testcluster-flintlock-client"}))
+}
+
+func getCertificate(c client.Client, key types.NamespacedName) *unstructured.Unstructured {
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(scope.CertificateGVK)
+	Expect(c.Get(context.TODO(), key, cert)).To(Succeed())
+
+	return cert
+}
+
+func nestedString(obj *unstructured.Unstructured, fields ...string) string {
+	value, _, err := unstructured.NestedString(obj.Object, fields...)
+	Expect(err).NotTo(HaveOccurred())
+
+	return value
+}
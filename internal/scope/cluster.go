@@ -71,6 +71,14 @@ func WithClusterControllerName(name string) ClusterScopeOption {
 	}
 }
 
+// WithClusterIdentityNamespace sets the namespace that the secrets of a MicrovmClusterIdentity
+// are read from.
+func WithClusterIdentityNamespace(namespace string) ClusterScopeOption {
+	return func(s *ClusterScope) {
+		s.identityNamespace = namespace
+	}
+}
+
 // ClusterScope is the scope for reconciling a cluster.
 type ClusterScope struct {
 	logr.Logger
@@ -78,9 +86,10 @@ type ClusterScope struct {
 	Cluster    *clusterv1.Cluster
 	MvmCluster *infrav1.MicrovmCluster
 
-	client         client.Client
-	patchHelper    *patch.Helper
-	controllerName string
+	client            client.Client
+	patchHelper       *patch.Helper
+	controllerName    string
+	identityNamespace string
 }
 
 // Name returns the name of the resource.
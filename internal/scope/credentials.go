@@ -0,0 +1,331 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/identity"
+)
+
+// MissingCredential is a secret, or a key in a secret, that's needed to connect to the
+// hosts but doesn't exist.
+type MissingCredential struct {
+	// Secret is the name of the secret.
+	Secret string
+	// Key is the name of the missing key. If empty the secret itself is missing.
+	Key string
+}
+
+func (m MissingCredential) String() string {
+	if m.Key == "" {
+		return fmt.Sprintf("secret %s not found", m.Secret)
+	}
+
+	return fmt.Sprintf("secret %s is missing key %s", m.Secret, m.Key)
+}
+
+// CredentialsSecretNames returns the names of the secrets with the credentials used to connect
+// to the hosts that are referenced by the MicrovmCluster.
+func CredentialsSecretNames(mvmCluster *infrav1.MicrovmCluster) []string {
+	names := map[string]bool{}
+
+	for _, name := range tlsSecretNames(mvmCluster) {
+		names[name] = true
+	}
+
+	if staticPool := mvmCluster.Spec.Placement.StaticPool; staticPool != nil && staticPool.BasicAuthSecret != "" {
+		names[staticPool.BasicAuthSecret] = true
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// GetMissingCredentials checks that the secrets the MicrovmCluster (or its identity) references
+// for connecting to the hosts exist and that the TLS secrets contain all the required keys. Tokens
+// for individual hosts in the basic auth secret are optional so aren't reported.
+func (cs *ClusterScope) GetMissingCredentials(ctx context.Context) ([]MissingCredential, error) {
+	source, err := getCredentialsSource(ctx, cs.client, cs.MvmCluster, cs.identityNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []MissingCredential{}
+
+	for _, name := range source.tlsSecretNames() {
+		secret, err := getCredentialsSecret(ctx, cs.client, source.namespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if secret == nil {
+			missing = append(missing, MissingCredential{Secret: name})
+
+			continue
+		}
+
+		for _, key := range []string{tlsCert, tlsKey, caCert} {
+			if _, ok := secret.Data[key]; !ok {
+				missing = append(missing, MissingCredential{Secret: name, Key: key})
+			}
+		}
+	}
+
+	if source.basicAuthSecret != "" {
+		secret, err := getCredentialsSecret(ctx, cs.client, source.namespace, source.basicAuthSecret)
+		if err != nil {
+			return nil, err
+		}
+
+		if secret == nil {
+			missing = append(missing, MissingCredential{Secret: source.basicAuthSecret})
+		}
+	}
+
+	return missing, nil
+}
+
+// credentialsSource is where the credentials for connecting to the hosts of a MicrovmCluster
+// are read from. This is either the MicrovmCluster itself or the identity it references.
+type credentialsSource struct {
+	// namespace is the namespace of the secrets.
+	namespace string
+	// tlsSecretRef is the TLS secret used for hosts that don't have their own.
+	tlsSecretRef string
+	// hostTLSSecretRefs are the TLS secrets of the hosts that have their own, by endpoint.
+	hostTLSSecretRefs map[string]string
+	// hostEndpoints are the endpoints of the hosts in the placement.
+	hostEndpoints []string
+	// basicAuthSecret is the secret with the basic auth token of each host.
+	basicAuthSecret string
+}
+
+// getCredentialsSource returns where the credentials of the MicrovmCluster are read from. If the
+// MicrovmCluster references an identity the secrets are in the identity namespace.
+func getCredentialsSource(
+	ctx context.Context,
+	c client.Reader,
+	mvmCluster *infrav1.MicrovmCluster,
+	identityNamespace string,
+) (*credentialsSource, error) {
+	source := &credentialsSource{
+		namespace:         mvmCluster.Namespace,
+		tlsSecretRef:      clusterTLSSecretName(mvmCluster),
+		hostTLSSecretRefs: map[string]string{},
+	}
+
+	if staticPool := mvmCluster.Spec.Placement.StaticPool; staticPool != nil {
+		source.basicAuthSecret = staticPool.BasicAuthSecret
+
+		for _, host := range staticPool.Hosts {
+			source.hostEndpoints = append(source.hostEndpoints, host.Endpoint)
+
+			if host.TLSSecretRef != "" {
+				source.hostTLSSecretRefs[host.Endpoint] = host.TLSSecretRef
+			}
+		}
+	}
+
+	if mvmCluster.Spec.IdentityRef == nil {
+		return source, nil
+	}
+
+	if identityNamespace == "" {
+		return nil, errIdentityNamespaceNotSet
+	}
+
+	clusterIdentity, err := identity.Get(ctx, c, mvmCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	source.namespace = identityNamespace
+	source.tlsSecretRef = clusterIdentity.Spec.TLSSecretRef
+	source.hostTLSSecretRefs = map[string]string{}
+	source.basicAuthSecret = clusterIdentity.Spec.BasicAuthSecret
+
+	return source, nil
+}
+
+// tlsSecretName returns the name of the TLS secret for the host, or an empty string if
+// the host doesn't use TLS.
+func (s *credentialsSource) tlsSecretName(hostEndpoint string) string {
+	if name, ok := s.hostTLSSecretRefs[hostEndpoint]; ok {
+		return name
+	}
+
+	return s.tlsSecretRef
+}
+
+// tlsSecretNames returns the names of the TLS secrets used by the hosts.
+func (s *credentialsSource) tlsSecretNames() []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	add(s.tlsSecretRef)
+
+	for _, endpoint := range s.hostEndpoints {
+		add(s.hostTLSSecretRefs[endpoint])
+	}
+
+	return names
+}
+
+// GetBasicAuthToken returns the token for the host at the address from the BasicAuthSecret on
+// the MvmCluster (or its identity). If no secret or no value is found, an empty string is returned.
+func (cs *ClusterScope) GetBasicAuthToken(ctx context.Context, addr string) (string, error) {
+	return getBasicAuthToken(ctx, cs.client, cs.MvmCluster, cs.identityNamespace, addr, cs.Logger)
+}
+
+// GetTLSConfig returns the TLS config for the host at the address, or nil if the host isn't
+// configured with TLS.
+func (cs *ClusterScope) GetTLSConfig(ctx context.Context, addr string) (*flclient.TLSConfig, error) {
+	return getHostTLSConfig(ctx, cs.client, cs.MvmCluster, cs.identityNamespace, addr, cs.Logger)
+}
+
+func getBasicAuthToken(
+	ctx context.Context,
+	c client.Client,
+	mvmCluster *infrav1.MicrovmCluster,
+	identityNamespace string,
+	addr string,
+	log logr.Logger,
+) (string, error) {
+	source, err := getCredentialsSource(ctx, c, mvmCluster, identityNamespace)
+	if err != nil {
+		return "", err
+	}
+
+	if source.basicAuthSecret == "" {
+		return "", nil
+	}
+
+	tokenSecret := &corev1.Secret{}
+	key := types.NamespacedName{
+		Name:      source.basicAuthSecret,
+		Namespace: source.namespace,
+	}
+
+	if err := c.Get(ctx, key, tokenSecret); err != nil {
+		return "", err
+	}
+
+	secretKey := basicAuthSecretKey(addr)
+	// If it's not there, that's fine; we will log and return an empty string
+	token := string(tokenSecret.Data[secretKey])
+
+	if token == "" {
+		log.Info(
+			"basicAuthToken for host not found in secret", "secret", tokenSecret.Name, "host", addr, "key", secretKey,
+		)
+	}
+
+	return token, nil
+}
+
+func getHostTLSConfig(
+	ctx context.Context,
+	c client.Client,
+	mvmCluster *infrav1.MicrovmCluster,
+	identityNamespace string,
+	addr string,
+	log logr.Logger,
+) (*flclient.TLSConfig, error) {
+	source, err := getCredentialsSource(ctx, c, mvmCluster, identityNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	secretName := source.tlsSecretName(addr)
+	if secretName == "" {
+		log.Info("no TLS configuration found. will create insecure connection")
+
+		return nil, nil
+	}
+
+	tlsConfig, cert, err := getTLSConfig(ctx, c, source.namespace, secretName)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(cert.NotAfter) {
+		return nil, &tlsCertificateExpiredError{secret: secretName, notAfter: cert.NotAfter}
+	}
+
+	return tlsConfig, nil
+}
+
+// getCredentialsSecret returns the secret or nil if it doesn't exist.
+func getCredentialsSecret(ctx context.Context, c client.Reader, namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+
+	if err := c.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("getting secret %s: %w", key, err)
+	}
+
+	return secret, nil
+}
+
+// tlsSecretNames returns the names of the TLS secrets referenced by the cluster and its hosts.
+func tlsSecretNames(mvmCluster *infrav1.MicrovmCluster) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	add(clusterTLSSecretName(mvmCluster))
+
+	if staticPool := mvmCluster.Spec.Placement.StaticPool; staticPool != nil {
+		for _, host := range staticPool.Hosts {
+			add(host.TLSSecretRef)
+		}
+	}
+
+	return names
+}
+
+// clusterTLSSecretName returns the name of the TLS secret used for the hosts that don't have
+// their own. This is the secret of the client certificate if it's issued by cert-manager.
+func clusterTLSSecretName(mvmCluster *infrav1.MicrovmCluster) string {
+	if mvmCluster.Spec.ClientCertificate != nil {
+		return ClientCertificateName(mvmCluster)
+	}
+
+	return mvmCluster.Spec.TLSSecretRef
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestCredentialsSecretNames(t *testing.T) {
+	RegisterTestingT(t)
+
+	mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{
+		TLSSecretRef: "cluster-tls",
+		Placement: infrav1.Placement{
+			StaticPool: &infrav1.StaticPoolPlacement{
+				BasicAuthSecret: "basic-auth",
+				Hosts: []infrav1.MicrovmHost{
+					{Endpoint: "10.0.0.1:9090", TLSSecretRef: "host-tls"},
+					{Endpoint: "10.0.0.2:9090", TLSSecretRef: "host-tls"},
+					{Endpoint: "10.0.0.3:9090"},
+				},
+			},
+		},
+	})
+
+	Expect(scope.CredentialsSecretNames(mvmCluster)).To(Equal([]string{"basic-auth", "cluster-tls", "host-tls"}))
+	Expect(scope.CredentialsSecretNames(newMicrovmCluster("other"))).To(BeEmpty())
+}
+
+func TestClusterGetMissingCredentials(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	spec := infrav1.MicrovmClusterSpec{
+		TLSSecretRef: "cluster-tls",
+		Placement: infrav1.Placement{
+			StaticPool: &infrav1.StaticPoolPlacement{
+				BasicAuthSecret: "basic-auth",
+				Hosts:           []infrav1.MicrovmHost{{Endpoint: "10.0.0.1:9090"}},
+			},
+		},
+	}
+
+	validTLS := newTLSData(time.Now().Add(time.Hour))
+	missingKeyTLS := newTLSData(time.Now().Add(time.Hour))
+	delete(missingKeyTLS, "ca.crt")
+	delete(missingKeyTLS, "tls.key")
+
+	tt := []struct {
+		name        string
+		initObjects []client.Object
+		expected    []scope.MissingCredential
+	}{
+		{
+			name: "all credentials exist",
+			initObjects: []client.Object{
+				newSecret("cluster-tls", validTLS),
+				newSecret("basic-auth", map[string][]byte{}),
+			},
+			expected: []scope.MissingCredential{},
+		},
+		{
+			name:        "secrets don't exist",
+			initObjects: []client.Object{},
+			expected: []scope.MissingCredential{
+				{Secret: "cluster-tls"},
+				{Secret: "basic-auth"},
+			},
+		},
+		{
+			name: "tls secret missing keys",
+			initObjects: []client.Object{
+				newSecret("cluster-tls", missingKeyTLS),
+				newSecret("basic-auth", map[string][]byte{}),
+			},
+			expected: []scope.MissingCredential{
+				{Secret: "cluster-tls", Key: "tls.key"},
+				{Secret: "cluster-tls", Key: "ca.crt"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			mvmCluster := newMicrovmClusterWithSpec("testcluster", spec)
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(tc.initObjects, mvmCluster)...).Build()
+
+			clusterScope, err := scope.NewClusterScope(&clusterv1.Cluster{}, mvmCluster, client)
+			Expect(err).NotTo(HaveOccurred())
+
+			missing, err := clusterScope.GetMissingCredentials(context.TODO())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missing).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestCredentialsFromIdentity(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	identityNamespace := "capmvm-system"
+	hostName := "10.0.0.1:9090"
+
+	identity := &infrav1.MicrovmClusterIdentity{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: infrav1.MicrovmClusterIdentitySpec{
+			TLSSecretRef:      "shared-tls",
+			BasicAuthSecret:   "shared-basic-auth",
+			AllowedNamespaces: &infrav1.AllowedNamespaces{NamespaceList: []string{"default"}},
+		},
+	}
+
+	tlsSecret := newSecret("shared-tls", newTLSData(time.Now().Add(time.Hour)))
+	tlsSecret.Namespace = identityNamespace
+	basicAuthSecret := newSecret("shared-basic-auth", map[string][]byte{"10.0.0.1": []byte("token")})
+	basicAuthSecret.Namespace = identityNamespace
+
+	mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{
+		IdentityRef: &infrav1.MicrovmClusterIdentityReference{Name: "shared"},
+		Placement: infrav1.Placement{
+			StaticPool: &infrav1.StaticPoolPlacement{
+				Hosts: []infrav1.MicrovmHost{{Endpoint: hostName}},
+			},
+		},
+	})
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(mvmCluster, identity, tlsSecret, basicAuthSecret).
+		Build()
+
+	clusterScope, err := scope.NewClusterScope(&clusterv1.Cluster{}, mvmCluster, client,
+		scope.WithClusterIdentityNamespace(identityNamespace))
+	Expect(err).NotTo(HaveOccurred())
+
+	missing, err := clusterScope.GetMissingCredentials(context.TODO())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(missing).To(BeEmpty())
+
+	certs, err := clusterScope.GetTLSCertificates(context.TODO())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(certs).To(HaveLen(1))
+	Expect(certs[0].SecretName).To(Equal("shared-tls"))
+
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:         client,
+		Cluster:        &clusterv1.Cluster{},
+		MicroVMCluster: mvmCluster,
+		Machine:        &clusterv1.Machine{},
+		MicroVMMachine: &infrav1.MicrovmMachine{},
+	}, scope.WithMachineIdentityNamespace(identityNamespace))
+	Expect(err).NotTo(HaveOccurred())
+
+	token, err := machineScope.GetBasicAuthToken(hostName)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(token).To(Equal("token"))
+
+	tlsConfig, err := machineScope.GetTLSConfig(hostName)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(tlsConfig).NotTo(BeNil())
+
+	noNamespaceScope, err := scope.NewClusterScope(&clusterv1.Cluster{}, mvmCluster, client)
+	Expect(err).NotTo(HaveOccurred())
+
+	_, err = noNamespaceScope.GetMissingCredentials(context.TODO())
+	Expect(err).To(HaveOccurred())
+}
@@ -3,7 +3,11 @@
 
 package scope
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	errClusterRequired        = errors.New("cluster required to create scope")
@@ -18,6 +22,14 @@ var (
 	errMissingBootstrapSecretKey  = errors.New("missing bootstrap secrey value key")
 
 	errFailureDomainNotFound = errors.New("no failure domains found on the cluster")
+
+	// ErrNoCompatibleHost is returned when none of the hosts the machine can be placed on can
+	// create its microvm.
+	ErrNoCompatibleHost = errors.New("no compatible host for the machine")
+
+	errInvalidTLSCertificate = errors.New("tls certificate is not PEM encoded")
+
+	errIdentityNamespaceNotSet = errors.New("identity namespace required to use a microvm cluster identity")
 )
 
 type tlsError struct {
@@ -27,3 +39,43 @@ type tlsError struct {
 func (t *tlsError) Error() string {
 	return "required key missing from TLS config data: " + t.key
 }
+
+type tlsCertificateExpiredError struct {
+	secret   string
+	notAfter time.Time
+}
+
+func (t *tlsCertificateExpiredError) Error() string {
+	return fmt.Sprintf("tls certificate in secret %s expired at %s", t.secret, t.notAfter.Format(time.RFC3339))
+}
+
+type unsupportedBootstrapFormatError struct {
+	format BootstrapFormat
+}
+
+func (u *unsupportedBootstrapFormatError) Error() string {
+	return "unsupported bootstrap data format: " + string(u.format)
+}
+
+type networkNotFoundError struct {
+	network string
+	host    string
+}
+
+func (n *networkNotFoundError) Error() string {
+	return fmt.Sprintf("network %s not found on host %s", n.network, n.host)
+}
+
+type sshPublicKeysMissingError struct {
+	kind string
+	name string
+	key  string
+}
+
+func (s *sshPublicKeysMissingError) Error() string {
+	if s.key == "" {
+		return fmt.Sprintf("ssh public keys %s %s not found", s.kind, s.name)
+	}
+
+	return fmt.Sprintf("ssh public keys %s %s is missing key %s", s.kind, s.name, s.key)
+}
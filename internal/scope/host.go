@@ -0,0 +1,136 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"net"
+	"strings"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+)
+
+// hostFromEndpoint returns the host part of a host endpoint. IPv6 addresses must be in brackets
+// when the endpoint has a port (e.g. [fd00::1]:9090). If the endpoint doesn't have a port it is
+// used as the host.
+func hostFromEndpoint(endpoint string) string {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return strings.TrimSuffix(strings.TrimPrefix(endpoint, "["), "]")
+	}
+
+	return host
+}
+
+// basicAuthSecretKey returns the key of the basic auth secret that holds the token for the host
+// endpoint. Secret keys can't contain colons, so IPv6 addresses are written in their canonical
+// form with the colons replaced by dashes (e.g. fd00::1 becomes fd00--1). IPv4 addresses and
+// hostnames are used as they are.
+func basicAuthSecretKey(endpoint string) string {
+	host := hostFromEndpoint(endpoint)
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() != nil {
+		return host
+	}
+
+	return strings.ReplaceAll(ip.String(), ":", "-")
+}
+
+// findHost returns the host in the static pool with the ID. Failure domains and providerIDs
+// created before hosts had stable IDs contain the endpoint of the host, so if no host has the ID
+// the host with a matching endpoint is returned. It returns nil if the host can't be found.
+func findHost(placement infrav1.Placement, id string) *infrav1.MicrovmHost {
+	if placement.StaticPool == nil {
+		return nil
+	}
+
+	hosts := placement.StaticPool.Hosts
+
+	for i := range hosts {
+		if hosts[i].ID() == id {
+			return &hosts[i]
+		}
+	}
+
+	for i := range hosts {
+		if hosts[i].Endpoint == id {
+			return &hosts[i]
+		}
+	}
+
+	return nil
+}
+
+// GetHost returns the host in the supplied failure domain, or nil if the host isn't in the
+// placement of the cluster.
+func (m *MachineScope) GetHost(failureDomain string) *infrav1.MicrovmHost {
+	return findHost(m.MvmCluster.Spec.Placement, failureDomain)
+}
+
+// GetHostEndpoint returns the current endpoint of the host in the supplied failure domain. If the
+// host isn't in the placement of the cluster the failure domain is assumed to be the endpoint.
+func (m *MachineScope) GetHostEndpoint(failureDomain string) string {
+	if host := m.GetHost(failureDomain); host != nil {
+		return host.Endpoint
+	}
+
+	return failureDomain
+}
+
+// GetProxy returns the proxy used to connect to the host at the address. The proxy of the host
+// takes precedence over the MicrovmProxy of the cluster, and it returns nil if the host should
+// be connected to directly.
+func (m *MachineScope) GetProxy(addr string) *flclient.Proxy {
+	return hostProxy(m.MvmCluster, findHost(m.MvmCluster.Spec.Placement, addr))
+}
+
+// GetProxy returns the proxy used to connect to the host at the address, or nil if the host
+// should be connected to directly.
+func (cs *ClusterScope) GetProxy(addr string) *flclient.Proxy {
+	return hostProxy(cs.MvmCluster, findHost(cs.MvmCluster.Spec.Placement, addr))
+}
+
+// hostProxy returns the proxy used to connect to the host, or nil to connect directly.
+func hostProxy(mvmCluster *infrav1.MicrovmCluster, host *infrav1.MicrovmHost) *flclient.Proxy {
+	if host == nil || host.Proxy == nil {
+		return mvmCluster.Spec.MicrovmProxy
+	}
+
+	if host.Proxy.Direct {
+		return nil
+	}
+
+	return &flclient.Proxy{Endpoint: host.Proxy.Endpoint}
+}
+
+// SetHostID records the ID of the host in the supplied failure domain on the MvmMachine so
+// that the host can still be found if its endpoint changes. The endpoint of the host is also
+// recorded for machines in other clusters, which can't resolve the ID.
+func (m *MachineScope) SetHostID(failureDomain string) {
+	host := m.GetHost(failureDomain)
+	if host == nil {
+		return
+	}
+
+	annotations := m.MvmMachine.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[infrav1.HostIDAnnotation] = host.ID()
+	annotations[infrav1.HostEndpointAnnotation] = host.Endpoint
+	m.MvmMachine.SetAnnotations(annotations)
+}
+
+// resolveHostID returns the ID of the host in the failure domain. Failure domains that contain
+// the endpoint of a host are changed to the ID of the host.
+func (m *MachineScope) resolveHostID(failureDomain string) string {
+	if host := m.GetHost(failureDomain); host != nil {
+		return host.ID()
+	}
+
+	return failureDomain
+}
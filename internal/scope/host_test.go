@@ -0,0 +1,259 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestMachineGetFailureDomainHostID(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	placement := infrav1.Placement{
+		StaticPool: &infrav1.StaticPoolPlacement{
+			Hosts: []infrav1.MicrovmHost{
+				{Name: "host1", Endpoint: "10.0.0.1:9090"},
+				{Endpoint: "10.0.0.2:9090"},
+			},
+		},
+	}
+
+	tt := []struct {
+		name             string
+		annotation       string
+		failureDomain    string
+		providerID       string
+		expected         string
+		expectedEndpoint string
+	}{
+		{
+			name:             "failure domain with host id",
+			failureDomain:    "host1",
+			expected:         "host1",
+			expectedEndpoint: "10.0.0.1:9090",
+		},
+		{
+			name:             "failure domain with endpoint of named host",
+			failureDomain:    "10.0.0.1:9090",
+			expected:         "host1",
+			expectedEndpoint: "10.0.0.1:9090",
+		},
+		{
+			name:             "failure domain with endpoint of unnamed host",
+			failureDomain:    "10.0.0.2:9090",
+			expected:         "10.0.0.2:9090",
+			expectedEndpoint: "10.0.0.2:9090",
+		},
+		{
+			name:             "providerID with host id",
+			providerID:       "microvm://host1/abcdef",
+			expected:         "host1",
+			expectedEndpoint: "10.0.0.1:9090",
+		},
+		{
+			name:             "providerID with endpoint of named host",
+			providerID:       "microvm://10.0.0.1:9090/abcdef",
+			expected:         "host1",
+			expectedEndpoint: "10.0.0.1:9090",
+		},
+		{
+			name:             "annotation takes precedence over a stale endpoint",
+			annotation:       "host1",
+			failureDomain:    "192.168.0.1:9090",
+			providerID:       "microvm://192.168.0.1:9090/abcdef",
+			expected:         "host1",
+			expectedEndpoint: "10.0.0.1:9090",
+		},
+		{
+			name:             "unknown host is used as the endpoint",
+			failureDomain:    "192.168.0.1:9090",
+			expected:         "192.168.0.1:9090",
+			expectedEndpoint: "192.168.0.1:9090",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			cluster := newCluster(clusterName, nil)
+			mvmCluster := newMicrovmClusterWithSpec(clusterName, infrav1.MicrovmClusterSpec{Placement: placement})
+			machine := newMachine(clusterName, machineName)
+			mvmMachine := newMicrovmMachine(clusterName, machineName, tc.providerID)
+
+			if tc.failureDomain != "" {
+				machine.Spec.FailureDomain = pointer.String(tc.failureDomain)
+			}
+
+			if tc.annotation != "" {
+				mvmMachine.Annotations = map[string]string{infrav1.HostIDAnnotation: tc.annotation}
+			}
+
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, mvmCluster, machine, mvmMachine).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        cluster,
+				MicroVMCluster: mvmCluster,
+				Machine:        machine,
+				MicroVMMachine: mvmMachine,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			failureDomain, err := machineScope.GetFailureDomain()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failureDomain).To(Equal(tc.expected))
+			Expect(machineScope.GetHostEndpoint(failureDomain)).To(Equal(tc.expectedEndpoint))
+		})
+	}
+}
+
+func TestMachineSetHostID(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	cluster := newCluster(clusterName, nil)
+	mvmCluster := newMicrovmClusterWithSpec(clusterName, infrav1.MicrovmClusterSpec{
+		Placement: infrav1.Placement{
+			StaticPool: &infrav1.StaticPoolPlacement{
+				Hosts: []infrav1.MicrovmHost{{Name: "host1", Endpoint: "10.0.0.1:9090"}},
+			},
+		},
+	})
+	machine := newMachine(clusterName, machineName)
+	mvmMachine := newMicrovmMachine(clusterName, machineName, "microvm://10.0.0.1:9090/abcdef")
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, mvmCluster, machine, mvmMachine).Build()
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:         client,
+		Cluster:        cluster,
+		MicroVMCluster: mvmCluster,
+		Machine:        machine,
+		MicroVMMachine: mvmMachine,
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	machineScope.SetHostID("unknown:9090")
+	Expect(mvmMachine.Annotations).NotTo(HaveKey(infrav1.HostIDAnnotation))
+
+	machineScope.SetHostID("10.0.0.1:9090")
+	Expect(mvmMachine.Annotations).To(HaveKeyWithValue(infrav1.HostIDAnnotation, "host1"))
+	Expect(mvmMachine.Annotations).To(HaveKeyWithValue(infrav1.HostEndpointAnnotation, "10.0.0.1:9090"))
+
+	// The endpoint of the host changes after the host ID has been recorded.
+	mvmCluster.Spec.Placement.StaticPool.Hosts[0].Endpoint = "10.0.0.5:9090"
+
+	failureDomain, err := machineScope.GetFailureDomain()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(failureDomain).To(Equal("host1"))
+	Expect(machineScope.GetHostEndpoint(failureDomain)).To(Equal("10.0.0.5:9090"))
+	Expect(machineScope.GetProviderID()).To(Equal("microvm://10.0.0.1:9090/abcdef"))
+}
+
+func TestMachineGetProxy(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	placement := infrav1.Placement{
+		StaticPool: &infrav1.StaticPoolPlacement{
+			Hosts: []infrav1.MicrovmHost{
+				{Endpoint: "10.0.0.1:9090"},
+				{Name: "remote", Endpoint: "192.168.0.1:9090", Proxy: &infrav1.HostProxy{Endpoint: "bastion:3128"}},
+				{Endpoint: "10.0.0.3:9090", Proxy: &infrav1.HostProxy{Direct: true}},
+			},
+		},
+	}
+
+	tt := []struct {
+		name         string
+		clusterProxy *flclient.Proxy
+		addr         string
+		expected     *flclient.Proxy
+	}{
+		{
+			name:         "cluster proxy",
+			clusterProxy: &flclient.Proxy{Endpoint: "proxy:3128"},
+			addr:         "10.0.0.1:9090",
+			expected:     &flclient.Proxy{Endpoint: "proxy:3128"},
+		},
+		{
+			name:         "no proxy",
+			clusterProxy: nil,
+			addr:         "10.0.0.1:9090",
+			expected:     nil,
+		},
+		{
+			name:         "host proxy",
+			clusterProxy: &flclient.Proxy{Endpoint: "proxy:3128"},
+			addr:         "192.168.0.1:9090",
+			expected:     &flclient.Proxy{Endpoint: "bastion:3128"},
+		},
+		{
+			name:         "host proxy without cluster proxy",
+			clusterProxy: nil,
+			addr:         "192.168.0.1:9090",
+			expected:     &flclient.Proxy{Endpoint: "bastion:3128"},
+		},
+		{
+			name:         "host direct",
+			clusterProxy: &flclient.Proxy{Endpoint: "proxy:3128"},
+			addr:         "10.0.0.3:9090",
+			expected:     nil,
+		},
+		{
+			name:         "unknown host",
+			clusterProxy: &flclient.Proxy{Endpoint: "proxy:3128"},
+			addr:         "10.0.0.4:9090",
+			expected:     &flclient.Proxy{Endpoint: "proxy:3128"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := newCluster(clusterName, nil)
+			mvmCluster := newMicrovmClusterWithSpec(clusterName, infrav1.MicrovmClusterSpec{
+				Placement:    placement,
+				MicrovmProxy: tc.clusterProxy,
+			})
+			machine := newMachine(clusterName, machineName)
+			mvmMachine := newMicrovmMachine(clusterName, machineName, "")
+
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, mvmCluster, machine, mvmMachine).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        cluster,
+				MicroVMCluster: mvmCluster,
+				Machine:        machine,
+				MicroVMMachine: mvmMachine,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(machineScope.GetProxy(tc.addr)).To(Equal(tc.expected))
+		})
+	}
+}
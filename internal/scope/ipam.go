@@ -0,0 +1,240 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/defaults"
+)
+
+const defaultControlPlaneEndpointPort = 6443
+
+// ReconcileIPAddresses makes sure that there is an IPAddressClaim for each network interface
+// that gets its address from an IP pool. The allocated addresses are returned keyed by the
+// guest device name of the interface. If any of the addresses haven't been allocated by the
+// IPAM provider yet then false is returned.
+func (m *MachineScope) ReconcileIPAddresses() (map[string]*flintlocktypes.StaticAddress, bool, error) {
+	addresses := map[string]*flintlocktypes.StaticAddress{}
+	machineAddresses := []clusterv1.MachineAddress{}
+
+	for _, cfg := range m.MvmMachine.Spec.NetworkInterfaceConfigs {
+		if cfg.AddressFromPool == nil {
+			continue
+		}
+
+		claim, err := m.ensureIPAddressClaim(cfg)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if claim.Status.AddressRef.Name == "" {
+			m.V(defaults.LogLevelDebug).Info("ip address not allocated yet", "claim", claim.Name)
+
+			return nil, false, nil
+		}
+
+		ipAddress := &ipamv1.IPAddress{}
+		key := types.NamespacedName{Namespace: m.Namespace(), Name: claim.Status.AddressRef.Name}
+
+		if err := m.client.Get(m.ctx, key, ipAddress); err != nil {
+			return nil, false, fmt.Errorf("getting ip address %s: %w", key, err)
+		}
+
+		address := &flintlocktypes.StaticAddress{
+			Address:     fmt.Sprintf("%s/%d", ipAddress.Spec.Address, ipAddress.Spec.Prefix),
+			Nameservers: cfg.Nameservers,
+		}
+
+		if ipAddress.Spec.Gateway != "" {
+			gateway := ipAddress.Spec.Gateway
+			address.Gateway = &gateway
+		}
+
+		addresses[cfg.GuestDeviceName] = address
+		machineAddresses = append(machineAddresses, clusterv1.MachineAddress{
+			Type:    clusterv1.MachineInternalIP,
+			Address: ipAddress.Spec.Address,
+		})
+	}
+
+	if len(machineAddresses) > 0 {
+		m.MvmMachine.Status.Addresses = machineAddresses
+	}
+
+	return addresses, true, nil
+}
+
+// ReleaseIPAddresses deletes any IPAddressClaims created for the machine so that the
+// addresses are returned to their pools.
+func (m *MachineScope) ReleaseIPAddresses() error {
+	for _, cfg := range m.MvmMachine.Spec.NetworkInterfaceConfigs {
+		if cfg.AddressFromPool == nil {
+			continue
+		}
+
+		claim := &ipamv1.IPAddressClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ipAddressClaimName(m.Name(), cfg.GuestDeviceName),
+				Namespace: m.Namespace(),
+			},
+		}
+
+		if err := m.client.Delete(m.ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting ip address claim %s: %w", claim.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *MachineScope) ensureIPAddressClaim(cfg infrav1.NetworkInterfaceConfig) (*ipamv1.IPAddressClaim, error) {
+	claim := &ipamv1.IPAddressClaim{}
+	key := types.NamespacedName{
+		Namespace: m.Namespace(),
+		Name:      ipAddressClaimName(m.Name(), cfg.GuestDeviceName),
+	}
+
+	err := m.client.Get(m.ctx, key, claim)
+	if err == nil {
+		return claim, nil
+	}
+
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting ip address claim %s: %w", key, err)
+	}
+
+	claim = &ipamv1.IPAddressClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabel: m.ClusterName(),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(m.MvmMachine, infrav1.GroupVersion.WithKind("MicrovmMachine")),
+			},
+		},
+		Spec: ipamv1.IPAddressClaimSpec{
+			ClusterName: m.ClusterName(),
+			PoolRef:     *cfg.AddressFromPool,
+		},
+	}
+
+	m.Info("creating ip address claim", "claim", claim.Name, "pool", cfg.AddressFromPool.Name)
+
+	if err := m.client.Create(m.ctx, claim); err != nil {
+		return nil, fmt.Errorf("creating ip address claim %s: %w", key, err)
+	}
+
+	return claim, nil
+}
+
+func ipAddressClaimName(machineName, deviceName string) string {
+	return strings.ToLower(fmt.Sprintf("%s-%s", machineName, strings.ReplaceAll(deviceName, "_", "-")))
+}
+
+// ReconcileControlPlaneEndpoint makes sure there is an IPAddressClaim for the control plane
+// endpoint if the cluster gets its endpoint from an IP pool. When the address has been
+// allocated it's set as the host of the control plane endpoint. If the address hasn't been
+// allocated by the IPAM provider yet then false is returned.
+func (cs *ClusterScope) ReconcileControlPlaneEndpoint(ctx context.Context) (bool, error) {
+	poolRef := cs.MvmCluster.Spec.ControlPlaneEndpointFromPool
+	if poolRef == nil || cs.MvmCluster.Spec.ControlPlaneEndpoint.Host != "" {
+		return true, nil
+	}
+
+	claim := &ipamv1.IPAddressClaim{}
+	key := types.NamespacedName{
+		Namespace: cs.Namespace(),
+		Name:      controlPlaneEndpointClaimName(cs.Name()),
+	}
+
+	err := cs.client.Get(ctx, key, claim)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("getting ip address claim %s: %w", key, err)
+	}
+
+	if apierrors.IsNotFound(err) {
+		claim = &ipamv1.IPAddressClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabel: cs.ClusterName(),
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(cs.MvmCluster, infrav1.GroupVersion.WithKind("MicrovmCluster")),
+				},
+			},
+			Spec: ipamv1.IPAddressClaimSpec{
+				ClusterName: cs.ClusterName(),
+				PoolRef:     *poolRef,
+			},
+		}
+
+		cs.Info("creating control plane endpoint ip address claim", "claim", claim.Name, "pool", poolRef.Name)
+
+		if err := cs.client.Create(ctx, claim); err != nil {
+			return false, fmt.Errorf("creating ip address claim %s: %w", key, err)
+		}
+	}
+
+	if claim.Status.AddressRef.Name == "" {
+		cs.V(defaults.LogLevelDebug).Info("control plane endpoint ip address not allocated yet", "claim", claim.Name)
+
+		return false, nil
+	}
+
+	ipAddress := &ipamv1.IPAddress{}
+	addressKey := types.NamespacedName{Namespace: cs.Namespace(), Name: claim.Status.AddressRef.Name}
+
+	if err := cs.client.Get(ctx, addressKey, ipAddress); err != nil {
+		return false, fmt.Errorf("getting ip address %s: %w", addressKey, err)
+	}
+
+	cs.MvmCluster.Spec.ControlPlaneEndpoint.Host = ipAddress.Spec.Address
+	if cs.MvmCluster.Spec.ControlPlaneEndpoint.Port == 0 {
+		cs.MvmCluster.Spec.ControlPlaneEndpoint.Port = defaultControlPlaneEndpointPort
+	}
+
+	cs.Info("control plane endpoint allocated", "host", ipAddress.Spec.Address)
+
+	return true, nil
+}
+
+// ReleaseControlPlaneEndpoint deletes the IPAddressClaim created for the control plane
+// endpoint so that the address is returned to its pool.
+func (cs *ClusterScope) ReleaseControlPlaneEndpoint(ctx context.Context) error {
+	if cs.MvmCluster.Spec.ControlPlaneEndpointFromPool == nil {
+		return nil
+	}
+
+	claim := &ipamv1.IPAddressClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controlPlaneEndpointClaimName(cs.Name()),
+			Namespace: cs.Namespace(),
+		},
+	}
+
+	if err := cs.client.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting ip address claim %s: %w", claim.Name, err)
+	}
+
+	return nil
+}
+
+func controlPlaneEndpointClaimName(clusterName string) string {
+	return fmt.Sprintf("%s-control-plane", clusterName)
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestMachineReconcileIPAddresses(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+	claimName := "machine-1-eth1"
+
+	pool := &corev1.TypedLocalObjectReference{
+		APIGroup: pointer.String("ipam.cluster.x-k8s.io"),
+		Kind:     "InClusterIPPool",
+		Name:     "pool1",
+	}
+
+	tt := []struct {
+		name              string
+		initObjects       []client.Object
+		expectedAllocated bool
+		expectedAddress   string
+		expectedGateway   *string
+	}{
+		{
+			name:              "claim is created when it doesn't exist",
+			expectedAllocated: false,
+		},
+		{
+			name: "claim exists but address not allocated",
+			initObjects: []client.Object{
+				newIPAddressClaim(claimName, ""),
+			},
+			expectedAllocated: false,
+		},
+		{
+			name: "claim exists and address is allocated",
+			initObjects: []client.Object{
+				newIPAddressClaim(claimName, "address1"),
+				newIPAddress("address1", "10.0.0.5", 24, "10.0.0.1"),
+			},
+			expectedAllocated: true,
+			expectedAddress:   "10.0.0.5/24",
+			expectedGateway:   pointer.String("10.0.0.1"),
+		},
+		{
+			name: "claim exists and address without gateway is allocated",
+			initObjects: []client.Object{
+				newIPAddressClaim(claimName, "address1"),
+				newIPAddress("address1", "fd00::5", 64, ""),
+			},
+			expectedAllocated: true,
+			expectedAddress:   "fd00::5/64",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			cluster := newCluster(clusterName, []string{"fd1"})
+			mvmCluster := newMicrovmCluster(clusterName)
+			machine := newMachine(clusterName, machineName)
+			mvmMachine := newMicrovmMachine(clusterName, machineName, "")
+			mvmMachine.Spec.NetworkInterfaces = []microvm.NetworkInterface{
+				{GuestDeviceName: "eth0"},
+				{GuestDeviceName: "eth1"},
+			}
+			mvmMachine.Spec.NetworkInterfaceConfigs = []infrav1.NetworkInterfaceConfig{
+				{GuestDeviceName: "eth0"},
+				{GuestDeviceName: "eth1", AddressFromPool: pool, Nameservers: []string{"1.1.1.1"}},
+			}
+
+			initObjects := append([]client.Object{cluster, mvmCluster, machine, mvmMachine}, tc.initObjects...)
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        cluster,
+				MicroVMCluster: mvmCluster,
+				Machine:        machine,
+				MicroVMMachine: mvmMachine,
+				Context:        context.TODO(),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			addresses, allocated, err := machineScope.ReconcileIPAddresses()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allocated).To(Equal(tc.expectedAllocated))
+
+			claim := &ipamv1.IPAddressClaim{}
+			Expect(client.Get(context.TODO(), objectKey(claimName), claim)).To(Succeed())
+			Expect(claim.Spec.PoolRef).To(Equal(*pool))
+			Expect(claim.Spec.ClusterName).To(Equal(clusterName))
+
+			if !tc.expectedAllocated {
+				Expect(addresses).To(BeNil())
+
+				return
+			}
+
+			Expect(addresses).To(HaveLen(1))
+			Expect(addresses).To(HaveKey("eth1"))
+			Expect(addresses["eth1"].Address).To(Equal(tc.expectedAddress))
+			Expect(addresses["eth1"].Gateway).To(Equal(tc.expectedGateway))
+			Expect(addresses["eth1"].Nameservers).To(Equal([]string{"1.1.1.1"}))
+			Expect(mvmMachine.Status.Addresses).To(HaveLen(1))
+			Expect(mvmMachine.Status.Addresses[0].Address).To(Equal(strings.Split(tc.expectedAddress, "/")[0]))
+		})
+	}
+}
+
+func TestMachineReleaseIPAddresses(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	cluster := newCluster(clusterName, []string{"fd1"})
+	mvmCluster := newMicrovmCluster(clusterName)
+	machine := newMachine(clusterName, machineName)
+	mvmMachine := newMicrovmMachine(clusterName, machineName, "")
+	mvmMachine.Spec.NetworkInterfaceConfigs = []infrav1.NetworkInterfaceConfig{
+		{GuestDeviceName: "eth0", AddressFromPool: &corev1.TypedLocalObjectReference{Name: "pool1"}},
+		{GuestDeviceName: "eth1", AddressFromPool: &corev1.TypedLocalObjectReference{Name: "pool1"}},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cluster, mvmCluster, machine, mvmMachine, newIPAddressClaim("machine-1-eth0", "address1"),
+	).Build()
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:         client,
+		Cluster:        cluster,
+		MicroVMCluster: mvmCluster,
+		Machine:        machine,
+		MicroVMMachine: mvmMachine,
+		Context:        context.TODO(),
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(machineScope.ReleaseIPAddresses()).To(Succeed())
+
+	err = client.Get(context.TODO(), objectKey("machine-1-eth0"), &ipamv1.IPAddressClaim{})
+	Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+func objectKey(name string) client.ObjectKey {
+	return client.ObjectKey{Name: name, Namespace: "default"}
+}
+
+func newIPAddressClaim(name, addressName string) *ipamv1.IPAddressClaim {
+	return &ipamv1.IPAddressClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: ipamv1.IPAddressClaimSpec{
+			ClusterName: "testcluster",
+			PoolRef: corev1.TypedLocalObjectReference{
+				APIGroup: pointer.String("ipam.cluster.x-k8s.io"),
+				Kind:     "InClusterIPPool",
+				Name:     "pool1",
+			},
+		},
+		Status: ipamv1.IPAddressClaimStatus{
+			AddressRef: corev1.LocalObjectReference{Name: addressName},
+		},
+	}
+}
+
+func newIPAddress(name, address string, prefix int, gateway string) *ipamv1.IPAddress {
+	return &ipamv1.IPAddress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: ipamv1.IPAddressSpec{
+			Address: address,
+			Prefix:  prefix,
+			Gateway: gateway,
+		},
+	}
+}
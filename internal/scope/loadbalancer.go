@@ -0,0 +1,85 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"bytes"
+	"fmt"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/kubevip"
+)
+
+// GetLoadBalancerUserData returns the files that need to be written in the guest to run the
+// control plane load balancer, and the commands to run after the bootstrap commands. These are
+// only needed for control plane machines when the load balancer is managed by the provider.
+func (m *MachineScope) GetLoadBalancerUserData() ([]flintlock.File, []string, error) {
+	lb := m.MvmCluster.Spec.LoadBalancer
+	if !m.IsControlPlane() || !lb.IsManaged() {
+		return nil, nil, nil
+	}
+
+	initNode, err := m.isKubeadmInitNode()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest, err := kubevip.Manifest(lb, loadBalancerEndpoint(m.MvmCluster, m.Cluster), initNode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating kube-vip manifest: %w", err)
+	}
+
+	files := []flintlock.File{
+		{
+			Path:        kubevip.ManifestPath,
+			Content:     string(manifest),
+			Owner:       "root:root",
+			Permissions: "0644",
+		},
+	}
+
+	if !initNode {
+		return files, nil, nil
+	}
+
+	return files, []string{kubevip.RestoreKubeconfigCommand}, nil
+}
+
+// isKubeadmInitNode returns whether the machine runs kubeadm init with a version of Kubernetes
+// that creates super-admin.conf, which kube-vip needs to use until init has finished.
+func (m *MachineScope) isKubeadmInitNode() (bool, error) {
+	if m.Machine.Spec.Version != nil && !kubevip.UsesSuperAdminKubeconfig(*m.Machine.Spec.Version) {
+		return false, nil
+	}
+
+	bootstrapSecret, err := m.getBootstrapSecret()
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Contains(bootstrapSecret.Data["value"], []byte(kubeadmInitCommand)), nil
+}
+
+// LoadBalancerEndpoint returns the control plane endpoint served by the load balancer.
+func (cs *ClusterScope) LoadBalancerEndpoint() clusterv1.APIEndpoint {
+	return loadBalancerEndpoint(cs.MvmCluster, cs.Cluster)
+}
+
+// loadBalancerEndpoint returns the control plane endpoint of the MicrovmCluster, falling back
+// to the one of the Cluster, with the port defaulted to 6443.
+func loadBalancerEndpoint(mvmCluster *infrav1.MicrovmCluster, cluster *clusterv1.Cluster) clusterv1.APIEndpoint {
+	endpoint := mvmCluster.Spec.ControlPlaneEndpoint
+	if endpoint.Host == "" {
+		endpoint = cluster.Spec.ControlPlaneEndpoint
+	}
+
+	if endpoint.Port == 0 {
+		endpoint.Port = defaultControlPlaneEndpointPort
+	}
+
+	return endpoint
+}
@@ -107,6 +107,14 @@ func WithMachineControllerName(name string) MachineScopeOption {
 	}
 }
 
+// WithMachineIdentityNamespace sets the namespace that the secrets of a MicrovmClusterIdentity
+// are read from.
+func WithMachineIdentityNamespace(namespace string) MachineScopeOption {
+	return func(s *MachineScope) {
+		s.identityNamespace = namespace
+	}
+}
+
 type MachineScope struct {
 	logr.Logger
 
@@ -116,10 +124,14 @@ type MachineScope struct {
 	Machine    *clusterv1.Machine
 	MvmMachine *infrav1.MicrovmMachine
 
-	client         client.Client
-	patchHelper    *patch.Helper
-	controllerName string
-	ctx            context.Context
+	client            client.Client
+	patchHelper       *patch.Helper
+	controllerName    string
+	identityNamespace string
+	ctx               context.Context
+
+	// sshPublicKeys are the keys resolved by ResolveSSHPublicKeys.
+	sshPublicKeys []microvm.SSHPublicKey
 }
 
 // Name returns the MicrovmMachine name.
@@ -191,25 +203,61 @@ func (m *MachineScope) GetLabels() map[string]string {
 	return labels
 }
 
+// GetFailureDomain returns the failure domain, which is the ID of the host, that the machine is
+// placed in. The host recorded on the MvmMachine is used first, followed by the failure domain of
+// the Machine and the providerID. Failure domains that contain the endpoint of a host are changed
+// to the ID of the host. Machines that don't have a microvm yet are only placed on hosts that can
+// create it, and ErrNoCompatibleHost is returned if there aren't any or if the host in the failure
+// domain of the Machine can't create it.
 func (m *MachineScope) GetFailureDomain() (string, error) {
-	if m.Machine.Spec.FailureDomain != nil && *m.Machine.Spec.FailureDomain != "" {
-		return *m.Machine.Spec.FailureDomain, nil
+	if hostID := m.MvmMachine.GetAnnotations()[infrav1.HostIDAnnotation]; hostID != "" {
+		return m.resolveHostID(hostID), nil
 	}
 
 	providerID := m.GetProviderID()
+	// Machines that have a microvm, or are being deleted, stay where they are.
+	placed := providerID != "" || !m.MvmMachine.DeletionTimestamp.IsZero()
+
+	if m.Machine.Spec.FailureDomain != nil && *m.Machine.Spec.FailureDomain != "" {
+		failureDomain := m.resolveHostID(*m.Machine.Spec.FailureDomain)
+
+		reason := m.incompatibility(failureDomain)
+		if placed || reason == "" {
+			return failureDomain, nil
+		}
+
+		// The machine isn't placed on another host as that would undo the spreading of the
+		// machines across the failure domains by Cluster API.
+		return "", fmt.Errorf("%w: failure domain %s of the machine: %s", ErrNoCompatibleHost, failureDomain, reason)
+	}
+
 	if providerID != "" {
-		return m.getFailureDomainFromProviderID(providerID), nil
+		return m.resolveHostID(m.getFailureDomainFromProviderID(providerID)), nil
 	}
 
 	// If we've got this far then we need to work out how to get a failure domain. In the future we will make
 	// the strategy configurable for static placement and also add support for the scheduler.
+	if len(m.Cluster.Status.FailureDomains) == 0 {
+		return "", errFailureDomainNotFound
+	}
+
 	failureDomainNames := make([]string, 0, len(m.Cluster.Status.FailureDomains))
+	incompatible := []string{}
+
 	for fdName := range m.Cluster.Status.FailureDomains {
+		if reason := m.incompatibility(fdName); reason != "" && !placed {
+			incompatible = append(incompatible, fmt.Sprintf("%s: %s", fdName, reason))
+
+			continue
+		}
+
 		failureDomainNames = append(failureDomainNames, fdName)
 	}
 
 	if len(failureDomainNames) == 0 {
-		return "", errFailureDomainNotFound
+		sort.Strings(incompatible)
+
+		return "", fmt.Errorf("%w: %s", ErrNoCompatibleHost, strings.Join(incompatible, "; "))
 	}
 
 	if len(failureDomainNames) == 1 {
@@ -223,12 +271,26 @@ func (m *MachineScope) GetFailureDomain() (string, error) {
 }
 
 // GetRawBootstrapData will return the contents of the secret that has been created by the
-// bootstrap provider that is being used for this cluster/machine. Initially this we will
-// be using the Kubeadm bootstrap provider and so this will contain cloud-init configuration
-// that will invoke kubeadm to create or join a cluster.
+// bootstrap provider that is being used for this cluster/machine. For the Kubeadm bootstrap
+// provider this will contain cloud-init configuration that will invoke kubeadm to create or
+// join a cluster. See GetBootstrapDelivery for how other formats are handled.
 func (m *MachineScope) GetRawBootstrapData() (string, error) {
+	bootstrapSecret, err := m.getBootstrapSecret()
+	if err != nil {
+		return "", err
+	}
+
+	bootstrapData, ok := bootstrapSecret.Data["value"]
+	if !ok {
+		return "", errMissingBootstrapSecretKey
+	}
+
+	return base64.StdEncoding.EncodeToString(bootstrapData), nil
+}
+
+func (m *MachineScope) getBootstrapSecret() (*corev1.Secret, error) {
 	if m.Machine.Spec.Bootstrap.DataSecretName == nil {
-		return "", errMissingBootstrapDataSecret
+		return nil, errMissingBootstrapDataSecret
 	}
 
 	bootstrapSecret := &corev1.Secret{}
@@ -238,15 +300,10 @@ func (m *MachineScope) GetRawBootstrapData() (string, error) {
 	}
 
 	if err := m.client.Get(m.ctx, secretKey, bootstrapSecret); err != nil {
-		return "", fmt.Errorf("getting bootstrap secret %s: %w", secretKey, err)
-	}
-
-	bootstrapData, ok := bootstrapSecret.Data["value"]
-	if !ok {
-		return "", errMissingBootstrapSecretKey
+		return nil, fmt.Errorf("getting bootstrap secret %s: %w", secretKey, err)
 	}
 
-	return base64.StdEncoding.EncodeToString(bootstrapData), nil
+	return bootstrapSecret, nil
 }
 
 // SetReady sets any properties/conditions that are used to indicate that the MicrovmMachine is 'Ready'
@@ -268,7 +325,8 @@ func (m *MachineScope) SetNotReady(
 	m.MvmMachine.Status.Ready = false
 }
 
-// SetProviderID saves the unique microvm and object ID to the MvmMachine spec.
+// SetProviderID saves the unique microvm and object ID to the MvmMachine spec. The failure domain
+// should be the ID of the host so that the providerID doesn't change with the host endpoint.
 func (m *MachineScope) SetProviderID(failureDomain, mvmUID string) {
 	providerID := fmt.Sprintf("%s%s/%s", ProviderPrefix, failureDomain, mvmUID)
 	m.MvmMachine.Spec.ProviderID = &providerID
@@ -295,92 +353,34 @@ func (m *MachineScope) GetInstanceID() string {
 }
 
 // GetSSHPublicKeys will return the SSH public keys for this machine. It will take into account
-// precedence rules. If there are no keys then nil will be returned.
+// the SSHPublicKeysPolicy of the cluster. If ResolveSSHPublicKeys has been called the keys from
+// the referenced Secrets and ConfigMaps are included. If there are no keys then nil will be returned.
 func (m *MachineScope) GetSSHPublicKeys() []microvm.SSHPublicKey {
-	if len(m.MvmMachine.Spec.SSHPublicKeys) != 0 {
-		return m.MvmMachine.Spec.SSHPublicKeys
+	if m.sshPublicKeys != nil {
+		return m.sshPublicKeys
 	}
 
-	if len(m.MvmCluster.Spec.SSHPublicKeys) != 0 {
-		return m.MvmCluster.Spec.SSHPublicKeys
-	}
-
-	return nil
+	return combineSSHPublicKeys(
+		m.MvmCluster.Spec.SSHPublicKeysPolicy,
+		mergeSSHPublicKeys(m.MvmCluster.Spec.SSHPublicKeys),
+		mergeSSHPublicKeys(m.MvmMachine.Spec.SSHPublicKeys),
+	)
 }
 
-// GetBasicAuthToken will fetch the BasicAuthSecret on the MvmCluster and
+// GetBasicAuthToken will fetch the BasicAuthSecret on the MvmCluster (or its identity) and
 // and return the token for the given host.
 // If no secret or no value is found, an empty string is returned.
 func (m *MachineScope) GetBasicAuthToken(addr string) (string, error) {
-	placement := m.MvmCluster.Spec.Placement
-	if placement.StaticPool == nil || placement.StaticPool.BasicAuthSecret == "" {
-		return "", nil
-	}
-
-	tokenSecret := &corev1.Secret{}
-	key := types.NamespacedName{
-		Name:      m.MvmCluster.Spec.Placement.StaticPool.BasicAuthSecret,
-		Namespace: m.MvmCluster.Namespace,
-	}
-
-	if err := m.client.Get(m.ctx, key, tokenSecret); err != nil {
-		return "", err
-	}
-
-	host := strings.Split(addr, ":")[0]
-	// If it's not there, that's fine; we will log and return an empty string
-	token := string(tokenSecret.Data[host])
-
-	if token == "" {
-		m.Info(
-			"basicAuthToken for host not found in secret", "secret", tokenSecret.Name, "host", host,
-		)
-	}
-
-	return token, nil
+	return getBasicAuthToken(m.ctx, m.client, m.MvmCluster, m.identityNamespace, addr, m.Logger)
 }
 
-// GetTLSConfig will fetch the TLSSecretRef and CASecretRef on the MvmCluster
-// and return the TLS config for the client.
-// If either are not set, it will be assumed that the hosts are not
+// GetTLSConfig will fetch the TLS secret for the host (or the TLSSecretRef on the MvmCluster
+// or its identity if the host doesn't have one) and return the TLS config for the client.
+// If none are set, it will be assumed that the host is not
 // configured will TLS and all client calls will be made without credentials.
-func (m *MachineScope) GetTLSConfig() (*flclient.TLSConfig, error) {
-	if m.MvmCluster.Spec.TLSSecretRef == "" {
-		m.Info("no TLS configuration found. will create insecure connection")
-
-		return nil, nil
-	}
-
-	secretKey := types.NamespacedName{
-		Name:      m.MvmCluster.Spec.TLSSecretRef,
-		Namespace: m.MvmCluster.Namespace,
-	}
-
-	tlsSecret := &corev1.Secret{}
-	if err := m.client.Get(context.TODO(), secretKey, tlsSecret); err != nil {
-		return nil, err
-	}
-
-	certBytes, ok := tlsSecret.Data[tlsCert]
-	if !ok {
-		return nil, &tlsError{tlsCert}
-	}
-
-	keyBytes, ok := tlsSecret.Data[tlsKey]
-	if !ok {
-		return nil, &tlsError{tlsKey}
-	}
-
-	caBytes, ok := tlsSecret.Data[caCert]
-	if !ok {
-		return nil, &tlsError{caCert}
-	}
-
-	return &flclient.TLSConfig{
-		Cert:   certBytes,
-		Key:    keyBytes,
-		CACert: caBytes,
-	}, nil
+// The secret is read each time so rotated certificates are used for the next connection.
+func (m *MachineScope) GetTLSConfig(addr string) (*flclient.TLSConfig, error) {
+	return getHostTLSConfig(m.ctx, m.client, m.MvmCluster, m.identityNamespace, addr, m.Logger)
 }
 
 func (m *MachineScope) getFailureDomainFromProviderID(providerID string) string {
@@ -388,8 +388,14 @@ func (m *MachineScope) getFailureDomainFromProviderID(providerID string) string
 		return ""
 	}
 
-	providerID = strings.ReplaceAll(providerID, ProviderPrefix, "")
-	parts := strings.Split(providerID, "/")
+	// The failure domain is the host endpoint, which can't contain a slash but can contain
+	// colons and brackets for IPv6 addresses (e.g. microvm://[fd00::1]:9090/abcdef).
+	providerID = strings.TrimPrefix(providerID, ProviderPrefix)
+
+	lastSlashIndex := strings.LastIndex(providerID, "/")
+	if lastSlashIndex == -1 {
+		return ""
+	}
 
-	return parts[0]
+	return providerID[:lastSlashIndex]
 }
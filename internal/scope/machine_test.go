@@ -3,6 +3,7 @@ package scope_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 
@@ -13,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -175,6 +177,62 @@ func TestMachineGetBasicAuthToken(t *testing.T) {
 	}
 }
 
+func TestMachineGetBasicAuthTokenIPv6(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	secretName := "testsecret"
+
+	mvmCluster := newMicrovmClusterWithSpec(clusterName, v1alpha1.MicrovmClusterSpec{
+		Placement: infrav1.Placement{
+			StaticPool: &infrav1.StaticPoolPlacement{
+				BasicAuthSecret: secretName,
+			},
+		},
+	})
+	secret := newSecret(secretName, map[string][]byte{
+		"fd00--1":  []byte("ipv6"),
+		"10.0.0.1": []byte("ipv4"),
+		"myhost":   []byte("hostname"),
+	})
+
+	tt := []struct {
+		name     string
+		endpoint string
+		expected string
+	}{
+		{name: "ipv6 endpoint", endpoint: "[fd00::1]:9090", expected: "ipv6"},
+		{name: "non canonical ipv6 endpoint", endpoint: "[fd00:0:0::0001]:9090", expected: "ipv6"},
+		{name: "ipv6 endpoint without port", endpoint: "[fd00::1]", expected: "ipv6"},
+		{name: "ipv4 endpoint", endpoint: "10.0.0.1:9090", expected: "ipv4"},
+		{name: "hostname endpoint", endpoint: "myhost:9090", expected: "hostname"},
+		{name: "hostname without port", endpoint: "myhost", expected: "hostname"},
+		{name: "unknown ipv6 endpoint", endpoint: "[2001:db8::1]:9090", expected: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(mvmCluster, secret).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        &clusterv1.Cluster{},
+				MicroVMCluster: mvmCluster,
+				Machine:        &clusterv1.Machine{},
+				MicroVMMachine: &infrav1.MicrovmMachine{},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			token, err := machineScope.GetBasicAuthToken(tc.endpoint)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal(tc.expected))
+		})
+	}
+}
+
 func TestMachineGetTLSConfig(t *testing.T) {
 	RegisterTestingT(t)
 
@@ -183,24 +241,41 @@ func TestMachineGetTLSConfig(t *testing.T) {
 
 	clusterName := "testcluster"
 	tlsSecretName := "testtlssecret"
+	hostTLSSecretName := "hosttlssecret"
+	hostName := "127.0.0.1:9090"
 
 	mvmCluster := newMicrovmClusterWithSpec(clusterName, v1alpha1.MicrovmClusterSpec{
 		TLSSecretRef: tlsSecretName,
 	})
+	hostTLSCluster := newMicrovmClusterWithSpec(clusterName, v1alpha1.MicrovmClusterSpec{
+		TLSSecretRef: tlsSecretName,
+		Placement: v1alpha1.Placement{
+			StaticPool: &v1alpha1.StaticPoolPlacement{
+				Hosts: []v1alpha1.MicrovmHost{{Endpoint: hostName, TLSSecretRef: hostTLSSecretName}},
+			},
+		},
+	})
 	otherClusterNoTLS := newMicrovmCluster(clusterName)
 
-	tlsData := map[string][]byte{
-		"tls.crt": []byte("foo"),
-		"tls.key": []byte("bar"),
-		"ca.crt":  []byte("baz"),
-	}
+	tlsData := newTLSData(time.Now().Add(24 * time.Hour))
 	tlsSecret := newSecret(tlsSecretName, tlsData)
 
+	hostTLSData := newTLSData(time.Now().Add(24 * time.Hour))
+	hostTLSSecret := newSecret(hostTLSSecretName, hostTLSData)
+
+	expiredTLSSecret := newSecret(tlsSecretName, newTLSData(time.Now().Add(-time.Hour)))
+
 	badData := map[string][]byte{
 		"not": []byte("great"),
 	}
 	otherTLSSecret := newSecret(tlsSecretName, badData)
 
+	notPEMSecret := newSecret(tlsSecretName, map[string][]byte{
+		"tls.crt": []byte("foo"),
+		"tls.key": []byte("bar"),
+		"ca.crt":  []byte("baz"),
+	})
+
 	tt := []struct {
 		name        string
 		expected    func(*flclient.TLSConfig, error)
@@ -216,9 +291,21 @@ func TestMachineGetTLSConfig(t *testing.T) {
 			expected: func(cfg *flclient.TLSConfig, err error) {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(cfg).ToNot(BeNil())
-				Expect(cfg.Cert).To(Equal([]byte("foo")))
-				Expect(cfg.Key).To(Equal([]byte("bar")))
-				Expect(cfg.CACert).To(Equal([]byte("baz")))
+				Expect(cfg.Cert).To(Equal(tlsData["tls.crt"]))
+				Expect(cfg.Key).To(Equal(tlsData["tls.key"]))
+				Expect(cfg.CACert).To(Equal(tlsData["ca.crt"]))
+			},
+		},
+		{
+			name: "returns the TLS config from the host secret when set",
+			initObjects: []client.Object{
+				hostTLSCluster, tlsSecret, hostTLSSecret,
+			},
+			cluster: hostTLSCluster,
+			expected: func(cfg *flclient.TLSConfig, err error) {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfg).ToNot(BeNil())
+				Expect(cfg.Cert).To(Equal(hostTLSData["tls.crt"]))
 			},
 		},
 		{
@@ -252,6 +339,26 @@ func TestMachineGetTLSConfig(t *testing.T) {
 				Expect(err).To(HaveOccurred())
 			},
 		},
+		{
+			name: "when the certificate is not PEM encoded, returns an error",
+			initObjects: []client.Object{
+				mvmCluster, notPEMSecret,
+			},
+			cluster: mvmCluster,
+			expected: func(cfg *flclient.TLSConfig, err error) {
+				Expect(err).To(HaveOccurred())
+			},
+		},
+		{
+			name: "when the certificate has expired, returns an error",
+			initObjects: []client.Object{
+				mvmCluster, expiredTLSSecret,
+			},
+			cluster: mvmCluster,
+			expected: func(cfg *flclient.TLSConfig, err error) {
+				Expect(err).To(MatchError(ContainSubstring("expired")))
+			},
+		},
 	}
 
 	for _, tc := range tt {
@@ -267,7 +374,7 @@ func TestMachineGetTLSConfig(t *testing.T) {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			tc.expected(machineScope.GetTLSConfig())
+			tc.expected(machineScope.GetTLSConfig(hostName))
 		})
 	}
 }
@@ -387,6 +494,46 @@ func TestMachineFailureDomainFromProviderID(t *testing.T) {
 	Expect(failureDomain).To(Equal("fd2"))
 }
 
+func TestMachineProviderIDIPv6(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	failureDomain := "[fd00::1]:9090"
+
+	clusterName := "testcluster"
+	cluster := newCluster(clusterName, []string{failureDomain})
+	mvmCluster := newMicrovmCluster(clusterName)
+
+	machineName := "machine-1"
+	machine := newMachine(clusterName, machineName)
+	mvmMachine := newMicrovmMachine(clusterName, machineName, "")
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, mvmCluster, machine, mvmMachine).Build()
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:         client,
+		Cluster:        cluster,
+		MicroVMCluster: mvmCluster,
+		Machine:        machine,
+		MicroVMMachine: mvmMachine,
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	machineScope.SetProviderID(failureDomain, "abcdef")
+	Expect(machineScope.GetProviderID()).To(Equal("microvm://[fd00::1]:9090/abcdef"))
+
+	providerID, err := scope.NewProviderID(machineScope.GetProviderID())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(providerID.CloudProvider()).To(Equal("microvm"))
+	Expect(machineScope.GetInstanceID()).To(Equal("abcdef"))
+
+	machine.Spec.FailureDomain = nil
+	fd, err := machineScope.GetFailureDomain()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(fd).To(Equal(failureDomain))
+}
+
 func setupScheme() (*runtime.Scheme, error) {
 	scheme := runtime.NewScheme()
 	if err := infrav1.AddToScheme(scheme); err != nil {
@@ -398,6 +545,9 @@ func setupScheme() (*runtime.Scheme, error) {
 	if err := corev1.AddToScheme(scheme); err != nil {
 		return nil, err
 	}
+	if err := ipamv1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
 	return scheme, nil
 }
 
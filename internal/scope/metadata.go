@@ -0,0 +1,50 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import "strconv"
+
+// These constants are the instance metadata keys that the provider injects into
+// every microvm. They are available in the guest via ds.meta_data.<key>.
+const (
+	// MetadataClusterNameKey is the name of the CAPI cluster the microvm belongs to.
+	MetadataClusterNameKey = "cluster_name"
+	// MetadataClusterNamespaceKey is the namespace of the CAPI cluster and MicrovmMachine.
+	MetadataClusterNamespaceKey = "cluster_namespace"
+	// MetadataMachineNameKey is the name of the CAPI machine that owns the MicrovmMachine.
+	MetadataMachineNameKey = "machine_name"
+	// MetadataFailureDomainKey is the failure domain (i.e. host) the microvm has been placed in.
+	MetadataFailureDomainKey = "failure_domain"
+	// MetadataControlPlaneKey is "true" if the microvm is a control plane node, otherwise "false".
+	MetadataControlPlaneKey = "control_plane"
+	// MetadataLabelsKey is a map containing the labels of the MicrovmMachine.
+	MetadataLabelsKey = "labels"
+)
+
+// GetInstanceMetadata returns the additional instance metadata that should be made
+// available to the microvm when it's placed in the supplied failure domain. The user
+// supplied values from the MicrovmMachine spec are included but can't override the
+// keys set by the provider.
+func (m *MachineScope) GetInstanceMetadata(failureDomain string) map[string]interface{} {
+	metadata := map[string]interface{}{}
+
+	for k, v := range m.MvmMachine.Spec.InstanceMetadata {
+		metadata[k] = v
+	}
+
+	metadata[MetadataClusterNameKey] = m.ClusterName()
+	metadata[MetadataClusterNamespaceKey] = m.Namespace()
+	metadata[MetadataMachineNameKey] = m.Machine.Name
+	metadata[MetadataFailureDomainKey] = failureDomain
+	metadata[MetadataControlPlaneKey] = strconv.FormatBool(m.IsControlPlane())
+
+	labels := map[string]string{}
+	for k, v := range m.MvmMachine.Labels {
+		labels[k] = v
+	}
+
+	metadata[MetadataLabelsKey] = labels
+
+	return metadata
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestMachineGetInstanceMetadata(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterName := "testcluster"
+	machineName := "machine-1"
+
+	tt := []struct {
+		name         string
+		controlPlane bool
+		userMetadata map[string]string
+		expected     map[string]interface{}
+	}{
+		{
+			name: "worker machine without user metadata",
+			expected: map[string]interface{}{
+				"cluster_name":      clusterName,
+				"cluster_namespace": "default",
+				"machine_name":      machineName,
+				"failure_domain":    "fd1",
+				"control_plane":     "false",
+				"labels": map[string]string{
+					clusterv1.ClusterNameLabel: clusterName,
+				},
+			},
+		},
+		{
+			name:         "control plane machine",
+			controlPlane: true,
+			expected: map[string]interface{}{
+				"cluster_name":      clusterName,
+				"cluster_namespace": "default",
+				"machine_name":      machineName,
+				"failure_domain":    "fd1",
+				"control_plane":     "true",
+				"labels": map[string]string{
+					clusterv1.ClusterNameLabel: clusterName,
+				},
+			},
+		},
+		{
+			name: "user metadata is added but can't override provider keys",
+			userMetadata: map[string]string{
+				"rack":         "r42",
+				"cluster_name": "not-my-cluster",
+			},
+			expected: map[string]interface{}{
+				"rack":              "r42",
+				"cluster_name":      clusterName,
+				"cluster_namespace": "default",
+				"machine_name":      machineName,
+				"failure_domain":    "fd1",
+				"control_plane":     "false",
+				"labels": map[string]string{
+					clusterv1.ClusterNameLabel: clusterName,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			cluster := newCluster(clusterName, []string{"fd1"})
+			mvmCluster := newMicrovmCluster(clusterName)
+			machine := newMachine(clusterName, machineName)
+			mvmMachine := newMicrovmMachine(clusterName, machineName, "")
+			mvmMachine.Spec.InstanceMetadata = tc.userMetadata
+
+			if tc.controlPlane {
+				machine.Labels[clusterv1.MachineControlPlaneLabel] = ""
+			}
+
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{
+				cluster, mvmCluster, machine, mvmMachine,
+			}...).Build()
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        cluster,
+				MicroVMCluster: mvmCluster,
+				Machine:        machine,
+				MicroVMMachine: mvmMachine,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(machineScope.GetInstanceMetadata("fd1")).To(Equal(tc.expected))
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	flintlocktypes "github.com/liquidmetal-dev/flintlock/api/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/macaddress"
+)
+
+// GetNetworkOverrides returns the network interface overrides for the host in the supplied
+// failure domain. Interfaces connected to a named network are attached to the bridge the host
+// maps the network to, followed by any overrides set explicitly for the host. No overrides are
+// returned if the host can't be found in the placement.
+func (m *MachineScope) GetNetworkOverrides(failureDomain string) ([]flintlock.InterfaceOverride, error) {
+	host := m.GetHost(failureDomain)
+	if host == nil {
+		return nil, nil
+	}
+
+	overrides, err := m.getHostNetworkOverrides(*host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hostOverride := range host.NetworkOverrides {
+		override := flintlock.InterfaceOverride{
+			DeviceID:   hostOverride.GuestDeviceName,
+			BridgeName: hostOverride.BridgeName,
+		}
+
+		switch hostOverride.Type {
+		case microvm.IfaceTypeMacvtap:
+			ifaceType := flintlocktypes.NetworkInterface_MACVTAP
+			override.Type = &ifaceType
+		case microvm.IfaceTypeTap:
+			ifaceType := flintlocktypes.NetworkInterface_TAP
+			override.Type = &ifaceType
+		}
+
+		overrides = append(overrides, override)
+	}
+
+	return overrides, nil
+}
+
+// GetGuestNetworkConfig returns the additional guest network configuration (i.e. VLANs, routes,
+// MTU and DNS) for the machine's network interfaces.
+func (m *MachineScope) GetGuestNetworkConfig() []flintlock.InterfaceConfig {
+	configs := []flintlock.InterfaceConfig{}
+
+	for _, cfg := range m.MvmMachine.Spec.NetworkInterfaceConfigs {
+		ifaceConfig := flintlock.InterfaceConfig{
+			DeviceID:    cfg.GuestDeviceName,
+			Gateway:     cfg.Gateway,
+			Nameservers: cfg.Nameservers,
+		}
+
+		if cfg.VLANID != nil {
+			ifaceConfig.VLANID = *cfg.VLANID
+		}
+
+		if cfg.MTU != nil {
+			ifaceConfig.MTU = *cfg.MTU
+		}
+
+		for _, route := range cfg.Routes {
+			ifaceConfig.Routes = append(ifaceConfig.Routes, flintlock.Route{
+				To:     route.To,
+				Via:    route.Via,
+				Metric: route.Metric,
+			})
+		}
+
+		configs = append(configs, ifaceConfig)
+	}
+
+	return configs
+}
+
+func (m *MachineScope) getHostNetworkOverrides(host infrav1.MicrovmHost) ([]flintlock.InterfaceOverride, error) {
+	bridges := map[string]string{}
+	for _, network := range host.Networks {
+		bridges[network.Name] = network.BridgeName
+	}
+
+	overrides := []flintlock.InterfaceOverride{}
+
+	for _, cfg := range m.MvmMachine.Spec.NetworkInterfaceConfigs {
+		if cfg.Network == "" {
+			continue
+		}
+
+		bridge, ok := bridges[cfg.Network]
+		if !ok {
+			return nil, &networkNotFoundError{network: cfg.Network, host: host.Endpoint}
+		}
+
+		ifaceType := flintlocktypes.NetworkInterface_TAP
+		overrides = append(overrides, flintlock.InterfaceOverride{
+			DeviceID:   cfg.GuestDeviceName,
+			Type:       &ifaceType,
+			BridgeName: bridge,
+		})
+	}
+
+	return overrides, nil
+}
+
+// GetDuplicateMACAddresses returns the MAC addresses of the machine's network interfaces that
+// are already used by another microvm on the host in the supplied failure domain.
+func (m *MachineScope) GetDuplicateMACAddresses(failureDomain string) ([]string, error) {
+	macs := map[string]bool{}
+
+	for _, iface := range m.MvmMachine.Spec.NetworkInterfaces {
+		if iface.GuestMAC != "" {
+			macs[macaddress.Normalize(iface.GuestMAC)] = true
+		}
+	}
+
+	if len(macs) == 0 {
+		return nil, nil
+	}
+
+	endpoint := m.GetHostEndpoint(failureDomain)
+
+	machines := &infrav1.MicrovmMachineList{}
+	if err := m.client.List(m.ctx, machines); err != nil {
+		return nil, fmt.Errorf("listing microvm machines: %w", err)
+	}
+
+	duplicates := []string{}
+
+	for _, other := range machines.Items {
+		if other.Namespace == m.Namespace() && other.Name == m.Name() {
+			continue
+		}
+
+		if m.getMachineHostEndpoint(&other) != endpoint {
+			continue
+		}
+
+		for _, iface := range other.Spec.NetworkInterfaces {
+			mac := macaddress.Normalize(iface.GuestMAC)
+			if macs[mac] {
+				duplicates = append(duplicates, mac)
+				delete(macs, mac)
+			}
+		}
+	}
+
+	sort.Strings(duplicates)
+
+	return duplicates, nil
+}
+
+// getMachineHostEndpoint returns the endpoint of the host that another microvm machine has been
+// placed on, or an empty string if it hasn't been placed yet. Host IDs are only unique within a
+// cluster so they are only resolved for machines in the same cluster. Machines in other clusters
+// use the endpoint they recorded, falling back to the host ID which is the endpoint of hosts
+// without a name.
+func (m *MachineScope) getMachineHostEndpoint(other *infrav1.MicrovmMachine) string {
+	sameCluster := other.Namespace == m.Namespace() && other.Labels[clusterv1.ClusterNameLabel] == m.ClusterName()
+
+	if endpoint := other.GetAnnotations()[infrav1.HostEndpointAnnotation]; endpoint != "" && !sameCluster {
+		return endpoint
+	}
+
+	hostID := other.GetAnnotations()[infrav1.HostIDAnnotation]
+	if hostID == "" && other.Spec.ProviderID != nil {
+		hostID = m.getFailureDomainFromProviderID(*other.Spec.ProviderID)
+	}
+
+	if hostID == "" {
+		return ""
+	}
+
+	if !sameCluster {
+		return hostID
+	}
+
+	return m.GetHostEndpoint(hostID)
+}
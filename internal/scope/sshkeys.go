@@ -0,0 +1,179 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"fmt"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+)
+
+// ResolveSSHPublicKeys reads the SSH public keys referenced by the cluster and the machine
+// and combines them with the inline keys. The result is returned by GetSSHPublicKeys. The keys
+// are read each time so that changes are used by the next microvm that is created.
+func (m *MachineScope) ResolveSSHPublicKeys() error {
+	clusterKeys, err := m.getSSHPublicKeysFrom(m.MvmCluster.Spec.SSHPublicKeysFrom)
+	if err != nil {
+		return err
+	}
+
+	machineKeys, err := m.getSSHPublicKeysFrom(m.MvmMachine.Spec.SSHPublicKeysFrom)
+	if err != nil {
+		return err
+	}
+
+	keys := combineSSHPublicKeys(
+		m.MvmCluster.Spec.SSHPublicKeysPolicy,
+		mergeSSHPublicKeys(m.MvmCluster.Spec.SSHPublicKeys, clusterKeys),
+		mergeSSHPublicKeys(m.MvmMachine.Spec.SSHPublicKeys, machineKeys),
+	)
+
+	if keys == nil {
+		keys = []microvm.SSHPublicKey{}
+	}
+
+	m.sshPublicKeys = keys
+
+	return nil
+}
+
+// getSSHPublicKeysFrom reads the keys from the referenced Secrets and ConfigMaps. Missing
+// references that are marked as optional are skipped.
+func (m *MachineScope) getSSHPublicKeysFrom(sources []infrav1.SSHPublicKeySource) ([]microvm.SSHPublicKey, error) {
+	keys := []microvm.SSHPublicKey{}
+
+	for _, source := range sources {
+		data, found, err := m.getSSHPublicKeysData(source)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			continue
+		}
+
+		authorizedKeys, err := infrav1.ParseAuthorizedKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ssh public keys for user %s: %w", source.User, err)
+		}
+
+		keys = append(keys, microvm.SSHPublicKey{User: source.User, AuthorizedKeys: authorizedKeys})
+	}
+
+	return keys, nil
+}
+
+// getSSHPublicKeysData returns the value of the key referenced by the source. False is
+// returned if an optional reference doesn't exist.
+func (m *MachineScope) getSSHPublicKeysData(source infrav1.SSHPublicKeySource) (string, bool, error) {
+	switch {
+	case source.SecretKeyRef != nil:
+		ref := source.SecretKeyRef
+		secret := &corev1.Secret{}
+
+		if err := m.client.Get(m.ctx, types.NamespacedName{Namespace: m.Namespace(), Name: ref.Name}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				return "", false, missingSSHPublicKeys("secret", ref.Name, "", ref.Optional)
+			}
+
+			return "", false, fmt.Errorf("getting ssh public keys secret %s: %w", ref.Name, err)
+		}
+
+		data, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", false, missingSSHPublicKeys("secret", ref.Name, ref.Key, ref.Optional)
+		}
+
+		return string(data), true, nil
+	case source.ConfigMapKeyRef != nil:
+		ref := source.ConfigMapKeyRef
+		configMap := &corev1.ConfigMap{}
+
+		if err := m.client.Get(m.ctx, types.NamespacedName{Namespace: m.Namespace(), Name: ref.Name}, configMap); err != nil {
+			if apierrors.IsNotFound(err) {
+				return "", false, missingSSHPublicKeys("configmap", ref.Name, "", ref.Optional)
+			}
+
+			return "", false, fmt.Errorf("getting ssh public keys configmap %s: %w", ref.Name, err)
+		}
+
+		data, ok := configMap.Data[ref.Key]
+		if !ok {
+			return "", false, missingSSHPublicKeys("configmap", ref.Name, ref.Key, ref.Optional)
+		}
+
+		return data, true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// missingSSHPublicKeys returns the error for a missing Secret or ConfigMap, or a missing key
+// if key is set. Nil is returned if the reference is optional.
+func missingSSHPublicKeys(kind, name, key string, optional *bool) error {
+	if optional != nil && *optional {
+		return nil
+	}
+
+	return &sshPublicKeysMissingError{kind: kind, name: name, key: key}
+}
+
+// mergeSSHPublicKeys combines lists of keys so that there is one entry for each user, in the
+// order the users first appear, with duplicate keys removed.
+func mergeSSHPublicKeys(lists ...[]microvm.SSHPublicKey) []microvm.SSHPublicKey {
+	var merged []microvm.SSHPublicKey
+
+	users := map[string]int{}
+	seen := map[string]map[string]bool{}
+
+	for _, list := range lists {
+		for _, key := range list {
+			i, ok := users[key.User]
+			if !ok {
+				i = len(merged)
+				users[key.User] = i
+				seen[key.User] = map[string]bool{}
+
+				merged = append(merged, microvm.SSHPublicKey{User: key.User})
+			}
+
+			for _, authorizedKey := range key.AuthorizedKeys {
+				if seen[key.User][authorizedKey] {
+					continue
+				}
+
+				seen[key.User][authorizedKey] = true
+				merged[i].AuthorizedKeys = append(merged[i].AuthorizedKeys, authorizedKey)
+			}
+		}
+	}
+
+	return merged
+}
+
+// combineSSHPublicKeys combines the keys of the cluster and the machine using the policy.
+func combineSSHPublicKeys(
+	policy infrav1.SSHPublicKeysPolicy,
+	clusterKeys []microvm.SSHPublicKey,
+	machineKeys []microvm.SSHPublicKey,
+) []microvm.SSHPublicKey {
+	if policy == infrav1.SSHPublicKeysPolicyMerge {
+		return mergeSSHPublicKeys(clusterKeys, machineKeys)
+	}
+
+	if len(machineKeys) != 0 {
+		return machineKeys
+	}
+
+	if len(clusterKeys) != 0 {
+		return clusterKeys
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+)
+
+func TestMachineResolveSSHPublicKeys(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterKey := newSSHPublicKey(1)
+	machineKey := newSSHPublicKey(2)
+	secretKey := newSSHPublicKey(3)
+
+	keysSecret := newSecret("keys", map[string][]byte{"ubuntu": []byte(secretKey + "\n# comment\n")})
+	invalidConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "default"},
+		Data:       map[string]string{"ubuntu": clusterKey + "\nnot-a-key\n"},
+	}
+
+	fromSecret := func(name, key string, optional bool) infrav1.SSHPublicKeySource {
+		return infrav1.SSHPublicKeySource{
+			User: "ubuntu",
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  key,
+				Optional:             pointer.Bool(optional),
+			},
+		}
+	}
+
+	tt := []struct {
+		name        string
+		policy      infrav1.SSHPublicKeysPolicy
+		clusterKeys []microvm.SSHPublicKey
+		clusterFrom []infrav1.SSHPublicKeySource
+		machineKeys []microvm.SSHPublicKey
+		machineFrom []infrav1.SSHPublicKeySource
+		initObjects []client.Object
+		expected    []microvm.SSHPublicKey
+		expectedErr string
+	}{
+		{
+			name:        "machine keys replace the cluster keys by default",
+			clusterKeys: []microvm.SSHPublicKey{{User: "ubuntu", AuthorizedKeys: []string{clusterKey}}},
+			machineKeys: []microvm.SSHPublicKey{{User: "root", AuthorizedKeys: []string{machineKey}}},
+			expected:    []microvm.SSHPublicKey{{User: "root", AuthorizedKeys: []string{machineKey}}},
+		},
+		{
+			name:        "cluster keys are used when the machine doesn't have any",
+			clusterKeys: []microvm.SSHPublicKey{{User: "ubuntu", AuthorizedKeys: []string{clusterKey}}},
+			clusterFrom: []infrav1.SSHPublicKeySource{fromSecret("keys", "ubuntu", false)},
+			initObjects: []client.Object{keysSecret},
+			expected:    []microvm.SSHPublicKey{{User: "ubuntu", AuthorizedKeys: []string{clusterKey, secretKey}}},
+		},
+		{
+			name:        "keys are merged per user",
+			policy:      infrav1.SSHPublicKeysPolicyMerge,
+			clusterKeys: []microvm.SSHPublicKey{{User: "ubuntu", AuthorizedKeys: []string{clusterKey}}},
+			machineKeys: []microvm.SSHPublicKey{
+				{User: "root", AuthorizedKeys: []string{machineKey}},
+				{User: "ubuntu", AuthorizedKeys: []string{machineKey, clusterKey}},
+			},
+			machineFrom: []infrav1.SSHPublicKeySource{fromSecret("keys", "ubuntu", false)},
+			initObjects: []client.Object{keysSecret},
+			expected: []microvm.SSHPublicKey{
+				{User: "ubuntu", AuthorizedKeys: []string{clusterKey, machineKey, secretKey}},
+				{User: "root", AuthorizedKeys: []string{machineKey}},
+			},
+		},
+		{
+			name:        "optional references that don't exist are skipped",
+			clusterFrom: []infrav1.SSHPublicKeySource{fromSecret("missing", "ubuntu", true), fromSecret("keys", "root", true)},
+			initObjects: []client.Object{keysSecret},
+			expected:    []microvm.SSHPublicKey{},
+		},
+		{
+			name:        "a missing secret is an error",
+			clusterFrom: []infrav1.SSHPublicKeySource{fromSecret("missing", "ubuntu", false)},
+			expectedErr: "ssh public keys secret missing not found",
+		},
+		{
+			name:        "a missing key is an error",
+			machineFrom: []infrav1.SSHPublicKeySource{fromSecret("keys", "root", false)},
+			initObjects: []client.Object{keysSecret},
+			expectedErr: "ssh public keys secret keys is missing key root",
+		},
+		{
+			name: "invalid keys are an error",
+			machineFrom: []infrav1.SSHPublicKeySource{{
+				User: "ubuntu",
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "invalid"},
+					Key:                  "ubuntu",
+				},
+			}},
+			initObjects: []client.Object{invalidConfigMap},
+			expectedErr: "parsing ssh public keys for user ubuntu: line 2: no supported key type found",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{
+				SSHPublicKeys:       tc.clusterKeys,
+				SSHPublicKeysFrom:   tc.clusterFrom,
+				SSHPublicKeysPolicy: tc.policy,
+			})
+			mvmMachine := newMicrovmMachine("testcluster", "machine1", "")
+			mvmMachine.Spec.SSHPublicKeys = tc.machineKeys
+			mvmMachine.Spec.SSHPublicKeysFrom = tc.machineFrom
+
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.initObjects...).Build()
+
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:         client,
+				Cluster:        &clusterv1.Cluster{},
+				MicroVMCluster: mvmCluster,
+				Machine:        &clusterv1.Machine{},
+				MicroVMMachine: mvmMachine,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = machineScope.ResolveSSHPublicKeys()
+			if tc.expectedErr != "" {
+				Expect(err).To(MatchError(tc.expectedErr))
+
+				return
+			}
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(machineScope.GetSSHPublicKeys()).To(Equal(tc.expected))
+		})
+	}
+}
+
+// newSSHPublicKey returns a syntactically valid ed25519 public key in the authorized_keys format.
+func newSSHPublicKey(seed byte) string {
+	const (
+		keyType = "ssh-ed25519"
+		keySize = 32
+	)
+
+	blob := binary.BigEndian.AppendUint32(nil, uint32(len(keyType)))
+	blob = append(blob, keyType...)
+	blob = binary.BigEndian.AppendUint32(blob, keySize)
+	blob = append(blob, bytes.Repeat([]byte{seed}, keySize)...)
+
+	return fmt.Sprintf("%s %s user%d@example.com", keyType, base64.StdEncoding.EncodeToString(blob), seed)
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	flclient "github.com/liquidmetal-dev/controller-pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TLSCertificate is the client certificate used to connect to a microvm host.
+type TLSCertificate struct {
+	// HostID is the ID of the host.
+	HostID string
+	// Host is the endpoint of the host.
+	Host string
+	// SecretName is the name of the secret that contains the certificate.
+	SecretName string
+	// NotAfter is the time the certificate expires.
+	NotAfter time.Time
+}
+
+// GetTLSCertificates returns the client certificate used to connect to each of the hosts
+// in the placement. Hosts that don't use TLS are skipped. A certificate that can't be read
+// only affects the hosts that use it, so the certificates that could be read are returned
+// along with an error for the others.
+func (cs *ClusterScope) GetTLSCertificates(ctx context.Context) ([]TLSCertificate, error) {
+	staticPool := cs.MvmCluster.Spec.Placement.StaticPool
+	if staticPool == nil {
+		return nil, nil
+	}
+
+	source, err := getCredentialsSource(ctx, cs.client, cs.MvmCluster, cs.identityNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []TLSCertificate{}
+	expiries := map[string]time.Time{}
+	secretErrs := map[string]error{}
+	errs := []error{}
+
+	for _, host := range staticPool.Hosts {
+		secretName := source.tlsSecretName(host.Endpoint)
+		if secretName == "" {
+			continue
+		}
+
+		_, read := expiries[secretName]
+		_, failed := secretErrs[secretName]
+
+		if !read && !failed {
+			_, cert, err := getTLSConfig(ctx, cs.client, source.namespace, secretName)
+			if err != nil {
+				secretErrs[secretName] = err
+			} else {
+				expiries[secretName] = cert.NotAfter
+			}
+		}
+
+		if err := secretErrs[secretName]; err != nil {
+			errs = append(errs, fmt.Errorf("getting tls config for host %s: %w", host.Endpoint, err))
+
+			continue
+		}
+
+		certs = append(certs, TLSCertificate{
+			HostID:     host.ID(),
+			Host:       host.Endpoint,
+			SecretName: secretName,
+			NotAfter:   expiries[secretName],
+		})
+	}
+
+	return certs, errors.Join(errs...)
+}
+
+// getTLSConfig reads the TLS config from the secret and parses the client certificate.
+func getTLSConfig(
+	ctx context.Context,
+	c client.Client,
+	namespace, name string,
+) (*flclient.TLSConfig, *x509.Certificate, error) {
+	tlsSecret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, tlsSecret); err != nil {
+		return nil, nil, err
+	}
+
+	certBytes, ok := tlsSecret.Data[tlsCert]
+	if !ok {
+		return nil, nil, &tlsError{tlsCert}
+	}
+
+	keyBytes, ok := tlsSecret.Data[tlsKey]
+	if !ok {
+		return nil, nil, &tlsError{tlsKey}
+	}
+
+	caBytes, ok := tlsSecret.Data[caCert]
+	if !ok {
+		return nil, nil, &tlsError{caCert}
+	}
+
+	block, _ := pem.Decode(certBytes)
+	if block == nil {
+		return nil, nil, errInvalidTLSCertificate
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing tls certificate: %w", err)
+	}
+
+	return &flclient.TLSConfig{
+		Cert:   certBytes,
+		Key:    keyBytes,
+		CACert: caBytes,
+	}, cert, nil
+}
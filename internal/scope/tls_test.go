@@ -0,0 +1,135 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package scope_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/scope"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/tlstest"
+)
+
+func TestClusterGetTLSCertificates(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	clusterExpiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	hostExpiry := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{
+		TLSSecretRef: "cluster-tls",
+		Placement: infrav1.Placement{
+			StaticPool: &infrav1.StaticPoolPlacement{
+				Hosts: []infrav1.MicrovmHost{
+					{Endpoint: "10.0.0.1:9090"},
+					{Endpoint: "10.0.0.2:9090", TLSSecretRef: "host-tls"},
+					{Endpoint: "10.0.0.3:9090"},
+				},
+			},
+		},
+	})
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{
+		mvmCluster,
+		newSecret("cluster-tls", newTLSData(clusterExpiry)),
+		newSecret("host-tls", newTLSData(hostExpiry)),
+	}...).Build()
+
+	clusterScope, err := scope.NewClusterScope(&clusterv1.Cluster{}, mvmCluster, client)
+	Expect(err).NotTo(HaveOccurred())
+
+	certs, err := clusterScope.GetTLSCertificates(context.TODO())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(certs).To(ConsistOf(
+		scope.TLSCertificate{HostID: "10.0.0.1:9090", Host: "10.0.0.1:9090", SecretName: "cluster-tls", NotAfter: clusterExpiry.UTC()},
+		scope.TLSCertificate{HostID: "10.0.0.2:9090", Host: "10.0.0.2:9090", SecretName: "host-tls", NotAfter: hostExpiry.UTC()},
+		scope.TLSCertificate{HostID: "10.0.0.3:9090", Host: "10.0.0.3:9090", SecretName: "cluster-tls", NotAfter: clusterExpiry.UTC()},
+	))
+}
+
+func TestClusterGetTLSCertificatesInvalid(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{
+		Placement: infrav1.Placement{
+			StaticPool: &infrav1.StaticPoolPlacement{
+				Hosts: []infrav1.MicrovmHost{
+					{Endpoint: "10.0.0.1:9090", TLSSecretRef: "host-tls"},
+					{Endpoint: "10.0.0.2:9090", TLSSecretRef: "other-tls"},
+				},
+			},
+		},
+	})
+
+	otherExpiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{
+		mvmCluster,
+		newSecret("host-tls", map[string][]byte{"tls.crt": []byte("foo"), "tls.key": []byte("bar"), "ca.crt": []byte("baz")}),
+		newSecret("other-tls", newTLSData(otherExpiry)),
+	}...).Build()
+
+	clusterScope, err := scope.NewClusterScope(&clusterv1.Cluster{}, mvmCluster, client)
+	Expect(err).NotTo(HaveOccurred())
+
+	certs, err := clusterScope.GetTLSCertificates(context.TODO())
+	Expect(err).To(HaveOccurred())
+	Expect(err.Error()).To(ContainSubstring("10.0.0.1:9090"))
+	Expect(certs).To(ConsistOf(
+		scope.TLSCertificate{HostID: "10.0.0.2:9090", Host: "10.0.0.2:9090", SecretName: "other-tls", NotAfter: otherExpiry.UTC()},
+	), "expect the certificates of the other hosts to be returned")
+}
+
+func TestMachineGetTLSConfigRotated(t *testing.T) {
+	RegisterTestingT(t)
+
+	scheme, err := setupScheme()
+	Expect(err).NotTo(HaveOccurred())
+
+	mvmCluster := newMicrovmClusterWithSpec("testcluster", infrav1.MicrovmClusterSpec{TLSSecretRef: "cluster-tls"})
+	tlsSecret := newSecret("cluster-tls", newTLSData(time.Now().Add(time.Hour)))
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(mvmCluster, tlsSecret).Build()
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:         client,
+		Cluster:        &clusterv1.Cluster{},
+		MicroVMCluster: mvmCluster,
+		Machine:        &clusterv1.Machine{},
+		MicroVMMachine: &infrav1.MicrovmMachine{},
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	_, err = machineScope.GetTLSConfig("127.0.0.1:9090")
+	Expect(err).NotTo(HaveOccurred())
+
+	rotated := newTLSData(time.Now().Add(24 * time.Hour))
+	tlsSecret.Data = rotated
+	Expect(client.Update(context.TODO(), tlsSecret)).To(Succeed())
+
+	cfg, err := machineScope.GetTLSConfig("127.0.0.1:9090")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cfg.Cert).To(Equal(rotated["tls.crt"]), "expect the rotated certificate to be used")
+}
+
+// newTLSData returns the data for a TLS secret with a self signed client certificate.
+func newTLSData(notAfter time.Time) map[string][]byte {
+	data, err := tlstest.NewSecretData(notAfter)
+	Expect(err).NotTo(HaveOccurred())
+
+	return data
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package tracing exports OpenTelemetry traces of the reconciles and the calls to the flintlock
+// hosts. The spans are no-ops unless an OTLP endpoint is configured.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// DefaultServiceName is the name of the service the spans are exported for if a name isn't
+	// specified.
+	DefaultServiceName = "cluster-api-provider-microvm"
+	// DefaultSamplingRatio is the fraction of traces that are sampled if a ratio isn't specified.
+	DefaultSamplingRatio = 1.0
+
+	tracerName      = "github.com/liquidmetal-dev/cluster-api-provider-microvm"
+	shutdownTimeout = 5 * time.Second
+)
+
+var errInvalidSamplingRatio = errors.New("tracing sampling ratio must be between 0 and 1")
+
+// Config is the configuration of the exporter.
+type Config struct {
+	// Endpoint is the host and port of the OTLP gRPC collector. Tracing is off if it's empty.
+	Endpoint string
+	// Insecure connects to the collector without TLS.
+	Insecure bool
+	// SamplingRatio is the fraction of traces started by the controllers that are sampled.
+	// Traces are always sampled if their parent is.
+	SamplingRatio float64
+	// ServiceName is the name of the service the spans are exported for.
+	ServiceName string
+	// ServiceVersion is the version of the service the spans are exported for.
+	ServiceVersion string
+}
+
+// Enabled returns true if traces are exported.
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Provider exports the spans to the collector. It's added to the manager so the spans that
+// haven't been exported yet are flushed when it stops.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// New sets up the global tracer provider and propagator to export spans to the collector in the
+// configuration.
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.SamplingRatio < 0 || cfg.SamplingRatio > 1 {
+		return nil, fmt.Errorf("%w: %v", errInvalidSamplingRatio, cfg.SamplingRatio)
+	}
+
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating otlp trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion(cfg.ServiceVersion),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplingRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return &Provider{provider: provider}, nil
+}
+
+// Start waits for the context to be done and then exports the remaining spans.
+func (p *Provider) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := p.provider.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down tracer provider: %w", err)
+	}
+
+	return nil
+}
+
+// NeedLeaderElection returns false so spans are exported whether or not the manager is the
+// leader.
+func (p *Provider) NeedLeaderElection() bool {
+	return false
+}
+
+// Tracer returns the tracer used for the spans of the provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// End ends the span, recording the error if there is one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// StartReconcile starts the span of a reconcile of the object of the kind.
+func StartReconcile(ctx context.Context, kind string, name types.NamespacedName) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, kind+".Reconcile", trace.WithAttributes(
+		semconv.K8SNamespaceName(name.Namespace),
+		attribute.String("capmvm.kind", kind),
+		attribute.String("capmvm.name", name.Name),
+	))
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/tracing"
+)
+
+func TestConfigEnabled(t *testing.T) {
+	RegisterTestingT(t)
+
+	Expect(tracing.Config{}.Enabled()).To(BeFalse())
+	Expect(tracing.Config{Endpoint: "otel-collector:4317"}.Enabled()).To(BeTrue())
+}
+
+func TestNewInvalidSamplingRatio(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, ratio := range []float64{-0.1, 1.5} {
+		_, err := tracing.New(context.TODO(), tracing.Config{
+			Endpoint:      "otel-collector:4317",
+			SamplingRatio: ratio,
+		})
+		Expect(err).To(MatchError(ContainSubstring("sampling ratio must be between 0 and 1")))
+	}
+}
+
+func TestEnd(t *testing.T) {
+	RegisterTestingT(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	_, ok := tracer.Start(context.TODO(), "ok")
+	tracing.End(ok, nil)
+
+	_, failed := tracer.Start(context.TODO(), "failed")
+	tracing.End(failed, errors.New("host unavailable"))
+
+	spans := recorder.Ended()
+	Expect(spans).To(HaveLen(2))
+
+	Expect(spans[0].Status().Code).To(Equal(codes.Unset))
+	Expect(spans[0].Events()).To(BeEmpty())
+
+	Expect(spans[1].Status().Code).To(Equal(codes.Error))
+	Expect(spans[1].Status().Description).To(Equal("host unavailable"))
+	Expect(spans[1].Events()).To(HaveLen(1))
+}
+
+func TestStartReconcile(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctx, span := tracing.StartReconcile(context.TODO(), "MicrovmMachine", types.NamespacedName{
+		Namespace: "ns1",
+		Name:      "machine1",
+	})
+	defer span.End()
+
+	Expect(ctx).NotTo(BeNil())
+	Expect(span.SpanContext().IsValid()).To(BeFalse(), "spans should be no-ops when tracing is disabled")
+}
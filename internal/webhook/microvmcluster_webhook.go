@@ -6,21 +6,29 @@ package webhook
 import (
 	"fmt"
 	"context"
+	"errors"
+	"reflect"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	// "k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/identity"
 )
 
 var _ = logf.Log.WithName("mvmcluster-resource")
 
 
-type MicrovmCluster struct{}
+type MicrovmCluster struct {
+	// Client is used to check that the namespace of the cluster is allowed to use the
+	// MicrovmClusterIdentity it references. If not set the check is skipped.
+	Client client.Reader
+}
 
 func (r *MicrovmCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
@@ -39,13 +47,14 @@ var (
 )
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
-func (r *MicrovmCluster) ValidateCreate(_ context.Context, obj runtime.Object) (warnings admission.Warnings, err error) {
+func (r *MicrovmCluster) ValidateCreate(ctx context.Context, obj runtime.Object) (warnings admission.Warnings, err error) {
 	cluster, ok := obj.(*infrav1.MicrovmCluster)
 	if !ok {
 		return warnings, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmCluster but got %T", obj))
 	}
 
-	allErrs := cluster.Spec.Placement.Validate()
+	allErrs := cluster.Spec.Validate()
+	allErrs = append(allErrs, r.validateIdentity(ctx, cluster)...)
 	if len(allErrs) > 0 {
 		warnings = append(warnings, fmt.Sprintf("cannot create microvm cluster %s", cluster.GetName()))
 		return warnings, apierrors.NewInvalid(
@@ -64,10 +73,62 @@ func (r *MicrovmCluster) ValidateDelete(_ context.Context, _ runtime.Object) (ad
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
-func (r *MicrovmCluster) ValidateUpdate(_ context.Context, _ runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+func (r *MicrovmCluster) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	cluster, ok := newObj.(*infrav1.MicrovmCluster)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmCluster but got %T", newObj))
+	}
+	oldCluster, ok := oldObj.(*infrav1.MicrovmCluster)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmCluster but got %T", oldObj))
+	}
+
+	// Clusters that are being deleted aren't validated so that their finalizer can always be
+	// removed, even if they were created before a check was added.
+	if !cluster.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	allErrs := cluster.Spec.ValidateUpdate(&oldCluster.Spec)
+
+	// The identity may have been changed to no longer allow the namespace since the cluster
+	// started using it, which the controller reports, so it's only checked when it changes.
+	if !reflect.DeepEqual(cluster.Spec.IdentityRef, oldCluster.Spec.IdentityRef) {
+		allErrs = append(allErrs, r.validateIdentity(ctx, cluster)...)
+	}
+
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(
+			cluster.GroupVersionKind().GroupKind(),
+			cluster.Name,
+			allErrs,
+		)
+	}
+
 	return nil, nil
 }
 
+// validateIdentity checks that the namespace of the cluster is allowed to use the identity it
+// references. An identity that doesn't exist yet is allowed and reported by the controller.
+func (r *MicrovmCluster) validateIdentity(ctx context.Context, cluster *infrav1.MicrovmCluster) field.ErrorList {
+	if r.Client == nil || cluster.Spec.IdentityRef == nil {
+		return nil
+	}
+
+	path := field.NewPath("spec", "identityRef")
+
+	_, err := identity.Get(ctx, r.Client, cluster)
+
+	switch {
+	case err == nil, errors.Is(err, identity.ErrNotFound):
+		return nil
+	case errors.Is(err, identity.ErrNamespaceNotAllowed):
+		return field.ErrorList{field.Forbidden(path, err.Error())}
+	default:
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+}
+
 // Default satisfies the defaulting webhook interface.
 func (r *MicrovmCluster) Default(_ context.Context, obj runtime.Object) error {
 	_, ok := obj.(*infrav1.MicrovmCluster)
@@ -0,0 +1,232 @@
+// Copyright 2021 Weaveworks or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MPL-2.0
+
+package webhook_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/liquidmetal-dev/controller-pkg/types/microvm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/webhook"
+)
+
+func TestMicrovmClusterValidateUpdateIdentity(t *testing.T) {
+	notAllowed := &infrav1.MicrovmClusterIdentity{
+		ObjectMeta: metav1.ObjectMeta{Name: "not-allowed"},
+		Spec: infrav1.MicrovmClusterIdentitySpec{
+			AllowedNamespaces: &infrav1.AllowedNamespaces{NamespaceList: []string{"other"}},
+		},
+	}
+
+	tt := []struct {
+		name        string
+		oldIdentity *infrav1.MicrovmClusterIdentityReference
+		deleting    bool
+		expectErr   bool
+	}{
+		{
+			name:      "identity changed to one that doesn't allow the namespace",
+			expectErr: true,
+		},
+		{
+			name:        "identity no longer allows the namespace",
+			oldIdentity: &infrav1.MicrovmClusterIdentityReference{Name: "not-allowed"},
+			expectErr:   false,
+		},
+		{
+			name:      "identity changed while deleting",
+			deleting:  true,
+			expectErr: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			oldCluster := newMicrovmCluster()
+			oldCluster.Spec.IdentityRef = tc.oldIdentity
+
+			newCluster := oldCluster.DeepCopy()
+			newCluster.Labels = map[string]string{"updated": "true"}
+			newCluster.Spec.IdentityRef = &infrav1.MicrovmClusterIdentityReference{Name: "not-allowed"}
+
+			if tc.deleting {
+				newCluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				newCluster.Finalizers = []string{infrav1.ClusterFinalizer}
+			}
+
+			validator := &webhook.MicrovmCluster{Client: newFakeClient(notAllowed)}
+
+			_, err := validator.ValidateUpdate(context.TODO(), oldCluster, newCluster)
+			if tc.expectErr {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestMicrovmClusterValidateUpdateSSHPublicKeys(t *testing.T) {
+	const legacyKey = "ssh-rsa not-base64 legacy"
+
+	tt := []struct {
+		name      string
+		newKeys   []string
+		deleting  bool
+		expectErr bool
+	}{
+		{
+			name:      "legacy key unchanged",
+			newKeys:   []string{legacyKey},
+			expectErr: false,
+		},
+		{
+			name:      "invalid key added",
+			newKeys:   []string{legacyKey, "ssh-ed25519 not-base64 added"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid key added while deleting",
+			newKeys:   []string{legacyKey, "ssh-ed25519 not-base64 added"},
+			deleting:  true,
+			expectErr: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			oldCluster := newMicrovmCluster()
+			oldCluster.Spec.SSHPublicKeys = []microvm.SSHPublicKey{
+				{User: "root", AuthorizedKeys: []string{legacyKey}},
+			}
+
+			newCluster := oldCluster.DeepCopy()
+			newCluster.Labels = map[string]string{"updated": "true"}
+			newCluster.Spec.SSHPublicKeys[0].AuthorizedKeys = tc.newKeys
+
+			if tc.deleting {
+				newCluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				newCluster.Finalizers = []string{infrav1.ClusterFinalizer}
+			}
+
+			validator := &webhook.MicrovmCluster{}
+
+			_, err := validator.ValidateUpdate(context.TODO(), oldCluster, newCluster)
+			if tc.expectErr {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestMicrovmClusterValidateUpdateControlPlaneEndpointFromPool(t *testing.T) {
+	tt := []struct {
+		name      string
+		oldHost   string
+		newHost   string
+		expectErr bool
+	}{
+		{
+			name:      "host set from the pool",
+			oldHost:   "",
+			newHost:   "10.0.0.100",
+			expectErr: false,
+		},
+		{
+			name:      "cluster with host from the pool updated",
+			oldHost:   "10.0.0.100",
+			newHost:   "10.0.0.100",
+			expectErr: false,
+		},
+		{
+			name:      "host from the pool changed",
+			oldHost:   "10.0.0.100",
+			newHost:   "10.0.0.101",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			RegisterTestingT(t)
+
+			oldCluster := newMicrovmCluster()
+			oldCluster.Spec.ControlPlaneEndpointFromPool = &corev1.TypedLocalObjectReference{
+				Kind: "InClusterIPPool",
+				Name: "pool1",
+			}
+			oldCluster.Spec.ControlPlaneEndpoint.Host = tc.oldHost
+
+			newCluster := oldCluster.DeepCopy()
+			newCluster.Labels = map[string]string{"updated": "true"}
+			newCluster.Spec.ControlPlaneEndpoint.Host = tc.newHost
+			newCluster.Spec.ControlPlaneEndpoint.Port = 6443
+
+			validator := &webhook.MicrovmCluster{}
+
+			_, err := validator.ValidateUpdate(context.TODO(), oldCluster, newCluster)
+			if tc.expectErr {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestMicrovmClusterValidateCreateControlPlaneEndpointFromPoolWithHost(t *testing.T) {
+	RegisterTestingT(t)
+
+	cluster := newMicrovmCluster()
+	cluster.Spec.ControlPlaneEndpointFromPool = &corev1.TypedLocalObjectReference{
+		Kind: "InClusterIPPool",
+		Name: "pool1",
+	}
+	cluster.Spec.ControlPlaneEndpoint.Host = "10.0.0.100"
+
+	validator := &webhook.MicrovmCluster{}
+
+	_, err := validator.ValidateCreate(context.TODO(), cluster)
+	Expect(err).To(HaveOccurred())
+}
+
+func newMicrovmCluster() *infrav1.MicrovmCluster {
+	return &infrav1.MicrovmCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tenant1",
+			Namespace: "ns1",
+		},
+		Spec: infrav1.MicrovmClusterSpec{
+			Placement: infrav1.Placement{
+				StaticPool: &infrav1.StaticPoolPlacement{
+					Hosts: []infrav1.MicrovmHost{{Endpoint: "127.0.0.1:9090"}},
+				},
+			},
+		},
+	}
+}
+
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(infrav1.AddToScheme(scheme)).To(Succeed())
+	Expect(corev1.AddToScheme(scheme)).To(Succeed())
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
@@ -8,18 +8,23 @@ import (
 	"reflect"
 	"context"
 	"k8s.io/apimachinery/pkg/runtime"
-	// "k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	infrav1 "github.com/liquidmetal-dev/cluster-api-provider-microvm/api/v1alpha1"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/macaddress"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 var machineLog = logf.Log.WithName("microvmmachine-resource")
 
-type MicrovmMachine struct{}
+type MicrovmMachine struct {
+	// MACAllocator is used to allocate unique MAC addresses to the network interfaces. If
+	// not set random MAC addresses are used.
+	MACAllocator *macaddress.Allocator
+}
 
 
 func (r *MicrovmMachine) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -39,7 +44,31 @@ var (
 )
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
-func (r *MicrovmMachine) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+func (r *MicrovmMachine) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	machine, ok := obj.(*infrav1.MicrovmMachine)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmMachine but got %T", obj))
+	}
+
+	allErrs := machine.Spec.Validate(field.NewPath("spec"))
+
+	if r.MACAllocator != nil {
+		macErrs, err := r.MACAllocator.Validate(ctx, machine)
+		if err != nil {
+			return nil, apierrors.NewInternalError(err)
+		}
+
+		allErrs = append(allErrs, macErrs...)
+	}
+
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(
+			machine.GroupVersionKind().GroupKind(),
+			machine.Name,
+			allErrs,
+		)
+	}
+
 	return nil, nil
 }
 
@@ -70,11 +99,18 @@ func (r *MicrovmMachine) ValidateUpdate(_ context.Context, oldObj, newObj runtim
 }
 
 // Default satisfies the defaulting webhook interface.
-func (r *MicrovmMachine) Default(_ context.Context, obj runtime.Object) error {
+func (r *MicrovmMachine) Default(ctx context.Context, obj runtime.Object) error {
 	machine, ok := obj.(*infrav1.MicrovmMachine)
 	if !ok {
 		return apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmMachine but got a %T", obj))
 	}
+
+	// The spec is immutable so only allocate MAC addresses when the machine is created.
+	if r.MACAllocator != nil && machine.CreationTimestamp.IsZero() {
+		if err := r.MACAllocator.Allocate(ctx, machine); err != nil {
+			return apierrors.NewInternalError(err)
+		}
+	}
 	
 	infrav1.SetObjectDefaults_MicrovmMachine(machine)
 	
@@ -4,9 +4,12 @@
 package webhook
 
 import (
-	// "fmt"
 	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -32,7 +35,7 @@ var _ webhook.CustomValidator = &MicrovmMachineTemplate{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
 func (r *MicrovmMachineTemplate) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
-	return nil, nil
+	return r.validate(obj)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
@@ -42,5 +45,36 @@ func (r *MicrovmMachineTemplate) ValidateDelete(_ context.Context, _ runtime.Obj
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
 func (r *MicrovmMachineTemplate) ValidateUpdate(_ context.Context, _ runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
-	return nil, nil
+	return r.validate(newObj)
+}
+
+func (r *MicrovmMachineTemplate) validate(obj runtime.Object) (admission.Warnings, error) {
+	template, ok := obj.(*infrav1.MicrovmMachineTemplate)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MicrovmMachineTemplate but got %T", obj))
+	}
+
+	var warnings admission.Warnings
+
+	// MAC addresses aren't defaulted for templates as the template spec is copied to every
+	// machine, so warn if one has been set explicitly.
+	for i, iface := range template.Spec.Template.Spec.NetworkInterfaces {
+		if iface.GuestMAC != "" {
+			warnings = append(warnings, fmt.Sprintf(
+				"spec.template.spec.networkInterfaces[%d].guestMac is set, every machine created from this template will use the mac address %s",
+				i, iface.GuestMAC,
+			))
+		}
+	}
+
+	allErrs := template.Spec.Template.Spec.Validate(field.NewPath("spec", "template", "spec"))
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(
+			template.GroupVersionKind().GroupKind(),
+			template.Name,
+			allErrs,
+		)
+	}
+
+	return warnings, nil
 }
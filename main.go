@@ -37,13 +37,19 @@ import (
 	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	expclusterv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/flags"
 	"sigs.k8s.io/cluster-api/util/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
-	
+
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/circuitbreaker"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/clientpool"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/flintlock"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/macaddress"
+	"github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/tracing"
 	webhookMicro "github.com/liquidmetal-dev/cluster-api-provider-microvm/internal/webhook"
 
 	//+kubebuilder:scaffold:imports
@@ -58,6 +64,7 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = clusterv1.AddToScheme(scheme)
 	_ = expclusterv1.AddToScheme(scheme)
+	_ = ipamv1.AddToScheme(scheme)
 	//+kubebuilder:scaffold:scheme
 
 	_ = "comment can't be at the end of the function"
@@ -75,10 +82,21 @@ var (
 	healthAddr                  string
 	watchFilterValue            string
 	webhookCertDir              string
+	macAddressScope             string
+	macAddressPrefix            string
+	identityNamespace           string
 	microvmClusterConcurrency   int
 	microvmMachineConcurrency   int
 	webhookPort                 int
 	syncPeriod                  time.Duration
+	tlsExpiryWarningPeriod      time.Duration
+	hostCheckInterval           time.Duration
+	hostCheckTimeout            time.Duration
+	flintlockIdleTimeout        time.Duration
+	flintlockFailureThreshold   int
+	flintlockBreakerCooldown    time.Duration
+	flintlockCallPolicy         flintlock.CallPolicy
+	tracingConfig               tracing.Config
 	leaderElectionLeaseDuration time.Duration
 	leaderElectionRenewDeadline time.Duration
 	leaderElectionRetryPeriod   time.Duration
@@ -160,6 +178,14 @@ func initFlags(fs *pflag.FlagSet) {
 		),
 	)
 
+	fs.StringVar(
+		&identityNamespace,
+		"identity-namespace",
+		defaultIdentityNamespace(),
+		"Namespace that the secrets of MicrovmClusterIdentities are read from. "+
+			"Defaults to the namespace the controller is running in.",
+	)
+
 	fs.IntVar(&microvmClusterConcurrency,
 		"microvmcluster-concurrency",
 		1,
@@ -178,6 +204,99 @@ func initFlags(fs *pflag.FlagSet) {
 		"The minimum interval at which watched resources are reconciled (e.g. 15m)",
 	)
 
+	fs.DurationVar(&tlsExpiryWarningPeriod,
+		"tls-expiry-warning-period",
+		controllers.DefaultTLSExpiryWarningPeriod,
+		"How long before a host client certificate expires that it's reported as expiring (e.g. 720h)",
+	)
+
+	fs.DurationVar(&hostCheckInterval,
+		"host-check-interval",
+		controllers.DefaultHostCheckInterval,
+		"How often the hosts of a ready MicrovmCluster are checked (e.g. 1m)",
+	)
+
+	fs.DurationVar(&hostCheckTimeout,
+		"host-check-timeout",
+		controllers.DefaultHostCheckTimeout,
+		"How long the call made to check that a host is reachable can take (e.g. 5s)",
+	)
+
+	fs.DurationVar(&flintlockIdleTimeout,
+		"flintlock-connection-idle-timeout",
+		clientpool.DefaultIdleTimeout,
+		"How long a pooled connection to a flintlock host can be unused before it's closed (e.g. 5m)",
+	)
+
+	fs.IntVar(&flintlockFailureThreshold,
+		"flintlock-circuit-breaker-threshold",
+		circuitbreaker.DefaultFailureThreshold,
+		"Number of consecutive calls to a flintlock host that can fail with unavailable or timeout errors "+
+			"before the host isn't called until the cooldown has passed",
+	)
+
+	fs.DurationVar(&flintlockBreakerCooldown,
+		"flintlock-circuit-breaker-cooldown",
+		circuitbreaker.DefaultCooldown,
+		"How long a failing flintlock host isn't called for before it's tried again (e.g. 30s)",
+	)
+
+	fs.DurationVar(&flintlockCallPolicy.CreateTimeout,
+		"flintlock-create-timeout",
+		flintlock.DefaultCreateTimeout,
+		"Default timeout of calls to flintlock hosts to create a microvm (e.g. 30s)",
+	)
+
+	fs.DurationVar(&flintlockCallPolicy.DeleteTimeout,
+		"flintlock-delete-timeout",
+		flintlock.DefaultDeleteTimeout,
+		"Default timeout of calls to flintlock hosts to delete a microvm (e.g. 30s)",
+	)
+
+	fs.DurationVar(&flintlockCallPolicy.GetTimeout,
+		"flintlock-get-timeout",
+		flintlock.DefaultGetTimeout,
+		"Default timeout of calls to flintlock hosts to get a microvm, including retries (e.g. 10s)",
+	)
+
+	fs.DurationVar(&flintlockCallPolicy.ListTimeout,
+		"flintlock-list-timeout",
+		flintlock.DefaultListTimeout,
+		"Default timeout of calls to flintlock hosts to list microvms, including retries (e.g. 30s)",
+	)
+
+	fs.IntVar(&flintlockCallPolicy.Retries,
+		"flintlock-retries",
+		flintlock.DefaultRetries,
+		"Default number of times calls to flintlock hosts to get or list microvms are retried "+
+			"if the host is unavailable or the call times out",
+	)
+
+	fs.DurationVar(&flintlockCallPolicy.RetryBackoff,
+		"flintlock-retry-backoff",
+		flintlock.DefaultRetryBackoff,
+		"Default time to wait before the first retry of a call to a flintlock host, doubling for each retry (e.g. 1s)",
+	)
+
+	fs.StringVar(&tracingConfig.Endpoint,
+		"tracing-endpoint",
+		"",
+		"Host and port of the OTLP gRPC collector to export traces to (e.g. otel-collector:4317). "+
+			"If unspecified tracing is disabled.",
+	)
+
+	fs.BoolVar(&tracingConfig.Insecure,
+		"tracing-insecure",
+		false,
+		"Connect to the OTLP collector without TLS",
+	)
+
+	fs.Float64Var(&tracingConfig.SamplingRatio,
+		"tracing-sampling-ratio",
+		tracing.DefaultSamplingRatio,
+		"Fraction of reconciles that are traced, between 0 and 1",
+	)
+
 	fs.IntVar(&webhookPort,
 		"webhook-port",
 		defaultWebhookPort,
@@ -190,6 +309,19 @@ func initFlags(fs *pflag.FlagSet) {
 		"Webhook Server Certificate Directory, is the directory that contains the server key and certificate",
 	)
 
+	fs.StringVar(&macAddressScope,
+		"mac-address-scope",
+		string(macaddress.ScopeCluster),
+		"The scope that allocated microvm MAC addresses are unique within (cluster, host or management-cluster)",
+	)
+
+	fs.StringVar(&macAddressPrefix,
+		"mac-address-prefix",
+		"",
+		"Optional OUI or prefix that allocated microvm MAC addresses start with (e.g. 02:00:5e). "+
+			"If unspecified random locally administered addresses are used.",
+	)
+
 	fs.StringVar(&healthAddr,
 		"health-addr",
 		":9440",
@@ -282,6 +414,11 @@ func main() {
 	// Setup the context that's going to be used in controllers and for the manager.
 	ctx := ctrl.SetupSignalHandler()
 
+	if err := setupTracing(ctx, mgr); err != nil {
+		setupLog.Error(err, "failed to setup tracing")
+		os.Exit(1)
+	}
+
 	if err := setupReconcilers(ctx, mgr); err != nil {
 		setupLog.Error(err, "failed to add Microvm Reconcilers")
 		os.Exit(1)
@@ -313,11 +450,37 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) error {
 		RecoverPanic:            ptr.To[bool](true),
 	}
 
+	if err := controllers.SetupIndexes(ctx, mgr); err != nil {
+		return fmt.Errorf("unable to setup indexes: %w", err)
+	}
+
+	clientPool := clientpool.New(client.NewFlintlockClient, clientpool.Config{
+		IdleTimeout: flintlockIdleTimeout,
+	})
+	if err := mgr.Add(clientPool); err != nil {
+		return fmt.Errorf("unable to add flintlock connection pool: %w", err)
+	}
+
+	breakers := circuitbreaker.New(circuitbreaker.Config{
+		FailureThreshold: flintlockFailureThreshold,
+		Cooldown:         flintlockBreakerCooldown,
+	})
+
 	if err := (&controllers.MicrovmClusterReconciler{
 		Client:           mgr.GetClient(),
 		Scheme:           mgr.GetScheme(),
 		Recorder:         mgr.GetEventRecorderFor("microvmcluster-controller"),
 		WatchFilterValue: watchFilterValue,
+
+		TLSExpiryWarningPeriod: tlsExpiryWarningPeriod,
+		HostCheckInterval:      hostCheckInterval,
+		HostCheckTimeout:       hostCheckTimeout,
+		IdentityNamespace:      identityNamespace,
+		ClientPool:             clientPool,
+		MvmClientFunc:          client.NewFlintlockClient,
+		DescribeHostFunc:       flintlock.DescribeHost,
+		CircuitBreakers:        breakers,
+		CallPolicy:             flintlockCallPolicy,
 	}).SetupWithManager(ctx, mgr, managerOptions); err != nil {
 		return fmt.Errorf("unable to create microvm cluster controller: %w", err)
 	}
@@ -328,6 +491,11 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) error {
 		Recorder:         mgr.GetEventRecorderFor("microvmmachine-controller"),
 		WatchFilterValue: watchFilterValue,
 		MvmClientFunc:    client.NewFlintlockClient,
+		ClientPool:       clientPool,
+		CircuitBreakers:  breakers,
+		CallPolicy:       flintlockCallPolicy,
+
+		IdentityNamespace: identityNamespace,
 	}).SetupWithManager(ctx, mgr, managerOptions); err != nil {
 		return fmt.Errorf("unable to create microvm machine controller: %w", err)
 	}
@@ -335,12 +503,51 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) error {
 	return nil
 }
 
+func setupTracing(ctx context.Context, mgr ctrl.Manager) error {
+	if !tracingConfig.Enabled() {
+		return nil
+	}
+
+	tracingConfig.ServiceVersion = version.Get().GitVersion
+
+	provider, err := tracing.New(ctx, tracingConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create tracer provider: %w", err)
+	}
+
+	if err := mgr.Add(provider); err != nil {
+		return fmt.Errorf("unable to add tracer provider: %w", err)
+	}
+
+	setupLog.Info("Exporting traces", "endpoint", tracingConfig.Endpoint)
+
+	return nil
+}
+
+// defaultIdentityNamespace returns the namespace the controller is running in, as set by the
+// downward API, or the namespace it's installed into by default.
+func defaultIdentityNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+
+	return "capmvm-system"
+}
+
 func setupWebhooks(mgr ctrl.Manager) error {
-	if err := (&webhookMicro.MicrovmCluster{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&webhookMicro.MicrovmCluster{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to setup MicrovmCluster webhook:%w", err)
 	}
 
-	if err := (&webhookMicro.MicrovmMachine{}).SetupWebhookWithManager(mgr); err != nil {
+	macAllocator, err := macaddress.NewAllocator(mgr.GetClient(), macaddress.Config{
+		Scope:  macaddress.Scope(macAddressScope),
+		Prefix: macAddressPrefix,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create mac address allocator: %w", err)
+	}
+
+	if err := (&webhookMicro.MicrovmMachine{MACAllocator: macAllocator}).SetupWebhookWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to setup MicrovmMachine webhook:%w", err)
 	}
 